@@ -0,0 +1,52 @@
+package main
+
+import (
+	"account-service/internal/breach"
+	"flag"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"os"
+)
+
+// buildBreachFilter build bloom filter of breached passwords from HIBP offline dump
+func buildBreachFilter(args []string) error {
+	fs := flag.NewFlagSet("build-breach-filter", flag.ExitOnError)
+	input := fs.String("input", "", "HIBP SHA-1 dump file with HASH:COUNT lines or directory of range files")
+	output := fs.String("output", "breached.bloom", "bloom filter file")
+	falsePositiveRate := fs.Float64("fp", 0.001, "false positive rate of bloom filter")
+	minCount := fs.Uint64("min-count", 0, "skip hashes seen less times in breaches")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("fs.Parse error: %w", err)
+	}
+
+	if *input == "" {
+		fs.Usage()
+		return fmt.Errorf("input is required")
+	}
+
+	bloom, count, err := breach.BuildBloomFilter(*input, *falsePositiveRate, *minCount)
+	if err != nil {
+		return fmt.Errorf("breach.BuildBloomFilter error: %w", err)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("os.Create error: %w", err)
+	}
+	defer file.Close()
+
+	size, err := bloom.WriteTo(file)
+	if err != nil {
+		return fmt.Errorf("bloom.WriteTo error: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("file.Close error: %w", err)
+	}
+
+	hclog.Default().Info("breach filter is built", "hashes", count, "bytes", size, "output", *output)
+
+	return nil
+}
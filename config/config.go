@@ -1,20 +1,112 @@
 package config
 
 import (
+	"account-service/internal/hasher"
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"fmt"
 	"github.com/joho/godotenv"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config of service
 type Config struct {
-	ServerHost string
-	NatsHost   string
-	DbDsn      string
-	JwtSecret  string
-	AesSecret  string
-	SentryDSN  string
-	JaegerHost string
+	ServerHost           string
+	NatsHost             string
+	DbDsn                string
+	JwtSecret            string
+	AesSecret            string
+	SentryDSN            string
+	JaegerHost           string
+	PolicyFile           string
+	PolicyReloadInterval time.Duration
+
+	// PasswordHistorySize count of recent passwords including current which can't be reused, zero disables check
+	PasswordHistorySize int
+	// PasswordHistoryRetention how long previous passwords are stored
+	PasswordHistoryRetention time.Duration
+	// PasswordPolicy rules of new passwords
+	PasswordPolicy validators.PasswordPolicy
+	// PasswordHash argon2id parameters of new password hashes, weaker hashes are upgraded on login
+	PasswordHash hasher.Params
+	// BreachedPasswordsPath bloom filter file or directory of HIBP range files, empty disables check
+	BreachedPasswordsPath string
+
+	// InvitationTTL how long invitation and its register token are valid
+	InvitationTTL time.Duration
+
+	// IdentityProvidersFile JSON file with external OpenID Connect providers, empty disables federated login
+	IdentityProvidersFile string
+
+	// AuthChain password authenticators of login tried in order: local and ldap
+	AuthChain []string
+	// LDAPURL url of LDAP or Active Directory server, ldaps or ldap with StartTLS
+	LDAPURL string
+	// LDAPStartTLS upgrade ldap connection by StartTLS
+	LDAPStartTLS bool
+	// LDAPInsecureSkipVerify don't verify certificate of LDAP server
+	LDAPInsecureSkipVerify bool
+	// LDAPCACertPath PEM file with CA certificates of LDAP server, system roots are used if empty
+	LDAPCACertPath string
+	// LDAPBindDN DN of service account searching users, anonymous search if empty
+	LDAPBindDN string
+	// LDAPBindPassword password of service account
+	LDAPBindPassword string
+	// LDAPBaseDN DN where users are searched
+	LDAPBaseDN string
+	// LDAPUserFilter filter of user entry, %s is replaced by escaped email
+	LDAPUserFilter string
+	// LDAPEmailAttribute attribute with email of user
+	LDAPEmailAttribute string
+	// LDAPFirstNameAttribute attribute with first name of user
+	LDAPFirstNameAttribute string
+	// LDAPLastNameAttribute attribute with last name of user
+	LDAPLastNameAttribute string
+	// LDAPGroupAttribute attribute with DNs of groups of user
+	LDAPGroupAttribute string
+	// LDAPDepartmentAttribute attribute with department name of user, empty keeps department of service
+	LDAPDepartmentAttribute string
+	// LDAPGroupRoles roles of groups, first matched group decides role of user
+	LDAPGroupRoles []models.GroupRole
+	// LDAPDefaultDepartment name of department of provisioned users without known department
+	LDAPDefaultDepartment string
+	// LDAPDefaultRole name of role of users without mapped group
+	LDAPDefaultRole string
+	// LDAPTimeout timeout of connection and requests to LDAP server
+	LDAPTimeout time.Duration
+
+	// ErasureRetention how long personal data of deleted users is kept
+	ErasureRetention time.Duration
+	// ErasureInterval how often deleted users are erased
+	ErasureInterval time.Duration
+	// ErasureMode anonymize or purge erased users
+	ErasureMode string
+	// ErasureDryRun only log users which would be erased
+	ErasureDryRun bool
+	// ErasureBatchSize count of users erased by one query
+	ErasureBatchSize int
+
+	// HTTPHost address of http server with SCIM endpoint and OAuth 2.0 authorization server, empty disables http server
+	HTTPHost string
+	// OIDCIssuer external url of authorization server used as issuer of id tokens, required with http server
+	OIDCIssuer string
+	// OIDCSigningKeyPath PEM file with RSA private key signing id tokens, required with http server
+	OIDCSigningKeyPath string
+	// ScimToken bearer token of SCIM clients, empty disables SCIM endpoint
+	ScimToken string
+	// ScimBaseURL external url of SCIM endpoint used in locations of resources
+	ScimBaseURL string
+	// ScimGroups models exposed as SCIM groups: departments or roles
+	ScimGroups string
+	// ScimDefaultDepartment name of department of users created without department
+	ScimDefaultDepartment string
+	// ScimDefaultRole name of role of users created without role
+	ScimDefaultRole string
 }
 
 // NewConfig generate new config
@@ -24,13 +116,385 @@ func NewConfig() (*Config, error) {
 		log.Println("NewConfig godotenv load failed")
 	}
 
+	policyReloadInterval, err := getDuration("POLICY_RELOAD_INTERVAL", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if policyReloadInterval <= 0 {
+		return nil, fmt.Errorf("invalid POLICY_RELOAD_INTERVAL: must be positive")
+	}
+
+	passwordHistorySize, err := getInt("PASSWORD_HISTORY_SIZE", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHistoryRetention, err := getDuration("PASSWORD_HISTORY_RETENTION", 365*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy, err := getPasswordPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := getPasswordHashParams()
+	if err != nil {
+		return nil, err
+	}
+
+	invitationTTL, err := getDuration("INVITATION_TTL", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	erasureRetention, err := getDuration("ERASURE_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	erasureInterval, err := getDuration("ERASURE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if erasureInterval <= 0 {
+		return nil, fmt.Errorf("invalid ERASURE_INTERVAL: must be positive")
+	}
+
+	erasureMode := os.Getenv("ERASURE_MODE")
+	if erasureMode == "" {
+		erasureMode = models.ErasureModeAnonymize
+	}
+	if erasureMode != models.ErasureModeAnonymize && erasureMode != models.ErasureModePurge {
+		return nil, fmt.Errorf("invalid ERASURE_MODE: %s", erasureMode)
+	}
+
+	erasureDryRun, err := getBool("ERASURE_DRY_RUN", false)
+	if err != nil {
+		return nil, err
+	}
+
+	erasureBatchSize, err := getInt("ERASURE_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	if erasureBatchSize <= 0 {
+		return nil, fmt.Errorf("invalid ERASURE_BATCH_SIZE: must be positive")
+	}
+
+	authChain := getList("AUTH_CHAIN")
+	if len(authChain) == 0 {
+		authChain = []string{models.AuthLocal}
+	}
+	for _, authenticator := range authChain {
+		if authenticator != models.AuthLocal && authenticator != models.AuthLDAP {
+			return nil, fmt.Errorf("invalid AUTH_CHAIN: unknown authenticator %s", authenticator)
+		}
+	}
+
+	ldapStartTLS, err := getBool("LDAP_START_TLS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	ldapInsecureSkipVerify, err := getBool("LDAP_INSECURE_SKIP_VERIFY", false)
+	if err != nil {
+		return nil, err
+	}
+
+	ldapGroupRoles, err := getGroupRoles("LDAP_GROUP_ROLES")
+	if err != nil {
+		return nil, err
+	}
+
+	ldapTimeout, err := getDuration("LDAP_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	ldapDefaultRole := os.Getenv("LDAP_DEFAULT_ROLE")
+	if ldapDefaultRole == "" {
+		ldapDefaultRole = models.EmployeeRole
+	}
+
+	ldapURL := os.Getenv("LDAP_URL")
+	for _, authenticator := range authChain {
+		if authenticator != models.AuthLDAP {
+			continue
+		}
+		if ldapURL == "" || os.Getenv("LDAP_BASE_DN") == "" || os.Getenv("LDAP_DEFAULT_DEPARTMENT") == "" {
+			return nil, fmt.Errorf("invalid AUTH_CHAIN: ldap requires LDAP_URL, LDAP_BASE_DN and LDAP_DEFAULT_DEPARTMENT")
+		}
+		// passwords of users must not be sent in clear text
+		if !strings.HasPrefix(ldapURL, "ldaps://") && !ldapStartTLS {
+			return nil, fmt.Errorf("invalid LDAP_URL: ldaps or LDAP_START_TLS is required")
+		}
+	}
+
+	scimGroups := os.Getenv("SCIM_GROUPS")
+	if scimGroups == "" {
+		scimGroups = models.ScimGroupsDepartments
+	}
+	if scimGroups != models.ScimGroupsDepartments && scimGroups != models.ScimGroupsRoles {
+		return nil, fmt.Errorf("invalid SCIM_GROUPS: %s", scimGroups)
+	}
+
+	scimBaseURL := os.Getenv("SCIM_BASE_URL")
+	if scimBaseURL == "" {
+		scimBaseURL = "/scim/v2"
+	}
+
+	scimDefaultRole := os.Getenv("SCIM_DEFAULT_ROLE")
+	if scimDefaultRole == "" {
+		scimDefaultRole = models.EmployeeRole
+	}
+
+	// users created by SCIM clients without enterprise department are placed to default department
+	scimDefaultDepartment := os.Getenv("SCIM_DEFAULT_DEPARTMENT")
+	if os.Getenv("SCIM_TOKEN") != "" && scimDefaultDepartment == "" {
+		return nil, fmt.Errorf("invalid SCIM_DEFAULT_DEPARTMENT: required when SCIM_TOKEN is set")
+	}
+
+	// issuer isn't derived from Host header of request because clients control it
+	oidcIssuer := strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/")
+	oidcSigningKeyPath := os.Getenv("OIDC_SIGNING_KEY_PATH")
+	if os.Getenv("HTTP_HOST") != "" {
+		issuerURL, err := url.Parse(oidcIssuer)
+		if oidcIssuer == "" || err != nil || issuerURL.Scheme != "https" || issuerURL.Host == "" {
+			return nil, fmt.Errorf("invalid OIDC_ISSUER: https url is required when HTTP_HOST is set")
+		}
+		if oidcSigningKeyPath == "" {
+			return nil, fmt.Errorf("invalid OIDC_SIGNING_KEY_PATH: required when HTTP_HOST is set")
+		}
+	}
+
 	return &Config{
-		ServerHost: os.Getenv("SERVER_HOST"),
-		NatsHost:   os.Getenv("NATS_HOST"),
-		DbDsn:      os.Getenv("DB_DSN"),
-		JwtSecret:  os.Getenv("JWT_SECRET"),
-		AesSecret:  os.Getenv("AES_SECRET"),
-		SentryDSN:  os.Getenv("SENTRY_DSN"),
-		JaegerHost: os.Getenv("JAEGER_HOST"),
+		ServerHost:           os.Getenv("SERVER_HOST"),
+		NatsHost:             os.Getenv("NATS_HOST"),
+		DbDsn:                os.Getenv("DB_DSN"),
+		JwtSecret:            os.Getenv("JWT_SECRET"),
+		AesSecret:            os.Getenv("AES_SECRET"),
+		SentryDSN:            os.Getenv("SENTRY_DSN"),
+		JaegerHost:           os.Getenv("JAEGER_HOST"),
+		PolicyFile:           os.Getenv("POLICY_FILE"),
+		PolicyReloadInterval: policyReloadInterval,
+
+		PasswordHistorySize:      passwordHistorySize,
+		PasswordHistoryRetention: passwordHistoryRetention,
+		PasswordPolicy:           *passwordPolicy,
+		PasswordHash:             *passwordHash,
+		BreachedPasswordsPath:    os.Getenv("BREACHED_PASSWORDS_PATH"),
+
+		InvitationTTL: invitationTTL,
+
+		IdentityProvidersFile: os.Getenv("IDENTITY_PROVIDERS_FILE"),
+
+		AuthChain:               authChain,
+		LDAPURL:                 ldapURL,
+		LDAPStartTLS:            ldapStartTLS,
+		LDAPInsecureSkipVerify:  ldapInsecureSkipVerify,
+		LDAPCACertPath:          os.Getenv("LDAP_CA_CERT_PATH"),
+		LDAPBindDN:              os.Getenv("LDAP_BIND_DN"),
+		LDAPBindPassword:        os.Getenv("LDAP_BIND_PASSWORD"),
+		LDAPBaseDN:              os.Getenv("LDAP_BASE_DN"),
+		LDAPUserFilter:          getString("LDAP_USER_FILTER", "(&(objectClass=person)(mail=%s))"),
+		LDAPEmailAttribute:      getString("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPFirstNameAttribute:  getString("LDAP_FIRST_NAME_ATTRIBUTE", "givenName"),
+		LDAPLastNameAttribute:   getString("LDAP_LAST_NAME_ATTRIBUTE", "sn"),
+		LDAPGroupAttribute:      getString("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPDepartmentAttribute: os.Getenv("LDAP_DEPARTMENT_ATTRIBUTE"),
+		LDAPGroupRoles:          ldapGroupRoles,
+		LDAPDefaultDepartment:   os.Getenv("LDAP_DEFAULT_DEPARTMENT"),
+		LDAPDefaultRole:         ldapDefaultRole,
+		LDAPTimeout:             ldapTimeout,
+
+		ErasureRetention: erasureRetention,
+		ErasureInterval:  erasureInterval,
+		ErasureMode:      erasureMode,
+		ErasureDryRun:    erasureDryRun,
+		ErasureBatchSize: erasureBatchSize,
+
+		HTTPHost:              os.Getenv("HTTP_HOST"),
+		OIDCIssuer:            oidcIssuer,
+		OIDCSigningKeyPath:    oidcSigningKeyPath,
+		ScimToken:             os.Getenv("SCIM_TOKEN"),
+		ScimBaseURL:           scimBaseURL,
+		ScimGroups:            scimGroups,
+		ScimDefaultDepartment: scimDefaultDepartment,
+		ScimDefaultRole:       scimDefaultRole,
+	}, nil
+}
+
+// getPasswordPolicy get password policy from environment, not set rules are taken from default policy
+func getPasswordPolicy() (*validators.PasswordPolicy, error) {
+	policy := validators.DefaultPasswordPolicy()
+
+	var err error
+	if policy.MinLength, err = getInt("PASSWORD_MIN_LENGTH", policy.MinLength); err != nil {
+		return nil, err
+	}
+	if policy.MaxLength, err = getInt("PASSWORD_MAX_LENGTH", policy.MaxLength); err != nil {
+		return nil, err
+	}
+	if policy.RequireUpper, err = getBool("PASSWORD_REQUIRE_UPPER", policy.RequireUpper); err != nil {
+		return nil, err
+	}
+	if policy.RequireLower, err = getBool("PASSWORD_REQUIRE_LOWER", policy.RequireLower); err != nil {
+		return nil, err
+	}
+	if policy.RequireDigit, err = getBool("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit); err != nil {
+		return nil, err
+	}
+	if policy.RequireSymbol, err = getBool("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol); err != nil {
+		return nil, err
+	}
+	if policy.BanPersonalInfo, err = getBool("PASSWORD_BAN_PERSONAL_INFO", policy.BanPersonalInfo); err != nil {
+		return nil, err
+	}
+	if policy.MaxRepeated, err = getInt("PASSWORD_MAX_REPEATED", policy.MaxRepeated); err != nil {
+		return nil, err
+	}
+	if policy.MinScore, err = getInt("PASSWORD_MIN_SCORE", policy.MinScore); err != nil {
+		return nil, err
+	}
+	policy.BannedSubstrings = getList("PASSWORD_BANNED_SUBSTRINGS")
+
+	if policy.MinLength < 0 || policy.MaxLength < 0 || (policy.MaxLength > 0 && policy.MinLength > policy.MaxLength) {
+		return nil, fmt.Errorf("invalid password length limits: min %d, max %d", policy.MinLength, policy.MaxLength)
+	}
+	if policy.MinScore < 0 || policy.MinScore > 4 {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_SCORE: must be from 0 to 4")
+	}
+
+	return &policy, nil
+}
+
+// getDuration get duration from environment or default if it is not set
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return duration, nil
+}
+
+// getInt get integer from environment or default if it is not set
+func getInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return number, nil
+}
+
+// getPasswordHashParams get argon2id parameters from environment, not set parameters are taken from defaults
+func getPasswordHashParams() (*hasher.Params, error) {
+	params := hasher.DefaultParams()
+
+	memory, err := getInt("ARGON2_MEMORY_KIB", int(params.Memory))
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := getInt("ARGON2_ITERATIONS", int(params.Iterations))
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := getInt("ARGON2_PARALLELISM", int(params.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	saltLength, err := getInt("ARGON2_SALT_LENGTH", int(params.SaltLength))
+	if err != nil {
+		return nil, err
+	}
+	keyLength, err := getInt("ARGON2_KEY_LENGTH", int(params.KeyLength))
+	if err != nil {
+		return nil, err
+	}
+
+	if memory <= 0 || iterations <= 0 || saltLength <= 0 || keyLength <= 0 || parallelism <= 0 || parallelism > 255 {
+		return nil, fmt.Errorf("invalid argon2 parameters")
+	}
+
+	return &hasher.Params{
+		Memory:      uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+		SaltLength:  uint32(saltLength),
+		KeyLength:   uint32(keyLength),
 	}, nil
 }
+
+// getBool get boolean from environment or default if it is not set
+func getBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return flag, nil
+}
+
+// getString get string from environment or default if it is not set
+func getString(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getGroupRoles get semicolon separated group DN|role pairs from environment,
+// semicolon is used because DNs contain commas
+func getGroupRoles(key string) ([]models.GroupRole, error) {
+	var groupRoles []models.GroupRole
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		separator := strings.LastIndex(item, "|")
+		if separator <= 0 || separator == len(item)-1 {
+			return nil, fmt.Errorf("invalid %s: %q is not group|role", key, item)
+		}
+		groupRoles = append(groupRoles, models.GroupRole{
+			Group: strings.TrimSpace(item[:separator]),
+			Role:  strings.TrimSpace(item[separator+1:]),
+		})
+	}
+
+	return groupRoles, nil
+}
+
+// getList get comma separated list from environment, empty items are skipped
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
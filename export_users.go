@@ -0,0 +1,129 @@
+package main
+
+import (
+	"account-service/config"
+	"account-service/internal/exporter"
+	"account-service/internal/hasher"
+	"account-service/internal/models"
+	"account-service/internal/server/repository"
+	"comet/db"
+	"flag"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// exportUsers write users matched by filter to csv or ndjson file
+func exportUsers(args []string) error {
+	fs := flag.NewFlagSet("export-users", flag.ExitOnError)
+	output := fs.String("output", "-", "csv or ndjson file, - for stdout")
+	format := fs.String("format", models.FormatCSV, "format of output: csv or ndjson")
+	query := fs.String("query", "", "search by names and email")
+	departmentID := fs.Uint("department", 0, "id of department")
+	subdepartments := fs.Bool("subdepartments", false, "include users of subdepartments")
+	roleID := fs.Uint("role", 0, "id of role")
+	registered := fs.String("registered", "", "filter by registration: true or false")
+	verified := fs.String("verified", "", "filter by verified email: true or false")
+	createdFrom := fs.String("created-from", "", "created at or after time in RFC3339")
+	createdTo := fs.String("created-to", "", "created before time in RFC3339")
+	sortBy := fs.String("sort", models.UserSortCreatedAt, "sort field: created_at, email or last_name")
+	descending := fs.Bool("desc", false, "sort descending")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("fs.Parse error: %w", err)
+	}
+
+	filter := models.UserFilter{
+		Query:                 *query,
+		DepartmentID:          uint32(*departmentID),
+		IncludeSubdepartments: *subdepartments,
+		RoleID:                uint32(*roleID),
+		SortBy:                *sortBy,
+		Descending:            *descending,
+	}
+
+	if filter.IsRegistered, err = optionalBool(*registered); err != nil {
+		return fmt.Errorf("invalid registered: %w", err)
+	}
+	if filter.EmailVerified, err = optionalBool(*verified); err != nil {
+		return fmt.Errorf("invalid verified: %w", err)
+	}
+	if filter.CreatedFrom, err = optionalTime(*createdFrom); err != nil {
+		return fmt.Errorf("invalid created-from: %w", err)
+	}
+	if filter.CreatedTo, err = optionalTime(*createdTo); err != nil {
+		return fmt.Errorf("invalid created-to: %w", err)
+	}
+
+	var writer io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("os.Create error: %w", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load environment: %w", err)
+	}
+
+	database, err := db.NewDatabase(cfg.DbDsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	passwordHasher, err := hasher.NewHasher(cfg.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to create password hasher: %w", err)
+	}
+
+	count, err := exporter.Export(repository.NewRepository(database, cfg, passwordHasher), writer, filter, *format)
+	if err != nil {
+		return fmt.Errorf("exporter.Export error: %w", err)
+	}
+
+	if file, ok := writer.(*os.File); ok && file != os.Stdout {
+		err = file.Close()
+		if err != nil {
+			return fmt.Errorf("file.Close error: %w", err)
+		}
+	}
+
+	hclog.Default().Info("users are exported", "count", count, "output", *output)
+
+	return nil
+}
+
+// optionalBool parse bool flag which is not set when empty
+func optionalBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+// optionalTime parse RFC3339 time flag which is not set when empty
+func optionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"account-service/config"
+	"account-service/internal/hasher"
+	"account-service/internal/importer"
+	"account-service/internal/models"
+	"account-service/internal/server/repository"
+	"comet/db"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// importUsers create users of csv or ndjson file and print json report
+func importUsers(args []string) error {
+	fs := flag.NewFlagSet("import-users", flag.ExitOnError)
+	input := fs.String("input", "-", "csv or ndjson file with users, - for stdin")
+	format := fs.String("format", models.FormatCSV, "format of input: csv or ndjson")
+	dryRun := fs.Bool("dry-run", false, "validate and import in transaction which is rolled back")
+	createMissing := fs.Bool("create-missing", false, "create departments and roles which are not found by name")
+	atomic := fs.Bool("atomic", false, "create all users or none of them")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("fs.Parse error: %w", err)
+	}
+
+	var reader io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("os.Open error: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load environment: %w", err)
+	}
+
+	database, err := db.NewDatabase(cfg.DbDsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	passwordHasher, err := hasher.NewHasher(cfg.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to create password hasher: %w", err)
+	}
+
+	report, err := importer.Import(repository.NewRepository(database, cfg, passwordHasher), reader, models.ImportOptions{
+		Format:        *format,
+		DryRun:        *dryRun,
+		CreateMissing: *createMissing,
+		Atomic:        *atomic,
+	})
+	if err != nil {
+		return fmt.Errorf("importer.Import error: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("encoder.Encode error: %w", err)
+	}
+
+	return nil
+}
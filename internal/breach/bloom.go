@@ -0,0 +1,155 @@
+package breach
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// bloomMagic first bytes of bloom filter file
+const bloomMagic = "BRBF"
+
+// bloomVersion version of bloom filter file format
+const bloomVersion uint32 = 1
+
+// BloomFilter bloom filter of SHA-1 hashes of passwords
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint32
+}
+
+// NewBloomFilter create empty bloom filter sized for count of hashes with false positive rate
+func NewBloomFilter(count uint64, falsePositiveRate float64) (*BloomFilter, error) {
+	if count == 0 {
+		return nil, fmt.Errorf("count of hashes must be positive")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1")
+	}
+
+	m := uint64(math.Ceil(-float64(count) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint32(math.Round(float64(m) / float64(count) * math.Ln2))
+	if hashes == 0 {
+		hashes = 1
+	}
+
+	return &BloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: hashes,
+	}, nil
+}
+
+// Add add SHA-1 hash to filter
+func (b *BloomFilter) Add(hash [sha1Size]byte) {
+	h1, h2 := split(hash)
+	for i := uint64(0); i < uint64(b.hashes); i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Test check SHA-1 hash is possibly in filter
+func (b *BloomFilter) Test(hash [sha1Size]byte) bool {
+	h1, h2 := split(hash)
+	for i := uint64(0); i < uint64(b.hashes); i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteTo write filter in binary format
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, len(bloomMagic)+16)
+	header = append(header, bloomMagic...)
+	header = binary.BigEndian.AppendUint32(header, bloomVersion)
+	header = binary.BigEndian.AppendUint32(header, b.hashes)
+	header = binary.BigEndian.AppendUint64(header, b.m)
+
+	n, err := bw.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, fmt.Errorf("bw.Write header error: %w", err)
+	}
+
+	word := make([]byte, 8)
+	for _, bits := range b.bits {
+		binary.BigEndian.PutUint64(word, bits)
+		n, err = bw.Write(word)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("bw.Write bits error: %w", err)
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return written, fmt.Errorf("bw.Flush error: %w", err)
+	}
+
+	return written, nil
+}
+
+// ReadBloomFilter read filter written by WriteTo, size is size of data of reader
+// and bounds size of bits of filter, so corrupted header can't allocate more memory than file has
+func ReadBloomFilter(r io.Reader, size int64) (*BloomFilter, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(bloomMagic)+16)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadFull header error: %w", err)
+	}
+
+	if string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, fmt.Errorf("not a bloom filter file")
+	}
+	header = header[len(bloomMagic):]
+
+	version := binary.BigEndian.Uint32(header[0:4])
+	if version != bloomVersion {
+		return nil, fmt.Errorf("unsupported bloom filter version: %d", version)
+	}
+
+	b := &BloomFilter{
+		hashes: binary.BigEndian.Uint32(header[4:8]),
+		m:      binary.BigEndian.Uint64(header[8:16]),
+	}
+	if b.hashes == 0 || b.m == 0 {
+		return nil, fmt.Errorf("invalid bloom filter parameters")
+	}
+
+	words := (b.m + 63) / 64
+	if remaining := size - int64(len(bloomMagic)+16); remaining < 0 || uint64(remaining) != words*8 {
+		return nil, fmt.Errorf("size of bloom filter %d bits doesn't match size of file %d bytes", b.m, size)
+	}
+
+	b.bits = make([]uint64, words)
+	word := make([]byte, 8)
+	for i := range b.bits {
+		_, err = io.ReadFull(br, word)
+		if err != nil {
+			return nil, fmt.Errorf("io.ReadFull bits error: %w", err)
+		}
+		b.bits[i] = binary.BigEndian.Uint64(word)
+	}
+
+	return b, nil
+}
+
+// split two independent hashes for double hashing, SHA-1 is uniform so its parts are used directly
+func split(hash [sha1Size]byte) (uint64, uint64) {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+
+	return h1, h2
+}
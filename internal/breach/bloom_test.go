@@ -0,0 +1,125 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	breached := []string{"password", "123456", "qwerty"}
+	var dump strings.Builder
+	for i, password := range breached {
+		hash := sha1.Sum([]byte(password))
+		fmt.Fprintf(&dump, "%s:%d\n", strings.ToUpper(hex.EncodeToString(hash[:])), i+1)
+	}
+	// hash seen less than min count is skipped
+	rare := sha1.Sum([]byte("rare password"))
+	fmt.Fprintf(&dump, "%s:1\n", strings.ToUpper(hex.EncodeToString(rare[:])))
+
+	dumpPath := filepath.Join(dir, "dump.txt")
+	err := os.WriteFile(dumpPath, []byte(dump.String()), 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile error: %v", err)
+	}
+
+	bloom, count, err := BuildBloomFilter(dumpPath, 0.0001, 2)
+	if err != nil {
+		t.Fatalf("BuildBloomFilter error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	filterPath := filepath.Join(dir, "breached.bloom")
+	file, err := os.Create(filterPath)
+	if err != nil {
+		t.Fatalf("os.Create error: %v", err)
+	}
+	_, err = bloom.WriteTo(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("WriteTo error: %v", err)
+	}
+
+	checker, err := NewChecker(filterPath)
+	if err != nil {
+		t.Fatalf("NewChecker error: %v", err)
+	}
+
+	tests := []struct {
+		password string
+		breached bool
+	}{
+		{password: "123456", breached: true},
+		{password: "qwerty", breached: true},
+		{password: "password"},
+		{password: "rare password"},
+		{password: "correct horse battery staple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got, err := checker.IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached error: %v", err)
+			}
+			if got != tt.breached {
+				t.Errorf("IsBreached = %v, want %v", got, tt.breached)
+			}
+		})
+	}
+}
+
+func TestReadBloomFilterCorrupted(t *testing.T) {
+	bloom, err := NewBloomFilter(10, 0.01)
+	if err != nil {
+		t.Fatalf("NewBloomFilter error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, err = bloom.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo error: %v", err)
+	}
+	data := buf.Bytes()
+
+	hugeSize := append([]byte(nil), data...)
+	binary.BigEndian.PutUint64(hugeSize[len(bloomMagic)+8:], 1<<62)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "huge size of filter", data: hugeSize},
+		{name: "truncated bits", data: data[:len(data)-8]},
+		{name: "trailing data", data: append(append([]byte(nil), data...), 0)},
+		{name: "truncated header", data: data[:len(bloomMagic)+4]},
+		{name: "other file", data: append([]byte("TEXT"), data[len(bloomMagic):]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadBloomFilter(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err == nil {
+				t.Errorf("ReadBloomFilter succeeded")
+			}
+		})
+	}
+
+	read, err := ReadBloomFilter(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ReadBloomFilter error: %v", err)
+	}
+	if read.m != bloom.m || read.hashes != bloom.hashes || len(read.bits) != len(bloom.bits) {
+		t.Errorf("filter = %d bits %d hashes, want %d bits %d hashes", read.m, read.hashes, bloom.m, bloom.hashes)
+	}
+}
@@ -0,0 +1,144 @@
+package breach
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BuildBloomFilter build bloom filter from HIBP dump, dump is either single file with HASH:COUNT lines
+// or directory of range files named by hash prefix with SUFFIX:COUNT lines,
+// hashes seen less than minCount times are skipped
+func BuildBloomFilter(path string, falsePositiveRate float64, minCount uint64) (*BloomFilter, uint64, error) {
+	sources, err := dumpSources(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// dump is read twice, first pass counts hashes to size filter
+	var count uint64
+	err = readDump(sources, minCount, func([sha1Size]byte) {
+		count++
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bloom, err := NewBloomFilter(count, falsePositiveRate)
+	if err != nil {
+		return nil, 0, fmt.Errorf("NewBloomFilter error: %w", err)
+	}
+
+	err = readDump(sources, minCount, bloom.Add)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bloom, count, nil
+}
+
+// dumpSource file of dump with hash prefix of its lines
+type dumpSource struct {
+	filename string
+	prefix   string
+}
+
+// dumpSources files of dump by path
+func dumpSources(path string) ([]dumpSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat error: %w", err)
+	}
+
+	if !info.IsDir() {
+		return []dumpSource{{filename: path}}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir error: %w", err)
+	}
+
+	sources := make([]dumpSource, 0, len(entries))
+	for _, entry := range entries {
+		prefix := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if entry.IsDir() || len(prefix) != prefixLength {
+			continue
+		}
+		if _, err := hex.DecodeString(prefix + "0"); err != nil {
+			continue
+		}
+
+		sources = append(sources, dumpSource{
+			filename: filepath.Join(path, entry.Name()),
+			prefix:   strings.ToUpper(prefix),
+		})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].prefix < sources[j].prefix
+	})
+
+	return sources, nil
+}
+
+// readDump call add for each hash of dump seen at least minCount times
+func readDump(sources []dumpSource, minCount uint64, add func([sha1Size]byte)) error {
+	for _, source := range sources {
+		err := readDumpFile(source, minCount, add)
+		if err != nil {
+			return fmt.Errorf("readDumpFile %s error: %w", source.filename, err)
+		}
+	}
+
+	return nil
+}
+
+// readDumpFile call add for each hash of dump file seen at least minCount times
+func readDumpFile(source dumpSource, minCount uint64, add func([sha1Size]byte)) error {
+	file, err := os.Open(source.filename)
+	if err != nil {
+		return fmt.Errorf("os.Open error: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hexHash, countText, hasCount := strings.Cut(text, ":")
+		if hasCount && minCount > 0 {
+			count, err := strconv.ParseUint(countText, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid count on line %d: %w", line, err)
+			}
+			if count < minCount {
+				continue
+			}
+		}
+
+		var hash [sha1Size]byte
+		decoded, err := hex.DecodeString(source.prefix + hexHash)
+		if err != nil || len(decoded) != sha1Size {
+			return fmt.Errorf("invalid hash on line %d", line)
+		}
+		copy(hash[:], decoded)
+
+		add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner.Scan error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sha1Size size of SHA-1 hash in bytes
+const sha1Size = sha1.Size
+
+// prefixLength length of hex hash prefix used as name of range file like in HIBP range API
+const prefixLength = 5
+
+// Checker check passwords against local list of breached passwords
+type Checker struct {
+	bloom     *BloomFilter
+	prefixDir string
+}
+
+// NewChecker create checker from path, directory is read as HIBP range files named by hash prefix
+// with SUFFIX:COUNT lines, file is read as bloom filter, checker without path reports nothing
+func NewChecker(path string) (*Checker, error) {
+	if path == "" {
+		return &Checker{}, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Stat error: %w", err)
+	}
+
+	if info.IsDir() {
+		return &Checker{prefixDir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open error: %w", err)
+	}
+	defer file.Close()
+
+	bloom, err := ReadBloomFilter(file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("ReadBloomFilter error: %w", err)
+	}
+
+	return &Checker{bloom: bloom}, nil
+}
+
+// IsBreached check password is in breached list, bloom filter may have rare false positives
+func (c *Checker) IsBreached(password string) (bool, error) {
+	hash := sha1.Sum([]byte(password))
+
+	switch {
+	case c.bloom != nil:
+		return c.bloom.Test(hash), nil
+	case c.prefixDir != "":
+		return c.inRangeFile(hash)
+	default:
+		return false, nil
+	}
+}
+
+// inRangeFile look up hash suffix in range file of hash prefix, missing file means no breached hashes with prefix
+func (c *Checker) inRangeFile(hash [sha1Size]byte) (bool, error) {
+	hexHash := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := hexHash[:prefixLength], hexHash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(c.prefixDir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("os.Open error: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(lineSuffix, suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("scanner.Scan error: %w", err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,192 @@
+package directory
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInvalidCredentials password of user is wrong
+	ErrInvalidCredentials = errors.New("invalid directory credentials")
+	// ErrUserNotFound no single user entry matches email
+	ErrUserNotFound = errors.New("directory user not found")
+)
+
+// Client LDAP or Active Directory authenticator, it opens connection per login
+// because connection is rebound as user
+type Client struct {
+	cfg       *config.Config
+	tlsConfig *tls.Config
+}
+
+// NewClient create client of directory configured by LDAP settings
+func NewClient(cfg *config.Config) (*Client, error) {
+	u, err := url.Parse(cfg.LDAPURL)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse error: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+	}
+
+	if cfg.LDAPCACertPath != "" {
+		data, err := os.ReadFile(cfg.LDAPCACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile error: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s has no PEM certificates", cfg.LDAPCACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Client{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Authenticate find user entry by email with service account and bind as it with password,
+// ErrUserNotFound or ErrInvalidCredentials is returned if user can't log in
+func (c *Client) Authenticate(ctx context.Context, email, password string) (*models.DirectoryAccount, error) {
+	// empty password makes unauthenticated bind which succeeds on many servers
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// go-ldap has no context support so connection is closed to abort requests
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if c.cfg.LDAPBindDN != "" {
+		err = conn.Bind(c.cfg.LDAPBindDN, c.cfg.LDAPBindPassword)
+		if err != nil {
+			return nil, fmt.Errorf("conn.Bind service error: %w", err)
+		}
+	}
+
+	attributes := []string{c.cfg.LDAPEmailAttribute, c.cfg.LDAPFirstNameAttribute, c.cfg.LDAPLastNameAttribute, c.cfg.LDAPGroupAttribute}
+	if c.cfg.LDAPDepartmentAttribute != "" {
+		attributes = append(attributes, c.cfg.LDAPDepartmentAttribute)
+	}
+
+	// size limit 2 is enough to detect ambiguous filter
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.cfg.LDAPBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(c.cfg.LDAPTimeout.Seconds()),
+		false,
+		strings.ReplaceAll(c.cfg.LDAPUserFilter, "%s", ldap.EscapeFilter(email)),
+		attributes,
+		nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conn.Search error: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+	entry := result.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conn.Bind user error: %w", err)
+	}
+
+	account := &models.DirectoryAccount{
+		DN:        entry.DN,
+		Email:     strings.TrimSpace(strings.ToLower(entry.GetAttributeValue(c.cfg.LDAPEmailAttribute))),
+		FirstName: strings.TrimSpace(entry.GetAttributeValue(c.cfg.LDAPFirstNameAttribute)),
+		LastName:  strings.TrimSpace(entry.GetAttributeValue(c.cfg.LDAPLastNameAttribute)),
+		Role:      c.groupRole(entry.GetAttributeValues(c.cfg.LDAPGroupAttribute)),
+	}
+	if c.cfg.LDAPDepartmentAttribute != "" {
+		account.Department = strings.TrimSpace(entry.GetAttributeValue(c.cfg.LDAPDepartmentAttribute))
+	}
+
+	return account, nil
+}
+
+// connect dial directory and upgrade connection by StartTLS if it is configured
+func (c *Client) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(c.cfg.LDAPURL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: c.cfg.LDAPTimeout}),
+		ldap.DialWithTLSConfig(c.tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap.DialURL error: %w", err)
+	}
+	conn.SetTimeout(c.cfg.LDAPTimeout)
+
+	if c.cfg.LDAPStartTLS {
+		err = conn.StartTLS(c.tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("conn.StartTLS error: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// groupRole role of first configured group user is member of, DNs are compared case insensitive
+func (c *Client) groupRole(groups []string) string {
+	memberOf := make([]*ldap.DN, 0, len(groups))
+	for _, group := range groups {
+		dn, err := ldap.ParseDN(group)
+		if err != nil {
+			continue
+		}
+		memberOf = append(memberOf, dn)
+	}
+
+	for _, mapping := range c.cfg.LDAPGroupRoles {
+		dn, err := ldap.ParseDN(mapping.Group)
+		if err != nil {
+			continue
+		}
+		for _, group := range memberOf {
+			if dn.EqualFold(group) {
+				return mapping.Role
+			}
+		}
+	}
+
+	return ""
+}
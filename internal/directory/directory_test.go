@@ -0,0 +1,273 @@
+package directory
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"errors"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// application tags of LDAP protocol operations
+const (
+	tagBindRequest   = 0
+	tagBindResponse  = 1
+	tagUnbindRequest = 2
+	tagSearchRequest = 3
+	tagSearchEntry   = 4
+	tagSearchDone    = 5
+)
+
+const (
+	testBaseDN      = "dc=example,dc=com"
+	serviceDN       = "cn=service,dc=example,dc=com"
+	servicePassword = "service-secret"
+)
+
+// stubEntry entry of stub directory
+type stubEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// stubServer in-process LDAP server answering bind and search of entries
+type stubServer struct {
+	entries []stubEntry
+
+	mu      sync.Mutex
+	filters []string
+}
+
+// start serve LDAP on local port until test ends, url of server is returned
+func (s *stubServer) start(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return "ldap://" + listener.Addr().String()
+}
+
+// serve handle requests of connection until unbind
+func (s *stubServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case tagBindRequest:
+			code := int64(ldap.LDAPResultInvalidCredentials)
+			if s.bind(op.Children[1].Data.String(), op.Children[2].Data.String()) {
+				code = ldap.LDAPResultSuccess
+			}
+			conn.Write(result(id, tagBindResponse, code).Bytes())
+		case tagSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			s.mu.Lock()
+			s.filters = append(s.filters, filter)
+			s.mu.Unlock()
+
+			for _, entry := range s.entries {
+				if strings.Contains(filter, "(mail="+entry.attrs["mail"][0]+")") {
+					conn.Write(searchEntry(id, entry).Bytes())
+				}
+			}
+			conn.Write(result(id, tagSearchDone, ldap.LDAPResultSuccess).Bytes())
+		case tagUnbindRequest:
+			return
+		}
+	}
+}
+
+// bind check credentials of service account or entry
+func (s *stubServer) bind(dn, password string) bool {
+	if dn == serviceDN && password == servicePassword {
+		return true
+	}
+	for _, entry := range s.entries {
+		if entry.dn == dn && entry.password == password {
+			return true
+		}
+	}
+
+	return false
+}
+
+// result LDAP result message with code
+func result(id int64, tag ber.Tag, code int64) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+
+	return message(id, response)
+}
+
+// searchEntry LDAP search result entry with all attributes of entry
+func searchEntry(id int64, entry stubEntry) *ber.Packet {
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range entry.attrs {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tagSearchEntry, nil, "")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, ""))
+	response.AppendChild(attributes)
+
+	return message(id, response)
+}
+
+// message LDAP message of response to request with id
+func message(id int64, response *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	packet.AppendChild(response)
+
+	return packet
+}
+
+func newTestClient(t *testing.T, server *stubServer) (*Client, *config.Config) {
+	t.Helper()
+
+	cfg := &config.Config{
+		LDAPURL:                 server.start(t),
+		LDAPBindDN:              serviceDN,
+		LDAPBindPassword:        servicePassword,
+		LDAPBaseDN:              testBaseDN,
+		LDAPUserFilter:          "(&(objectClass=person)(mail=%s))",
+		LDAPEmailAttribute:      "mail",
+		LDAPFirstNameAttribute:  "givenName",
+		LDAPLastNameAttribute:   "sn",
+		LDAPGroupAttribute:      "memberOf",
+		LDAPDepartmentAttribute: "department",
+		LDAPTimeout:             5 * time.Second,
+		LDAPGroupRoles: []models.GroupRole{
+			{Group: "cn=developers,ou=groups,dc=example,dc=com", Role: "developer"},
+			{Group: "cn=admins,ou=groups,dc=example,dc=com", Role: "admin"},
+		},
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	return client, cfg
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := &stubServer{entries: []stubEntry{
+		{
+			dn:       "uid=ann,ou=people,dc=example,dc=com",
+			password: "ann-secret",
+			attrs: map[string][]string{
+				"mail":       {"Ann@Example.com"},
+				"givenName":  {" Ann "},
+				"sn":         {"Lee"},
+				"department": {"Engineering"},
+				// first configured group wins regardless of order and case of groups of user
+				"memberOf": {"CN=Admins,OU=Groups,DC=example,DC=com", "cn=developers,ou=groups,dc=example,dc=com"},
+			},
+		},
+		{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob-secret",
+			attrs:    map[string][]string{"mail": {"bob@example.com"}, "memberOf": {"cn=sales,ou=groups,dc=example,dc=com"}},
+		},
+		{dn: "uid=twin1,dc=example,dc=com", password: "secret", attrs: map[string][]string{"mail": {"twin@example.com"}}},
+		{dn: "uid=twin2,dc=example,dc=com", password: "secret", attrs: map[string][]string{"mail": {"twin@example.com"}}},
+	}}
+	client, _ := newTestClient(t, server)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		account  *models.DirectoryAccount
+		err      error
+	}{
+		{
+			name:     "mapped group",
+			email:    "Ann@Example.com",
+			password: "ann-secret",
+			account: &models.DirectoryAccount{
+				DN: "uid=ann,ou=people,dc=example,dc=com", Email: "ann@example.com", FirstName: "Ann", LastName: "Lee",
+				Department: "Engineering", Role: "developer",
+			},
+		},
+		{
+			name:     "no mapped group",
+			email:    "bob@example.com",
+			password: "bob-secret",
+			account:  &models.DirectoryAccount{DN: "uid=bob,ou=people,dc=example,dc=com", Email: "bob@example.com"},
+		},
+		{name: "wrong password", email: "bob@example.com", password: "ann-secret", err: ErrInvalidCredentials},
+		{name: "empty password", email: "bob@example.com", err: ErrInvalidCredentials},
+		{name: "unknown user", email: "carol@example.com", password: "secret", err: ErrUserNotFound},
+		{name: "ambiguous email", email: "twin@example.com", password: "secret", err: ErrUserNotFound},
+		{name: "filter injection", email: "*)(uid=*", password: "secret", err: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := client.Authenticate(context.Background(), tt.email, tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.account != nil && *account != *tt.account {
+				t.Errorf("account = %+v, want %+v", account, tt.account)
+			}
+		})
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for _, filter := range server.filters {
+		if strings.Contains(filter, "(uid=*)") {
+			t.Errorf("filter %s isn't escaped", filter)
+		}
+	}
+}
+
+func TestAuthenticateServiceBindFails(t *testing.T) {
+	client, cfg := newTestClient(t, &stubServer{})
+	cfg.LDAPBindPassword = "wrong"
+
+	_, err := client.Authenticate(context.Background(), "ann@example.com", "secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("error = %v, want failure of service account", err)
+	}
+}
@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"account-service/internal/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader header of csv export, department and role columns are same as in import
+var csvHeader = []string{
+	"id", "email", "first_name", "last_name", "department_id", "department", "role_id", "role",
+	"is_registered", "email_verified", "created_at", "updated_at", "deactivated_at",
+}
+
+// Repository repository which reads exported users
+type Repository interface {
+	StreamUsers(filter models.UserFilter, fn func(user *models.ExportedUser) error) error
+}
+
+// userWriter writer of exported users in format
+type userWriter interface {
+	Write(user *models.ExportedUser) error
+	Flush() error
+}
+
+// Export write users matched by filter to w in csv or ndjson, users are written while they are read
+// so memory doesn't depend on count of users, count of written users is returned
+func Export(db Repository, w io.Writer, filter models.UserFilter, format string) (int, error) {
+	var writer userWriter
+	switch format {
+	case models.FormatCSV:
+		writer = newCSVWriter(w)
+	case models.FormatNDJSON:
+		writer = newNDJSONWriter(w)
+	default:
+		return 0, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	count := 0
+	err := db.StreamUsers(filter, func(user *models.ExportedUser) error {
+		count++
+		return writer.Write(user)
+	})
+	if err != nil {
+		return count, fmt.Errorf("db.StreamUsers error: %w", err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// ContentType content type and file extension of format
+func ContentType(format string) (string, string) {
+	if format == models.FormatNDJSON {
+		return "application/x-ndjson", "ndjson"
+	}
+
+	return "text/csv", "csv"
+}
+
+// csvWriter writer of csv rows with header
+type csvWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+// newCSVWriter create csv writer
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+// Write write header before first user and row of user
+func (w *csvWriter) Write(user *models.ExportedUser) error {
+	err := w.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	var deactivatedAt string
+	if user.DeactivatedAt != nil {
+		deactivatedAt = user.DeactivatedAt.UTC().Format(time.RFC3339)
+	}
+
+	err = w.writer.Write([]string{
+		user.ID,
+		user.Email,
+		user.FirstName,
+		user.LastName,
+		strconv.FormatUint(uint64(user.DepartmentID), 10),
+		user.DepartmentName,
+		strconv.FormatUint(uint64(user.RoleID), 10),
+		user.RoleName,
+		strconv.FormatBool(user.IsRegistered),
+		strconv.FormatBool(user.EmailVerified),
+		user.CreatedAt.UTC().Format(time.RFC3339),
+		user.UpdatedAt.UTC().Format(time.RFC3339),
+		deactivatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("w.writer.Write error: %w", err)
+	}
+
+	return nil
+}
+
+// Flush write header if there are no users and flush buffered rows
+func (w *csvWriter) Flush() error {
+	err := w.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	w.writer.Flush()
+	err = w.writer.Error()
+	if err != nil {
+		return fmt.Errorf("w.writer.Flush error: %w", err)
+	}
+
+	return nil
+}
+
+// writeHeader write header once
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+
+	err := w.writer.Write(csvHeader)
+	if err != nil {
+		return fmt.Errorf("w.writer.Write header error: %w", err)
+	}
+	w.headerWritten = true
+
+	return nil
+}
+
+// ndjsonWriter writer of json object on each line
+type ndjsonWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+// newNDJSONWriter create ndjson writer
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, encoder: json.NewEncoder(buf)}
+}
+
+// Write write user as json line
+func (w *ndjsonWriter) Write(user *models.ExportedUser) error {
+	err := w.encoder.Encode(user)
+	if err != nil {
+		return fmt.Errorf("w.encoder.Encode error: %w", err)
+	}
+
+	return nil
+}
+
+// Flush flush buffered lines
+func (w *ndjsonWriter) Flush() error {
+	err := w.buf.Flush()
+	if err != nil {
+		return fmt.Errorf("w.buf.Flush error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package federation
+
+import (
+	"account-service/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// keysTTL how long fetched key set of provider is used
+	keysTTL = time.Hour
+	// fetchTimeout timeout of requests to providers
+	fetchTimeout = 10 * time.Second
+)
+
+// ErrUnknownProvider provider is not configured
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Providers file with configured external providers
+type Providers struct {
+	Providers []Provider `json:"providers"`
+}
+
+// Provider configuration of external OpenID Connect provider
+type Provider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Issuer      string `json:"issuer"`
+	ClientID    string `json:"client_id"`
+	// JWKSURL url of key set, it is discovered from issuer if empty
+	JWKSURL string   `json:"jwks_url,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	// Algorithms allowed signing algorithms of id tokens, RS256 if empty
+	Algorithms []string `json:"algorithms,omitempty"`
+	// RequireNonce reject id tokens without nonce of login request
+	RequireNonce bool `json:"require_nonce"`
+	// AllowedDomains email domains of users allowed to log in, any domain if empty
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+
+	// EmailClaim claim with email, email if empty
+	EmailClaim string `json:"email_claim,omitempty"`
+	// FirstNameClaim claim with first name, given_name if empty
+	FirstNameClaim string `json:"first_name_claim,omitempty"`
+	// LastNameClaim claim with last name, family_name if empty
+	LastNameClaim string `json:"last_name_claim,omitempty"`
+
+	// LinkByEmail link existing account with same verified email on first login
+	LinkByEmail bool `json:"link_by_email"`
+	// DefaultDepartment name of department of provisioned users
+	DefaultDepartment string `json:"default_department"`
+	// DefaultRole name of role of provisioned users, employee if empty
+	DefaultRole string `json:"default_role"`
+}
+
+// LoadProviders read providers from JSON file, empty path means there are no providers
+func LoadProviders(path string) ([]Provider, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile error: %w", err)
+	}
+
+	var file Providers
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	seen := make(map[string]bool, len(file.Providers))
+	for i := range file.Providers {
+		provider := &file.Providers[i]
+		provider.Issuer = strings.TrimSuffix(provider.Issuer, "/")
+		if provider.Name == "" || provider.Issuer == "" || provider.ClientID == "" || provider.DefaultDepartment == "" {
+			return nil, fmt.Errorf("provider %d: name, issuer, client_id and default_department are required", i)
+		}
+		if provider.DefaultRole == "" {
+			provider.DefaultRole = models.EmployeeRole
+		}
+		if seen[provider.Name] {
+			return nil, fmt.Errorf("provider %s is duplicated", provider.Name)
+		}
+		seen[provider.Name] = true
+	}
+
+	return file.Providers, nil
+}
+
+// Federation verifier of id tokens of configured external providers
+type Federation struct {
+	verifiers map[string]*Verifier
+	order     []string
+}
+
+// New create federation with key sets of providers fetched by client and cached
+func New(providers []Provider, client *http.Client) *Federation {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+
+	verifiers := make([]*Verifier, 0, len(providers))
+	for _, provider := range providers {
+		keys := NewCachedKeys(&HTTPKeySource{Client: client, Issuer: provider.Issuer, URL: provider.JWKSURL}, keysTTL)
+		verifiers = append(verifiers, NewVerifier(provider, keys))
+	}
+
+	return NewFromVerifiers(verifiers...)
+}
+
+// NewFromVerifiers create federation of verifiers, key sources of verifiers can be replaced in tests
+func NewFromVerifiers(verifiers ...*Verifier) *Federation {
+	f := &Federation{verifiers: make(map[string]*Verifier, len(verifiers))}
+	for _, verifier := range verifiers {
+		f.verifiers[verifier.provider.Name] = verifier
+		f.order = append(f.order, verifier.provider.Name)
+	}
+
+	return f
+}
+
+// Providers configured providers in order of configuration
+func (f *Federation) Providers() []models.IdentityProvider {
+	providers := make([]models.IdentityProvider, 0, len(f.order))
+	for _, name := range f.order {
+		providers = append(providers, f.verifiers[name].provider.info())
+	}
+
+	return providers
+}
+
+// Provider configured provider by name
+func (f *Federation) Provider(name string) (*models.IdentityProvider, error) {
+	verifier, ok := f.verifiers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	info := verifier.provider.info()
+
+	return &info, nil
+}
+
+// Verify verify id token of provider and get identity of user
+func (f *Federation) Verify(ctx context.Context, provider, idToken, nonce string) (*models.ExternalIdentity, error) {
+	verifier, ok := f.verifiers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return verifier.Verify(ctx, idToken, nonce)
+}
+
+// info public information and provisioning settings of provider
+func (p *Provider) info() models.IdentityProvider {
+	displayName := p.DisplayName
+	if displayName == "" {
+		displayName = p.Name
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	return models.IdentityProvider{
+		Name:              p.Name,
+		DisplayName:       displayName,
+		Issuer:            p.Issuer,
+		ClientID:          p.ClientID,
+		Scopes:            scopes,
+		LinkByEmail:       p.LinkByEmail,
+		DefaultDepartment: p.DefaultDepartment,
+		DefaultRole:       p.DefaultRole,
+	}
+}
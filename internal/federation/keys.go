@@ -0,0 +1,242 @@
+package federation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxKeySetSize max size of response with key set or provider metadata
+	maxKeySetSize = 1024 * 1024
+	// minRefreshInterval min time between fetches of key set when token is signed by unknown key
+	minRefreshInterval = time.Minute
+)
+
+// KeySource source of public keys of provider by key id
+type KeySource interface {
+	Keys(ctx context.Context) (map[string]interface{}, error)
+}
+
+// StaticKeys fixed key set, it is used in tests instead of fetched key set
+type StaticKeys map[string]interface{}
+
+// Keys keys of set
+func (k StaticKeys) Keys(context.Context) (map[string]interface{}, error) {
+	return k, nil
+}
+
+// HTTPKeySource key set fetched from url, url is discovered from provider metadata of issuer if it is empty
+type HTTPKeySource struct {
+	Client *http.Client
+	Issuer string
+	URL    string
+}
+
+// Keys fetch key set, keys which are not used for signatures or have unsupported type are skipped
+func (s *HTTPKeySource) Keys(ctx context.Context) (map[string]interface{}, error) {
+	keysURL := s.URL
+	if keysURL == "" {
+		var metadata struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		err := s.getJSON(ctx, s.Issuer+"/.well-known/openid-configuration", &metadata)
+		if err != nil {
+			return nil, fmt.Errorf("s.getJSON metadata error: %w", err)
+		}
+		if metadata.Issuer != s.Issuer || metadata.JWKSURI == "" {
+			return nil, fmt.Errorf("metadata of %s has other issuer or no jwks_uri", s.Issuer)
+		}
+		keysURL = metadata.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := s.getJSON(ctx, keysURL, &set)
+	if err != nil {
+		return nil, fmt.Errorf("s.getJSON keys error: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// getJSON get json document by url
+func (s *HTTPKeySource) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext error: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s.Client.Do error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxKeySetSize)).Decode(v)
+	if err != nil {
+		return fmt.Errorf("json.Decode error: %w", err)
+	}
+
+	return nil
+}
+
+// jsonWebKey public key of RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey RSA or ECDSA public key of jwk
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("exponent is too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// decodeInt integer of base64url encoded big-endian bytes
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("base64.DecodeString error: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("value is empty")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// CachedKeys key set of source cached for ttl, set is fetched again earlier
+// if token is signed by unknown key because provider rotated keys
+type CachedKeys struct {
+	source KeySource
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// NewCachedKeys create cache of key set of source
+func NewCachedKeys(source KeySource, ttl time.Duration) *CachedKeys {
+	return &CachedKeys{
+		source: source,
+		ttl:    ttl,
+	}
+}
+
+// Key public key by key id, only key of set is returned if key id is empty
+func (c *CachedKeys) Key(ctx context.Context, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	age := time.Since(c.fetchedAt)
+	key, found := lookupKey(c.keys, kid)
+	if c.keys == nil || age > c.ttl || (!found && age > minRefreshInterval) {
+		keys, err := c.source.Keys(ctx)
+		if err != nil {
+			// stale keys are used while provider is unavailable
+			if found {
+				return key, nil
+			}
+			return nil, fmt.Errorf("c.source.Keys error: %w", err)
+		}
+		c.keys = keys
+		c.fetchedAt = time.Now()
+		key, found = lookupKey(c.keys, kid)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("key %q is not found", kid)
+	}
+
+	return key, nil
+}
+
+// lookupKey key of set by key id
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+
+	return key, ok
+}
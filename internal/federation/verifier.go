@@ -0,0 +1,177 @@
+package federation
+
+import (
+	"account-service/internal/models"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"strings"
+	"time"
+)
+
+// clockSkew allowed difference of clocks of provider and service
+const clockSkew = time.Minute
+
+// KeyResolver resolve public key of provider by key id
+type KeyResolver interface {
+	Key(ctx context.Context, kid string) (interface{}, error)
+}
+
+// Verifier verifier of id tokens of one provider
+type Verifier struct {
+	provider Provider
+	keys     KeyResolver
+}
+
+// NewVerifier create verifier of id tokens of provider signed by keys
+func NewVerifier(provider Provider, keys KeyResolver) *Verifier {
+	provider.Issuer = strings.TrimSuffix(provider.Issuer, "/")
+	if len(provider.Algorithms) == 0 {
+		provider.Algorithms = []string{jwt.SigningMethodRS256.Alg()}
+	}
+	if provider.EmailClaim == "" {
+		provider.EmailClaim = "email"
+	}
+	if provider.FirstNameClaim == "" {
+		provider.FirstNameClaim = "given_name"
+	}
+	if provider.LastNameClaim == "" {
+		provider.LastNameClaim = "family_name"
+	}
+
+	return &Verifier{
+		provider: provider,
+		keys:     keys,
+	}
+}
+
+// Verify check signature, issuer, audience, lifetime and nonce of id token and map its claims to identity,
+// nonce of token must match nonce of login request if either of them is present
+func (v *Verifier) Verify(ctx context.Context, idToken, nonce string) (*models.ExternalIdentity, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: v.provider.Algorithms, SkipClaimsValidation: true}
+
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parser.ParseWithClaims error: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.provider.Issuer {
+		return nil, fmt.Errorf("issuer %q is not %q", iss, v.provider.Issuer)
+	}
+
+	if !v.audienceValid(claims) {
+		return nil, fmt.Errorf("token is not issued for client %s", v.provider.ClientID)
+	}
+
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true) {
+		return nil, fmt.Errorf("token is expired")
+	}
+	if !claims.VerifyIssuedAt(now.Add(clockSkew).Unix(), true) {
+		return nil, fmt.Errorf("token is issued in future")
+	}
+	if !claims.VerifyNotBefore(now.Add(clockSkew).Unix(), false) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if nonce == "" && v.provider.RequireNonce {
+		return nil, fmt.Errorf("nonce is required")
+	}
+	if (nonce != "" || tokenNonce != "") && subtle.ConstantTimeCompare([]byte(nonce), []byte(tokenNonce)) != 1 {
+		return nil, fmt.Errorf("nonce doesn't match")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	email, _ := claims[v.provider.EmailClaim].(string)
+	email = strings.TrimSpace(strings.ToLower(email))
+	if !v.domainAllowed(email) {
+		return nil, fmt.Errorf("email domain of %q is not allowed", email)
+	}
+
+	firstName, _ := claims[v.provider.FirstNameClaim].(string)
+	lastName, _ := claims[v.provider.LastNameClaim].(string)
+
+	return &models.ExternalIdentity{
+		Provider:      v.provider.Name,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: boolClaim(claims["email_verified"]),
+		FirstName:     strings.TrimSpace(firstName),
+		LastName:      strings.TrimSpace(lastName),
+	}, nil
+}
+
+// audienceValid check client is audience of token, authorized party must be client if token has several audiences
+func (v *Verifier) audienceValid(claims jwt.MapClaims) bool {
+	var audiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []string{aud}
+	case []interface{}:
+		for _, item := range aud {
+			if value, ok := item.(string); ok {
+				audiences = append(audiences, value)
+			}
+		}
+	}
+
+	found := false
+	for _, audience := range audiences {
+		if audience == v.provider.ClientID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	azp, hasAzp := claims["azp"].(string)
+	if len(audiences) > 1 || hasAzp {
+		return azp == v.provider.ClientID
+	}
+
+	return true
+}
+
+// domainAllowed check domain of email is allowed for provider
+func (v *Verifier) domainAllowed(email string) bool {
+	if len(v.provider.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range v.provider.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boolClaim boolean claim, some providers send booleans as strings
+func boolClaim(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
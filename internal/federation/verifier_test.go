@@ -0,0 +1,280 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testClientID = "account-service"
+	testKeyID    = "key-1"
+)
+
+func newTestVerifier(t *testing.T, provider Provider) (*Verifier, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey error: %v", err)
+	}
+
+	provider.Name = "idp"
+	provider.Issuer = testIssuer + "/"
+	provider.ClientID = testClientID
+
+	keys := NewCachedKeys(StaticKeys{testKeyID: &key.PublicKey}, time.Hour)
+
+	return NewVerifier(provider, keys), key
+}
+
+// validClaims claims of token accepted by verifier of test provider
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"sub":            "subject-1",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          "nonce-1",
+		"email":          " Bob@Example.com ",
+		"email_verified": "true",
+		"given_name":     "Bob",
+		"family_name":    "Smith",
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("token.SignedString error: %v", err)
+	}
+
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	verifier, key := newTestVerifier(t, Provider{})
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(claims jwt.MapClaims)
+		// token signs changed claims, by default they are signed by provider key
+		token func(claims jwt.MapClaims) string
+		nonce string
+		valid bool
+	}{
+		{name: "valid", nonce: "nonce-1", valid: true},
+		{
+			name:   "audience list with authorized party",
+			change: func(c jwt.MapClaims) { c["aud"] = []interface{}{"other", testClientID}; c["azp"] = testClientID },
+			nonce:  "nonce-1",
+			valid:  true,
+		},
+		{
+			name:   "expired within clock skew",
+			change: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-clockSkew / 2).Unix() },
+			nonce:  "nonce-1",
+			valid:  true,
+		},
+		{
+			name:  "key id is optional with single key",
+			token: func(c jwt.MapClaims) string { return sign(t, jwt.SigningMethodRS256, key, "", c) },
+			nonce: "nonce-1",
+			valid: true,
+		},
+		{
+			name:   "other issuer",
+			change: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "issuer with trailing slash",
+			change: func(c jwt.MapClaims) { c["iss"] = testIssuer + "/" },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "other audience",
+			change: func(c jwt.MapClaims) { c["aud"] = "other" },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "audience list without authorized party",
+			change: func(c jwt.MapClaims) { c["aud"] = []interface{}{"other", testClientID} },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "authorized party is other client",
+			change: func(c jwt.MapClaims) { c["azp"] = "other" },
+			nonce:  "nonce-1",
+		},
+		{
+			name:  "nonce doesn't match",
+			nonce: "nonce-2",
+		},
+		{
+			name:   "nonce of request is missing in token",
+			change: func(c jwt.MapClaims) { delete(c, "nonce") },
+			nonce:  "nonce-1",
+		},
+		{
+			name: "nonce of token without nonce of request",
+		},
+		{
+			name:   "expired",
+			change: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "without expiry",
+			change: func(c jwt.MapClaims) { delete(c, "exp") },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "issued in future",
+			change: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(2 * clockSkew).Unix() },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "not valid yet",
+			change: func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(2 * clockSkew).Unix() },
+			nonce:  "nonce-1",
+		},
+		{
+			name:   "without subject",
+			change: func(c jwt.MapClaims) { delete(c, "sub") },
+			nonce:  "nonce-1",
+		},
+		{
+			name:  "signed by other key",
+			token: func(c jwt.MapClaims) string { return sign(t, jwt.SigningMethodRS256, other, testKeyID, c) },
+			nonce: "nonce-1",
+		},
+		{
+			name:  "unknown key id",
+			token: func(c jwt.MapClaims) string { return sign(t, jwt.SigningMethodRS256, key, "key-2", c) },
+			nonce: "nonce-1",
+		},
+		{
+			name:  "algorithm is not allowed",
+			token: func(c jwt.MapClaims) string { return sign(t, jwt.SigningMethodRS512, key, testKeyID, c) },
+			nonce: "nonce-1",
+		},
+		{
+			name: "unsigned",
+			token: func(c jwt.MapClaims) string {
+				return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, testKeyID, c)
+			},
+			nonce: "nonce-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+
+			var token string
+			if tt.token != nil {
+				token = tt.token(claims)
+			} else {
+				token = sign(t, jwt.SigningMethodRS256, key, testKeyID, claims)
+			}
+
+			identity, err := verifier.Verify(context.Background(), token, tt.nonce)
+			if tt.valid && err != nil {
+				t.Fatalf("Verify error: %v", err)
+			}
+			if !tt.valid {
+				if err == nil {
+					t.Fatalf("Verify accepted token")
+				}
+				return
+			}
+
+			if identity.Provider != "idp" || identity.Subject != "subject-1" || identity.Email != "bob@example.com" ||
+				!identity.EmailVerified || identity.FirstName != "Bob" || identity.LastName != "Smith" {
+				t.Errorf("identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyProviderSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		nonce    string
+		change   func(claims jwt.MapClaims)
+		valid    bool
+	}{
+		{
+			name:     "nonce is required",
+			provider: Provider{RequireNonce: true},
+			change:   func(c jwt.MapClaims) { delete(c, "nonce") },
+		},
+		{
+			name:     "allowed domain",
+			provider: Provider{AllowedDomains: []string{"EXAMPLE.com"}},
+			nonce:    "nonce-1",
+			valid:    true,
+		},
+		{
+			name:     "domain is not allowed",
+			provider: Provider{AllowedDomains: []string{"example.org"}},
+			nonce:    "nonce-1",
+		},
+		{
+			name:     "subdomain is not allowed",
+			provider: Provider{AllowedDomains: []string{"example.com"}},
+			nonce:    "nonce-1",
+			change:   func(c jwt.MapClaims) { c["email"] = "bob@mail.example.com" },
+		},
+		{
+			name:     "custom email claim",
+			provider: Provider{EmailClaim: "upn", AllowedDomains: []string{"corp.example.com"}},
+			nonce:    "nonce-1",
+			change:   func(c jwt.MapClaims) { c["upn"] = "bob@corp.example.com" },
+			valid:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, key := newTestVerifier(t, tt.provider)
+
+			claims := validClaims()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+
+			_, err := verifier.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, key, testKeyID, claims), tt.nonce)
+			if tt.valid && err != nil {
+				t.Fatalf("Verify error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("Verify accepted token")
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package hasher
+
+import (
+	"fmt"
+	"github.com/matthewhartstonge/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// bcryptPrefixes prefixes of bcrypt hashes, such hashes come from imports of legacy systems
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// Params parameters of argon2id hashes of new passwords
+type Params struct {
+	// Memory memory cost in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams argon2id parameters recommended by RFC 9106 for memory constrained environments
+func DefaultParams() Params {
+	config := argon2.MemoryConstrainedDefaults()
+
+	return Params{
+		Memory:      config.MemoryCost,
+		Iterations:  config.TimeCost,
+		Parallelism: config.Parallelism,
+		SaltLength:  config.SaltLength,
+		KeyLength:   config.HashLength,
+	}
+}
+
+// Hasher hash passwords with current parameters and verify argon2 and legacy bcrypt hashes
+type Hasher struct {
+	config argon2.Config
+}
+
+// NewHasher create hasher with argon2id parameters
+func NewHasher(params Params) (*Hasher, error) {
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, fmt.Errorf("argon2 memory, iterations and parallelism must be positive")
+	}
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("argon2 memory must be at least 8 KiB per thread")
+	}
+	if params.SaltLength < 8 || params.KeyLength < 16 {
+		return nil, fmt.Errorf("argon2 salt must be at least 8 bytes and key at least 16 bytes")
+	}
+
+	return &Hasher{
+		config: argon2.Config{
+			HashLength:  params.KeyLength,
+			SaltLength:  params.SaltLength,
+			TimeCost:    params.Iterations,
+			MemoryCost:  params.Memory,
+			Parallelism: params.Parallelism,
+			Mode:        argon2.ModeArgon2id,
+			Version:     argon2.Version13,
+		},
+	}, nil
+}
+
+// Hash hash password with current parameters
+func (h *Hasher) Hash(password string) (string, error) {
+	encoded, err := h.config.HashEncoded([]byte(password))
+	if err != nil {
+		return "", fmt.Errorf("h.config.HashEncoded error: %w", err)
+	}
+
+	return string(encoded), nil
+}
+
+// Verify check password matches argon2 or bcrypt hash
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("bcrypt.CompareHashAndPassword error: %w", err)
+		}
+
+		return true, nil
+	}
+
+	isValid, err := argon2.VerifyEncoded([]byte(password), []byte(hash))
+	if err != nil {
+		return false, fmt.Errorf("argon2.VerifyEncoded error: %w", err)
+	}
+
+	return isValid, nil
+}
+
+// NeedsRehash check hash uses legacy algorithm or parameters weaker than current ones
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if isBcrypt(hash) {
+		return true
+	}
+
+	raw, err := argon2.Decode([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return raw.Config.Mode != h.config.Mode ||
+		raw.Config.Version < h.config.Version ||
+		raw.Config.MemoryCost < h.config.MemoryCost ||
+		raw.Config.TimeCost < h.config.TimeCost ||
+		uint32(len(raw.Salt)) < h.config.SaltLength ||
+		uint32(len(raw.Hash)) < h.config.HashLength
+}
+
+// IsSupported check hash is argon2 or bcrypt hash which can be verified
+func IsSupported(hash string) bool {
+	if isBcrypt(hash) {
+		_, err := bcrypt.Cost([]byte(hash))
+		return err == nil
+	}
+
+	_, err := argon2.Decode([]byte(hash))
+	return err == nil
+}
+
+// isBcrypt check hash is bcrypt hash
+func isBcrypt(hash string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
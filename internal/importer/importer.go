@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"account-service/internal/hasher"
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxRows max rows in one import
+const MaxRows = 10000
+
+// ErrMalformed data can't be parsed as rows of format
+var ErrMalformed = errors.New("malformed import data")
+
+// requiredColumns columns which must be in csv header
+var requiredColumns = []string{"email", "first_name", "last_name", "department", "role"}
+
+// Repository repository which creates imported users
+type Repository interface {
+	ImportUsers(rows []models.ImportRow, options models.ImportOptions) (*models.ImportReport, error)
+}
+
+// Import parse and validate rows of reader and create users of valid rows
+func Import(db Repository, r io.Reader, options models.ImportOptions) (*models.ImportReport, error) {
+	rows, err := ParseRows(r, options.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+
+	seen := make(map[string]int, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		if row.Error != "" {
+			continue
+		}
+
+		err = ValidateRow(row)
+		if err != nil {
+			row.Error = err.Error()
+			continue
+		}
+
+		if line, ok := seen[row.Email]; ok {
+			row.Error = fmt.Sprintf("email is duplicate of line %d", line)
+			continue
+		}
+		seen[row.Email] = row.Line
+	}
+
+	report, err := db.ImportUsers(rows, options)
+	if err != nil {
+		return nil, fmt.Errorf("db.ImportUsers error: %w", err)
+	}
+
+	return report, nil
+}
+
+// ValidateRow normalize and validate fields of row
+func ValidateRow(row *models.ImportRow) error {
+	row.Email = strings.TrimSpace(strings.ToLower(row.Email))
+	row.FirstName = strings.TrimSpace(row.FirstName)
+	row.LastName = strings.TrimSpace(row.LastName)
+	row.Department = strings.TrimSpace(row.Department)
+	row.Role = strings.TrimSpace(row.Role)
+	row.PasswordHash = strings.TrimSpace(row.PasswordHash)
+
+	if err := validators.ValidateEmail(row.Email); err != nil {
+		return fmt.Errorf("invalid email: %w", err)
+	}
+	if err := validators.ValidateFIO(row.FirstName); err != nil {
+		return fmt.Errorf("invalid first name: %w", err)
+	}
+	if err := validators.ValidateFIO(row.LastName); err != nil {
+		return fmt.Errorf("invalid last name: %w", err)
+	}
+	if err := validators.ValidateName(row.Department); err != nil {
+		return fmt.Errorf("invalid department: %w", err)
+	}
+	if err := validators.ValidateName(row.Role); err != nil {
+		return fmt.Errorf("invalid role: %w", err)
+	}
+	if row.PasswordHash != "" && !hasher.IsSupported(row.PasswordHash) {
+		return fmt.Errorf("password hash is not argon2 or bcrypt hash")
+	}
+
+	return nil
+}
+
+// ParseRows parse rows of csv with header or ndjson, malformed rows are returned with error
+func ParseRows(r io.Reader, format string) ([]models.ImportRow, error) {
+	switch format {
+	case models.FormatCSV:
+		return parseCSV(r)
+	case models.FormatNDJSON:
+		return parseNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// parseCSV parse csv rows, columns are found by header
+func parseCSV(r io.Reader) ([]models.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s is missing in header", name)
+		}
+	}
+
+	var rows []models.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if len(rows) >= MaxRows {
+			return nil, fmt.Errorf("too many rows, max %d", MaxRows)
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, models.ImportRow{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reader.Read error: %w", err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, models.ImportRow{
+			Line:         line,
+			Email:        field("email"),
+			FirstName:    field("first_name"),
+			LastName:     field("last_name"),
+			Department:   field("department"),
+			Role:         field("role"),
+			PasswordHash: field("password_hash"),
+		})
+	}
+
+	return rows, nil
+}
+
+// parseNDJSON parse json object on each line, empty lines are skipped
+func parseNDJSON(r io.Reader) ([]models.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []models.ImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if len(rows) >= MaxRows {
+			return nil, fmt.Errorf("too many rows, max %d", MaxRows)
+		}
+
+		var row models.ImportRow
+		err := json.Unmarshal([]byte(text), &row)
+		if err != nil {
+			row = models.ImportRow{Error: fmt.Sprintf("invalid json: %s", err)}
+		}
+		row.Line = line
+
+		rows = append(rows, row)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("scanner.Scan error: %w", err)
+	}
+
+	return rows, nil
+}
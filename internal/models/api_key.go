@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix prefix of api keys which distinguishes them from access tokens
+const APIKeyPrefix = "ak_"
+
+// APIKey long-lived key of service principal, key is APIKeyPrefix, public prefix and secret
+// joined by underscore, only hash of secret is stored
+type APIKey struct {
+	ID string `gorm:"primaryKey;type:uuid" json:"id"`
+
+	Name string `json:"name"`
+	// Prefix public part of key used to find it
+	Prefix     string `gorm:"uniqueIndex" json:"prefix"`
+	SecretHash string `json:"-"`
+	// Permissions space separated permissions of key
+	Permissions string `json:"permissions"`
+	// DepartmentID department key is scoped to, 0 if key is not scoped
+	DepartmentID uint32     `json:"department_id"`
+	CreatedBy    string     `json:"created_by"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// BeforeCreate add uuid to id
+func (key *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	key.ID = uuid.NewString()
+	return
+}
+
+// TableName name of table of api keys
+func (key *APIKey) TableName() string {
+	return "api_keys"
+}
+
+// PermissionList permissions of key
+func (key *APIKey) PermissionList() []string {
+	return strings.Fields(key.Permissions)
+}
+
+// SplitAPIKey split api key into prefix and secret, false is returned if value is not api key
+func SplitAPIKey(value string) (string, string, bool) {
+	if !strings.HasPrefix(value, APIKeyPrefix) {
+		return "", "", false
+	}
+
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(value, APIKeyPrefix), "_")
+	if !found || prefix == "" || secret == "" {
+		return "", "", false
+	}
+
+	return prefix, secret, true
+}
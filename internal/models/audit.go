@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AuditEntry record of administrative call, actor and subject are pseudonymized when user is erased
+type AuditEntry struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ActorID    string `gorm:"index" json:"actor_id"`
+	ActorEmail string `json:"actor_email"`
+	SubjectID  string `gorm:"index" json:"subject_id"`
+	Method     string `json:"method"`
+	Code       string `json:"code"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
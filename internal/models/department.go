@@ -3,5 +3,6 @@ package models
 type Department struct {
 	ID uint32 `gorm:"primaryKey;type=uint32" json:"id"`
 
-	Name string `gorm:"unique" json:"name"`
+	Name     string  `gorm:"unique" json:"name"`
+	ParentID *uint32 `gorm:"index" json:"parent_id"`
 }
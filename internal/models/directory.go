@@ -0,0 +1,26 @@
+package models
+
+const (
+	// AuthLocal authenticator checking password hash stored by service
+	AuthLocal = "local"
+	// AuthLDAP authenticator binding to LDAP or Active Directory as user
+	AuthLDAP = "ldap"
+)
+
+// GroupRole role of members of directory group
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// DirectoryAccount user entry of directory authenticated by password
+type DirectoryAccount struct {
+	DN        string
+	Email     string
+	FirstName string
+	LastName  string
+	// Department name of department of user, empty if directory has no department
+	Department string
+	// Role name of role of first mapped group of user, empty if user is in no mapped group
+	Role string
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	// ErasureModeAnonymize personal data of erased user is replaced and row is kept
+	ErasureModeAnonymize = "anonymize"
+	// ErasureModePurge row of erased user is removed
+	ErasureModePurge = "purge"
+)
+
+// ErasureCandidate user which personal data is due to be erased
+type ErasureCandidate struct {
+	UserID             string
+	Email              string
+	DeletedAt          *time.Time
+	ErasureRequestedAt *time.Time
+	Tokens             int64
+	PasswordHistory    int64
+	RoleAssignments    int64
+	AuditEntries       int64
+}
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"account-service/internal/validators"
 	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"strings"
 )
 
 // BadRequestError bad request
@@ -155,6 +158,30 @@ func PasswordNotValidError(err error) error {
 	)
 }
 
+// PasswordPolicyError password violates password policy, all violations are returned as bad request details of field
+func PasswordPolicyError(field string, violations []validators.PasswordViolation) error {
+	descriptions := make([]string, 0, len(violations))
+	details := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		descriptions = append(descriptions, violation.Description)
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Rule + ": " + violation.Description,
+		})
+	}
+
+	st := status.New(
+		codes.Canceled,
+		fmt.Sprintf("Password is not valid: %s", strings.Join(descriptions, ", ")),
+	)
+	stWithDetails, err := st.WithDetails(details)
+	if err != nil {
+		return st.Err()
+	}
+
+	return stWithDetails.Err()
+}
+
 // UsernameNotValidError username iis invalid
 func UsernameNotValidError(err error) error {
 	return status.Errorf(
@@ -192,3 +219,197 @@ var AlreadyExistError = status.Errorf(
 	codes.AlreadyExists,
 	"Already exist",
 )
+
+// PermissionDeniedError user has not permission
+var PermissionDeniedError = status.Errorf(
+	codes.PermissionDenied,
+	"Permission denied",
+)
+
+// NameNotValidError name is invalid
+func NameNotValidError(err error) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		fmt.Sprintf("Name is not valid: %s", err.Error()),
+	)
+}
+
+// DepartmentInUseError department has users
+var DepartmentInUseError = status.Errorf(
+	codes.FailedPrecondition,
+	"Department has users",
+)
+
+// RoleInUseError role has users
+var RoleInUseError = status.Errorf(
+	codes.FailedPrecondition,
+	"Role has users",
+)
+
+// BuiltinRoleError built-in role can't be removed, renamed or changed
+var BuiltinRoleError = status.Errorf(
+	codes.FailedPrecondition,
+	"Built-in role can't be removed, renamed or changed",
+)
+
+// PermissionNotValidError permission is unknown
+func PermissionNotValidError(permission string) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		fmt.Sprintf("Permission is not valid: %s", permission),
+	)
+}
+
+// DepartmentCycleError department can't be moved into own subtree
+var DepartmentCycleError = status.Errorf(
+	codes.FailedPrecondition,
+	"Department can't be moved into own subtree",
+)
+
+// DepartmentHasChildrenError department has child departments
+var DepartmentHasChildrenError = status.Errorf(
+	codes.FailedPrecondition,
+	"Department has child departments",
+)
+
+// RoleAssignmentNotFoundError role assignment not exist
+var RoleAssignmentNotFoundError = status.Errorf(
+	codes.NotFound,
+	"Role assignment not exist",
+)
+
+// InvalidValidityWindowError ends at is not after starts at
+var InvalidValidityWindowError = status.Errorf(
+	codes.InvalidArgument,
+	"End of validity window must be after start",
+)
+
+// InvalidPageTokenError page token is invalid
+var InvalidPageTokenError = status.Errorf(
+	codes.InvalidArgument,
+	"Invalid page token",
+)
+
+// TooManyIDsError too many ids in request
+var TooManyIDsError = status.Errorf(
+	codes.InvalidArgument,
+	"Too many ids in request",
+)
+
+// VersionConflictError data was changed by another request
+var VersionConflictError = status.Errorf(
+	codes.Aborted,
+	"Data was changed by another request",
+)
+
+// PasswordReusedError new password is same as current or one of recent passwords
+var PasswordReusedError = status.Errorf(
+	codes.InvalidArgument,
+	"Password was used recently",
+)
+
+// AccountDeactivatedError account is deactivated
+var AccountDeactivatedError = status.Errorf(
+	codes.PermissionDenied,
+	"Account is deactivated",
+)
+
+// SelfAccountActionError administrator can't deactivate or delete own account
+var SelfAccountActionError = status.Errorf(
+	codes.FailedPrecondition,
+	"Own account can't be deactivated or deleted",
+)
+
+// InvitationNotFoundError invitation is not found or is not pending
+var InvitationNotFoundError = status.Errorf(
+	codes.NotFound,
+	"Pending invitation not found",
+)
+
+// NotificationsDisabledError notifications are not configured so invitation can't be delivered
+var NotificationsDisabledError = status.Errorf(
+	codes.FailedPrecondition,
+	"Notifications are not configured",
+)
+
+// InvitationExistError there is pending invitation for email
+var InvitationExistError = status.Errorf(
+	codes.AlreadyExists,
+	"Pending invitation for email already exist",
+)
+
+// InvitationFieldLockedError registration changes field fixed by invitation
+var InvitationFieldLockedError = status.Errorf(
+	codes.InvalidArgument,
+	"Email, department and role are fixed by invitation",
+)
+
+// OAuthClientNotFoundError oauth client is not found
+var OAuthClientNotFoundError = status.Errorf(
+	codes.NotFound,
+	"OAuth client not found",
+)
+
+// InvalidOAuthClientError registration of oauth client is invalid
+func InvalidOAuthClientError(err error) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		fmt.Sprintf("Invalid OAuth client: %s", err.Error()),
+	)
+}
+
+// ConsentNotFoundError user has no consent for client
+var ConsentNotFoundError = status.Errorf(
+	codes.NotFound,
+	"Consent not found",
+)
+
+// IdentityProviderNotFoundError external identity provider is not configured
+var IdentityProviderNotFoundError = status.Errorf(
+	codes.NotFound,
+	"Identity provider not found",
+)
+
+// InvalidIdentityTokenError id token of external provider is not valid
+var InvalidIdentityTokenError = status.Errorf(
+	codes.Unauthenticated,
+	"Invalid identity token",
+)
+
+// IdentityNotLinkedError account with email of external identity exists but identity is not linked to it
+var IdentityNotLinkedError = status.Errorf(
+	codes.FailedPrecondition,
+	"Account with email already exists, log in and link identity",
+)
+
+// IdentityAlreadyLinkedError external identity is linked to another account
+var IdentityAlreadyLinkedError = status.Errorf(
+	codes.AlreadyExists,
+	"Identity is already linked",
+)
+
+// LinkedIdentityNotFoundError linked identity is not found
+var LinkedIdentityNotFoundError = status.Errorf(
+	codes.NotFound,
+	"Linked identity not found",
+)
+
+// LastLoginMethodError identity can't be unlinked because user has no password or other identity
+var LastLoginMethodError = status.Errorf(
+	codes.FailedPrecondition,
+	"Last login method can't be unlinked",
+)
+
+// APIKeyNotFoundError api key is not found
+var APIKeyNotFoundError = status.Errorf(
+	codes.NotFound,
+	"API key not found",
+)
+
+// InvalidAPIKeyError request of api key is invalid
+func InvalidAPIKeyError(err error) error {
+	return status.Errorf(
+		codes.InvalidArgument,
+		fmt.Sprintf("Invalid API key: %s", err.Error()),
+	)
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ExportedUser user with names of department and role for bulk export
+type ExportedUser struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	DepartmentID   uint32     `json:"department_id"`
+	DepartmentName string     `json:"department"`
+	RoleID         uint32     `json:"role_id"`
+	RoleName       string     `json:"role"`
+	IsRegistered   bool       `json:"is_registered"`
+	EmailVerified  bool       `json:"email_verified"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeactivatedAt  *time.Time `json:"deactivated_at,omitempty"`
+}
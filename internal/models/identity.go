@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// LinkedIdentity identity of user at external OpenID Connect provider, user can have several linked identities
+// in addition to password
+type LinkedIdentity struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID   string `gorm:"type:uuid;index" json:"user_id"`
+	Provider string `gorm:"uniqueIndex:idx_linked_identity_provider_subject" json:"provider"`
+	Subject  string `gorm:"uniqueIndex:idx_linked_identity_provider_subject" json:"subject"`
+	// Email email of identity at provider when identity is linked
+	Email string `json:"email"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// ExternalIdentity user claims of verified id token of external provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// IdentityProvider external OpenID Connect provider users can log in with
+type IdentityProvider struct {
+	Name        string
+	DisplayName string
+	Issuer      string
+	ClientID    string
+	Scopes      []string
+
+	// LinkByEmail provider is trusted to link existing account with same verified email on first login
+	LinkByEmail bool
+	// DefaultDepartment name of department of provisioned users
+	DefaultDepartment string
+	// DefaultRole name of role of provisioned users
+	DefaultRole string
+}
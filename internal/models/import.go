@@ -0,0 +1,65 @@
+package models
+
+const (
+	// FormatCSV comma separated values with header row
+	FormatCSV = "csv"
+	// FormatNDJSON json object on each line
+	FormatNDJSON = "ndjson"
+)
+
+const (
+	// ImportCreated user is created
+	ImportCreated = "created"
+	// ImportValid user would be created, used in dry run
+	ImportValid = "valid"
+	// ImportFailed row is invalid or user can't be created
+	ImportFailed = "failed"
+	// ImportSkipped row is not processed because of previous failure in atomic mode
+	ImportSkipped = "skipped"
+	// ImportRolledBack user was created but transaction is rolled back because of failure in atomic mode
+	ImportRolledBack = "rolled_back"
+)
+
+// ImportRow user row of import file
+type ImportRow struct {
+	Line         int    `json:"-"`
+	Email        string `json:"email"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Department   string `json:"department"`
+	Role         string `json:"role"`
+	PasswordHash string `json:"password_hash"`
+	// Error validation error of row, rows with error are not imported
+	Error string `json:"-"`
+}
+
+// ImportOptions options of users import
+type ImportOptions struct {
+	Format string
+	// DryRun import is done in transaction which is always rolled back
+	DryRun bool
+	// CreateMissing departments and roles which are not found by name are created
+	CreateMissing bool
+	// Atomic all users are created or none of them
+	Atomic bool
+}
+
+// ImportResult result of row import
+type ImportResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// ImportReport result of users import
+type ImportReport struct {
+	DryRun             bool           `json:"dry_run"`
+	Committed          bool           `json:"committed"`
+	Created            int            `json:"created"`
+	Failed             int            `json:"failed"`
+	CreatedDepartments []string       `json:"created_departments"`
+	CreatedRoles       []string       `json:"created_roles"`
+	Results            []ImportResult `json:"results"`
+}
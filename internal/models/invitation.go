@@ -0,0 +1,73 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// InvitationPending invitation is waiting for registration
+	InvitationPending = "pending"
+	// InvitationAccepted invited user is registered
+	InvitationAccepted = "accepted"
+	// InvitationCancelled invitation is cancelled by administrator
+	InvitationCancelled = "cancelled"
+	// InvitationExpired pending invitation is expired, status is not stored
+	InvitationExpired = "expired"
+)
+
+const (
+	// InvitationClaim register token claim with id of invitation
+	InvitationClaim = "invitation_id"
+	// InvitedDepartmentClaim register token claim with invited department
+	InvitedDepartmentClaim = "department_id"
+	// InvitedRoleClaim register token claim with invited role
+	InvitedRoleClaim = "role_id"
+)
+
+// SubjectInvitation notification subject of invitations
+const SubjectInvitation = "account.invitation"
+
+// Invitation invitation of user to register with fixed email, department and role,
+// register tokens of invitation have invitation id as identity
+type Invitation struct {
+	ID string `gorm:"primaryKey;type:uuid" json:"id"`
+
+	Email          string     `gorm:"index" json:"email"`
+	DepartmentID   uint32     `json:"department_id"`
+	RoleID         uint32     `json:"role_id"`
+	InvitedBy      string     `json:"invited_by"`
+	Status         string     `gorm:"index" json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedUserID string     `json:"accepted_user_id"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate add uuid to id
+func (invitation *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	invitation.ID = uuid.NewString()
+	return
+}
+
+// EffectiveStatus status of invitation with expiration of pending invitation at moment
+func (invitation *Invitation) EffectiveStatus(moment time.Time) string {
+	if invitation.Status == InvitationPending && !moment.Before(invitation.ExpiresAt) {
+		return InvitationExpired
+	}
+
+	return invitation.Status
+}
+
+// InvitationNotification notification with register token for invited user
+type InvitationNotification struct {
+	InvitationID string    `json:"invitation_id"`
+	Email        string    `json:"email"`
+	Token        string    `json:"token"`
+	DepartmentID uint32    `json:"department_id"`
+	RoleID       uint32    `json:"role_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -33,22 +33,28 @@ const (
 	ResetPasswordToken = "RESET_PASSWORD"
 	// ChangeNumberOTPToken change number otp token
 	ChangeNumberOTPToken = "CHANGE_NUMBER_OTP"
+	// AuthorizationCodeToken oauth authorization code
+	AuthorizationCodeToken = "AUTHORIZATION_CODE"
+	// OAuthSessionToken browser session of authorization server
+	OAuthSessionToken = "OAUTH_SESSION"
 )
 
 // Expirations expire time of tokens
 var Expirations = map[string]time.Duration{
-	PhoneOTPToken:        time.Minute * 5,
-	AuthorizeOTPToken:    time.Minute * 5,
-	FirstLoginToken:      time.Minute * 30,
-	RegisterToken:        time.Minute * 15,
-	AuthToken:            time.Hour * 24,
-	RefreshAuthToken:     time.Hour * 24 * 7,
-	AccessToken:          time.Minute * 15,
-	RefreshAccessToken:   time.Hour * 2,
-	DeviceToken:          time.Hour * 24 * 1000,
-	ForgotOTPToken:       time.Hour * 24,
-	ResetPasswordToken:   time.Hour * 1,
-	ChangeNumberOTPToken: time.Hour * 1,
+	PhoneOTPToken:          time.Minute * 5,
+	AuthorizeOTPToken:      time.Minute * 5,
+	FirstLoginToken:        time.Minute * 30,
+	RegisterToken:          time.Minute * 15,
+	AuthToken:              time.Hour * 24,
+	RefreshAuthToken:       time.Hour * 24 * 7,
+	AccessToken:            time.Minute * 15,
+	RefreshAccessToken:     time.Hour * 2,
+	DeviceToken:            time.Hour * 24 * 1000,
+	ForgotOTPToken:         time.Hour * 24,
+	ResetPasswordToken:     time.Hour * 1,
+	ChangeNumberOTPToken:   time.Hour * 1,
+	AuthorizationCodeToken: time.Minute * 1,
+	OAuthSessionToken:      time.Hour * 12,
 }
 
 // RefreshRegex refresh token regex
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	// GrantAuthorizationCode authorization code grant
+	GrantAuthorizationCode = "authorization_code"
+	// GrantRefreshToken refresh token grant
+	GrantRefreshToken = "refresh_token"
+	// GrantClientCredentials client credentials grant
+	GrantClientCredentials = "client_credentials"
+)
+
+const (
+	// ClientIDClaim jwt claim with id of oauth client which token is issued to
+	ClientIDClaim = "client_id"
+	// ScopeClaim jwt claim with space separated granted scopes
+	ScopeClaim = "scope"
+	// RedirectURIClaim authorization code claim with redirect uri of authorization request
+	RedirectURIClaim = "redirect_uri"
+	// CodeChallengeClaim authorization code claim with PKCE code challenge
+	CodeChallengeClaim = "code_challenge"
+	// NonceClaim authorization code and id token claim with nonce of authentication request
+	NonceClaim = "nonce"
+	// AuthTimeClaim claim with time when user logged in
+	AuthTimeClaim = "auth_time"
+)
+
+const (
+	// ScopeOpenID scope of OpenID Connect authentication request
+	ScopeOpenID = "openid"
+	// ScopeProfile scope of name claims
+	ScopeProfile = "profile"
+	// ScopeEmail scope of email claims
+	ScopeEmail = "email"
+)
+
+// OAuthGrantTypes supported grant types
+var OAuthGrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials}
+
+// OAuthClient application registered to get tokens from authorization server,
+// public clients have no secret and must use PKCE, client credentials tokens have client id as identity
+// and permissions of role of client
+type OAuthClient struct {
+	ID string `gorm:"primaryKey;type:uuid" json:"id"`
+
+	Name         string `json:"name"`
+	SecretHash   string `json:"-"`
+	RedirectURIs string `json:"redirect_uris"`
+	Scopes       string `json:"scopes"`
+	GrantTypes   string `json:"grant_types"`
+	Trusted      bool   `json:"trusted"`
+	RoleID       uint32 `json:"role_id"`
+	DepartmentID uint32 `json:"department_id"`
+	CreatedBy    string `json:"created_by"`
+
+	// PostLogoutRedirectURIs space separated uris where user can be returned after logout
+	PostLogoutRedirectURIs string `json:"post_logout_redirect_uris"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// BeforeCreate add uuid to id
+func (client *OAuthClient) BeforeCreate(tx *gorm.DB) (err error) {
+	client.ID = uuid.NewString()
+	return
+}
+
+// TableName name of table of oauth clients
+func (client *OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IsConfidential check client authenticates with secret
+func (client *OAuthClient) IsConfidential() bool {
+	return client.SecretHash != ""
+}
+
+// HasRedirectURI check redirect uri is registered, uris are compared exactly
+func (client *OAuthClient) HasRedirectURI(uri string) bool {
+	return containsString(strings.Fields(client.RedirectURIs), uri)
+}
+
+// HasPostLogoutRedirectURI check post logout redirect uri is registered, uris are compared exactly
+func (client *OAuthClient) HasPostLogoutRedirectURI(uri string) bool {
+	return containsString(strings.Fields(client.PostLogoutRedirectURIs), uri)
+}
+
+// AllowsGrant check client may use grant type
+func (client *OAuthClient) AllowsGrant(grantType string) bool {
+	return containsString(strings.Fields(client.GrantTypes), grantType)
+}
+
+// AllowsScopes check all scopes are registered for client
+func (client *OAuthClient) AllowsScopes(scopes []string) bool {
+	allowed := strings.Fields(client.Scopes)
+	for _, scope := range scopes {
+		if !containsString(allowed, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OAuthConsent scopes user allowed oauth client to access on its behalf
+type OAuthConsent struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID   string `gorm:"type:uuid;uniqueIndex:idx_oauth_consent_user_client" json:"user_id"`
+	ClientID string `gorm:"type:uuid;uniqueIndex:idx_oauth_consent_user_client;index" json:"client_id"`
+	Scopes   string `json:"scopes"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName name of table of consents
+func (consent *OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// Covers check consent includes all scopes
+func (consent *OAuthConsent) Covers(scopes []string) bool {
+	granted := strings.Fields(consent.Scopes)
+	for _, scope := range scopes {
+		if !containsString(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ConsentWithClient consent with name of client
+type ConsentWithClient struct {
+	OAuthConsent
+	ClientName string `json:"client_name"`
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
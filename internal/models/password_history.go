@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordHistory previous password hash of user
+type PasswordHistory struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID string `gorm:"type:uuid;index" json:"user_id"`
+	Hash   string `json:"hash"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,200 @@
+package models
+
+import "sort"
+
+const (
+	// PermissionUsersRead read accounts of other users
+	PermissionUsersRead = "users:read"
+	// PermissionUsersLookup get accounts of other users by ids
+	PermissionUsersLookup = "users:lookup"
+	// PermissionUsersWrite change accounts of other users
+	PermissionUsersWrite = "users:write"
+	// PermissionUsersDelete delete, restore and erase accounts of other users
+	PermissionUsersDelete = "users:delete"
+	// PermissionUsersExport export personal data of other users and accounts in bulk
+	PermissionUsersExport = "users:export"
+	// PermissionUsersInvite invite new users
+	PermissionUsersInvite = "users:invite"
+	// PermissionUsersImport import users in bulk
+	PermissionUsersImport = "users:import"
+	// PermissionDepartmentsRead read departments
+	PermissionDepartmentsRead = "departments:read"
+	// PermissionDepartmentsWrite create, change and remove departments
+	PermissionDepartmentsWrite = "departments:write"
+	// PermissionRolesRead read roles and permissions
+	PermissionRolesRead = "roles:read"
+	// PermissionRolesWrite create, change and remove roles
+	PermissionRolesWrite = "roles:write"
+	// PermissionRolesAssign grant and revoke roles of users
+	PermissionRolesAssign = "roles:assign"
+	// PermissionClientsWrite register, rotate and remove oauth clients
+	PermissionClientsWrite = "clients:write"
+	// PermissionAPIKeysWrite create, rotate and revoke api keys
+	PermissionAPIKeysWrite = "apikeys:write"
+	// PermissionPolicyExplain evaluate access policy on behalf of any user
+	PermissionPolicyExplain = "policy:explain"
+)
+
+const (
+	// AdminRole built-in administrator role
+	AdminRole = "admin"
+	// EmployeeRole built-in employee role
+	EmployeeRole = "employee"
+	// ServiceRole built-in role of service principals
+	ServiceRole = "service"
+)
+
+const (
+	// PermissionsClaim jwt claim with global permissions of user
+	PermissionsClaim = "permissions"
+	// RolesClaim jwt claim with effective roles of user
+	RolesClaim = "roles"
+)
+
+// BuiltinRoles roles seeded on startup with their permissions
+var BuiltinRoles = map[string][]string{
+	AdminRole: {
+		PermissionUsersRead,
+		PermissionUsersLookup,
+		PermissionUsersWrite,
+		PermissionUsersDelete,
+		PermissionUsersExport,
+		PermissionUsersInvite,
+		PermissionUsersImport,
+		PermissionDepartmentsRead,
+		PermissionDepartmentsWrite,
+		PermissionRolesRead,
+		PermissionRolesWrite,
+		PermissionRolesAssign,
+		PermissionPolicyExplain,
+		PermissionClientsWrite,
+		PermissionAPIKeysWrite,
+	},
+	EmployeeRole: {
+		PermissionDepartmentsRead,
+		PermissionRolesRead,
+	},
+	ServiceRole: {
+		PermissionUsersLookup,
+		PermissionDepartmentsRead,
+		PermissionRolesRead,
+	},
+}
+
+// IsKnownPermission check permission is one of permissions of service,
+// built-in admin role has all of them
+func IsKnownPermission(name string) bool {
+	for _, permission := range BuiltinRoles[AdminRole] {
+		if permission == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Permission model for permission
+type Permission struct {
+	ID uint32 `gorm:"primaryKey;type=uint32" json:"id"`
+
+	Name string `gorm:"unique" json:"name"`
+}
+
+// Principal authenticated caller of rpc
+type Principal struct {
+	UserID       string
+	Email        string
+	DepartmentID uint32
+	RoleID       uint32
+	Roles        []EffectiveRole
+	Permissions  []string
+	Token        *JWT
+	// ClientID id of oauth client if principal is client authenticated by client credentials
+	ClientID string
+	// APIKeyID id of api key if principal is service authenticated by api key
+	APIKeyID string
+}
+
+// HasPermissions check principal has all of permissions
+func (p *Principal) HasPermissions(permissions ...string) bool {
+	for _, required := range permissions {
+		found := false
+		for _, permission := range p.Permissions {
+			if permission == required {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasPermissionsIn check principal has all of permissions globally or by roles scoped to one of departments,
+// departments are path from root to department
+func (p *Principal) HasPermissionsIn(departments []uint32, permissions ...string) bool {
+	for _, required := range permissions {
+		if p.HasPermissions(required) {
+			continue
+		}
+
+		found := false
+		for _, role := range p.Roles {
+			if role.DepartmentID == nil || !containsID(departments, *role.DepartmentID) {
+				continue
+			}
+
+			for _, permission := range role.Permissions {
+				if permission == required {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GlobalPermissions sorted permissions of roles not scoped to department
+func GlobalPermissions(roles []EffectiveRole) []string {
+	set := make(map[string]struct{})
+	for _, role := range roles {
+		if role.DepartmentID != nil {
+			continue
+		}
+
+		for _, permission := range role.Permissions {
+			set[permission] = struct{}{}
+		}
+	}
+
+	permissions := make([]string, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions
+}
@@ -3,5 +3,6 @@ package models
 type Role struct {
 	ID uint32 `gorm:"primaryKey;type=uint32" json:"id"`
 
-	Name string `gorm:"unique" json:"name"`
+	Name        string       `gorm:"unique" json:"name"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RoleAssignment role granted to user in addition to primary role,
+// optionally scoped to department subtree and limited by validity window
+type RoleAssignment struct {
+	ID uint32 `gorm:"primaryKey;type=uint32" json:"id"`
+
+	UserID       string     `gorm:"type:uuid;index" json:"user_id"`
+	RoleID       uint32     `gorm:"index" json:"role_id"`
+	DepartmentID *uint32    `gorm:"index" json:"department_id"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	GrantedBy    string     `json:"granted_by"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsActive check assignment is valid at moment
+func (ra *RoleAssignment) IsActive(moment time.Time) bool {
+	if ra.StartsAt != nil && moment.Before(*ra.StartsAt) {
+		return false
+	}
+
+	if ra.EndsAt != nil && !moment.Before(*ra.EndsAt) {
+		return false
+	}
+
+	return true
+}
+
+// EffectiveRole role of user in effect, department scope is nil for global role
+type EffectiveRole struct {
+	RoleID       uint32   `json:"role_id"`
+	DepartmentID *uint32  `json:"department_id,omitempty"`
+	Permissions  []string `json:"-"`
+}
@@ -0,0 +1,8 @@
+package models
+
+const (
+	// ScimGroupsDepartments departments are exposed as SCIM groups
+	ScimGroupsDepartments = "departments"
+	// ScimGroupsRoles roles are exposed as SCIM groups
+	ScimGroupsRoles = "roles"
+)
@@ -10,8 +10,9 @@ import (
 type Token struct {
 	ID string `gorm:"primaryKey;type:uuid" json:"id"`
 
-	Identity  string    `json:"identity"`
+	Identity  string    `gorm:"index" json:"identity"`
 	Variety   string    `json:"variety"`
+	Session   string    `gorm:"index" json:"session"`
 	IsRevoked bool      `json:"is_revoked"`
 	LastUse   time.Time `json:"last_use"`
 
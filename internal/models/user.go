@@ -15,13 +15,24 @@ type User struct {
 	LastName      string `json:"last_name"`
 	Email         string `gorm:"unique" json:"email"`
 	EmailVerified bool   `json:"email_verified"`
-	DepartmentID  uint32 `json:"department_id"`
-	RoleID        uint32 `json:"role_id"`
+	DepartmentID  uint32 `gorm:"index" json:"department_id"`
+	RoleID        uint32 `gorm:"index" json:"role_id"`
 	IsRegistered  bool   `json:"is_registered"`
 
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	DeletedAt time.Time `json:"deleted_at"`
+	CreatedAt     time.Time      `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeactivatedAt *time.Time     `json:"deactivated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	// ErasureRequestedAt user asked to erase personal data without waiting for retention period
+	ErasureRequestedAt *time.Time `gorm:"index" json:"erasure_requested_at"`
+	// ErasedAt personal data of user is anonymized
+	ErasedAt *time.Time `gorm:"index" json:"erased_at"`
+}
+
+// IsDeactivated check user is deactivated and can't log in
+func (user *User) IsDeactivated() bool {
+	return user.DeactivatedAt != nil
 }
 
 // BeforeCreate - create new uuid
@@ -29,3 +40,48 @@ func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	user.ID = uuid.NewString()
 	return
 }
+
+const (
+	// UserSortCreatedAt sort users by creation time
+	UserSortCreatedAt = "created_at"
+	// UserSortEmail sort users by email
+	UserSortEmail = "email"
+	// UserSortLastName sort users by last name
+	UserSortLastName = "last_name"
+)
+
+// UserFilter filter, sort and page of users listing
+type UserFilter struct {
+	Query                 string
+	DepartmentID          uint32
+	IncludeSubdepartments bool
+	RoleID                uint32
+	IsRegistered          *bool
+	EmailVerified         *bool
+	CreatedFrom           *time.Time
+	CreatedTo             *time.Time
+	SortBy                string
+	Descending            bool
+	After                 *UserCursor
+	Limit                 int
+}
+
+// UserCursor position in sorted users listing
+type UserCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         string `json:"i"`
+}
+
+// SortValue value of user by which listing is sorted
+func (user *User) SortValue(sortBy string) string {
+	switch sortBy {
+	case UserSortEmail:
+		return user.Email
+	case UserSortLastName:
+		return user.LastName
+	default:
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
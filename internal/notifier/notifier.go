@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrNotConfigured NATS host is not configured so notification can't be delivered
+var ErrNotConfigured = errors.New("notifications are not configured")
+
+// Notifier publish notifications as json messages to NATS subjects,
+// notifier without NATS host rejects notifications because they may carry the only copy of secret
+type Notifier struct {
+	conn *nats.Conn
+}
+
+// NewNotifier connect notifier to NATS host
+func NewNotifier(host string) (*Notifier, error) {
+	if host == "" {
+		return &Notifier{}, nil
+	}
+
+	conn, err := nats.Connect(host)
+	if err != nil {
+		return nil, fmt.Errorf("nats.Connect error: %w", err)
+	}
+
+	return &Notifier{conn: conn}, nil
+}
+
+// Notify publish message to subject
+func (n *Notifier) Notify(subject string, message interface{}) error {
+	if n.conn == nil {
+		return ErrNotConfigured
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	err = n.conn.Publish(subject, data)
+	if err != nil {
+		return fmt.Errorf("n.conn.Publish error: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled check notifications can be delivered
+func (n *Notifier) Enabled() bool {
+	return n.conn != nil
+}
+
+// Close flush pending notifications and close connection
+func (n *Notifier) Close() {
+	if n.conn == nil {
+		return
+	}
+
+	err := n.conn.Drain()
+	if err != nil {
+		hclog.Default().Error("[notifier.Close] n.conn.Drain", "error", err)
+	}
+}
@@ -0,0 +1,463 @@
+package oauth
+
+import (
+	"account-service/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// codeChallengeS256 only supported PKCE method, plain method is rejected
+const codeChallengeS256 = "S256"
+
+// maxNonceLength max length of nonce of OpenID Connect request
+const maxNonceLength = 512
+
+// authorizeRequest validated parameters of authorization request
+type authorizeRequest struct {
+	client              *models.OAuthClient
+	redirectURI         string
+	effectiveRedirect   string
+	scopes              []string
+	state               string
+	codeChallenge       string
+	codeChallengeMethod string
+	nonce               string
+	prompt              []string
+	maxAge              string
+}
+
+// values parameters of request kept in hidden fields of login and consent forms
+func (req *authorizeRequest) values() url.Values {
+	values := url.Values{
+		"response_type": {"code"},
+		"client_id":     {req.client.ID},
+	}
+	for key, value := range map[string]string{
+		"redirect_uri":          req.redirectURI,
+		"scope":                 strings.Join(req.scopes, " "),
+		"state":                 req.state,
+		"code_challenge":        req.codeChallenge,
+		"code_challenge_method": req.codeChallengeMethod,
+		"nonce":                 req.nonce,
+		"prompt":                strings.Join(req.prompt, " "),
+		"max_age":               req.maxAge,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	return values
+}
+
+// hasPrompt check prompt of OpenID Connect request contains value
+func (req *authorizeRequest) hasPrompt(value string) bool {
+	return containsScope(req.prompt, value)
+}
+
+// expired check browser session is older than max_age of request
+func (req *authorizeRequest) expired(session *models.JWT) bool {
+	if req.maxAge == "" {
+		return false
+	}
+	maxAge, _ := strconv.ParseInt(req.maxAge, 10, 64)
+
+	return time.Now().Unix()-authTime(session) > maxAge
+}
+
+// pageError error of authorization request which can't be returned to client because redirect uri is not trusted
+type pageError struct {
+	message string
+}
+
+// Error text of error
+func (e *pageError) Error() string {
+	return e.message
+}
+
+// authorize handle authorization endpoint, user logs in and gives consent in browser,
+// authorization code is returned to redirect uri of client
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return errMethod
+	}
+
+	ctx, span := h.trace.Start(r.Context(), "oauth.authorize")
+	defer span.End()
+
+	err := r.ParseForm()
+	if err != nil {
+		return invalidRequest("request is malformed")
+	}
+
+	req, err := h.parseAuthorizeRequest(r.Form)
+	if err != nil {
+		if pe, ok := err.(*pageError); ok {
+			renderPage(w, http.StatusBadRequest, errorPage, pageData{Error: pe.message})
+			return nil
+		}
+		return err
+	}
+
+	// errors are returned to client after redirect uri is validated
+	oe := validateAuthorizeParams(req, r.Form)
+	if oe != nil {
+		redirectError(w, r, req, oe)
+		return nil
+	}
+
+	user, session, err := h.session(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	action := r.PostForm.Get("action")
+	if r.Method == http.MethodPost && action == "login" {
+		var rejection string
+		user, session, rejection, err = h.login(ctx, w, r)
+		if err != nil {
+			return err
+		}
+		if rejection != "" {
+			renderPage(w, http.StatusUnauthorized, loginPage, h.pageData(req, nil, rejection))
+			return nil
+		}
+	}
+
+	// prompt and max_age are kept in login and consent forms, so they force login only on initial request
+	if user != nil && r.Method == http.MethodGet && (req.hasPrompt("login") || req.expired(session)) {
+		user = nil
+	}
+
+	if user == nil {
+		if req.hasPrompt("none") {
+			redirectError(w, r, req, newError(http.StatusUnauthorized, "login_required", "user is not logged in"))
+			return nil
+		}
+		renderPage(w, http.StatusOK, loginPage, h.pageData(req, nil, ""))
+		return nil
+	}
+
+	consented, err := h.hasConsent(user, req)
+	if err != nil {
+		return err
+	}
+	if consented && !req.client.Trusted && r.Method == http.MethodGet && req.hasPrompt("consent") {
+		consented = false
+	}
+
+	if !consented {
+		if req.hasPrompt("none") {
+			redirectError(w, r, req, newError(http.StatusForbidden, "consent_required", "user didn't allow access"))
+			return nil
+		}
+
+		validCSRF := hmac.Equal([]byte(r.PostForm.Get("csrf")), []byte(h.csrfToken(session)))
+		switch {
+		case r.Method == http.MethodPost && action == "deny" && validCSRF:
+			redirectError(w, r, req, newError(http.StatusForbidden, "access_denied", "user denied access"))
+			return nil
+		case r.Method == http.MethodPost && action == "allow" && validCSRF:
+			err = h.grantConsent(user, req)
+			if err != nil {
+				return err
+			}
+		default:
+			renderPage(w, http.StatusOK, consentPage, h.pageData(req, session, ""))
+			return nil
+		}
+	}
+
+	code, err := h.tokenSrv.NewJWT(ctx, models.AuthorizationCodeToken, user.ID, user.Email, jwt.MapClaims{
+		models.ClientIDClaim:      req.client.ID,
+		models.RedirectURIClaim:   req.redirectURI,
+		models.ScopeClaim:         strings.Join(req.scopes, " "),
+		models.CodeChallengeClaim: req.codeChallenge,
+		models.NonceClaim:         req.nonce,
+		models.AuthTimeClaim:      authTime(session),
+	})
+	if err != nil {
+		return fmt.Errorf("h.tokenSrv.NewJWT error: %w", err)
+	}
+
+	redirect(w, r, req, url.Values{"code": {code.ToJWTString()}})
+
+	return nil
+}
+
+// parseAuthorizeRequest find client and check redirect uri, other parameters are only read
+func (h *Handler) parseAuthorizeRequest(form url.Values) (*authorizeRequest, error) {
+	clientID := form.Get("client_id")
+	if clientID == "" {
+		return nil, &pageError{message: "client_id is required"}
+	}
+
+	client, err := h.db.GetOAuthClientByID(clientID)
+	if isNotFound(err) {
+		return nil, &pageError{message: "client is not registered"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetOAuthClientByID error: %w", err)
+	}
+
+	// redirect uri can be omitted only if client has single registered uri
+	redirectURI := form.Get("redirect_uri")
+	effectiveRedirect := redirectURI
+	if redirectURI == "" {
+		registered := strings.Fields(client.RedirectURIs)
+		if len(registered) != 1 {
+			return nil, &pageError{message: "redirect_uri is required"}
+		}
+		effectiveRedirect = registered[0]
+	}
+	if !client.HasRedirectURI(effectiveRedirect) {
+		return nil, &pageError{message: "redirect_uri is not registered for client"}
+	}
+
+	scopes := strings.Fields(form.Get("scope"))
+	if len(scopes) == 0 {
+		scopes = strings.Fields(client.Scopes)
+	}
+
+	return &authorizeRequest{
+		client:              client,
+		redirectURI:         redirectURI,
+		effectiveRedirect:   effectiveRedirect,
+		scopes:              uniqueScopes(scopes),
+		state:               form.Get("state"),
+		codeChallenge:       form.Get("code_challenge"),
+		codeChallengeMethod: form.Get("code_challenge_method"),
+		nonce:               form.Get("nonce"),
+		prompt:              uniqueScopes(strings.Fields(form.Get("prompt"))),
+		maxAge:              form.Get("max_age"),
+	}, nil
+}
+
+// validateAuthorizeParams check response type, grant, scopes and PKCE of request,
+// PKCE is required for public clients
+func validateAuthorizeParams(req *authorizeRequest, form url.Values) *oauthError {
+	if form.Get("response_type") != "code" {
+		return newError(http.StatusBadRequest, "unsupported_response_type", "only code response type is supported")
+	}
+	if !req.client.AllowsGrant(models.GrantAuthorizationCode) {
+		return errUnauthorizedClient
+	}
+	if !req.client.AllowsScopes(req.scopes) {
+		return errInvalidScope
+	}
+
+	for _, prompt := range req.prompt {
+		if prompt != "none" && prompt != "login" && prompt != "consent" {
+			return invalidRequest("prompt " + prompt + " is not supported")
+		}
+	}
+	if req.hasPrompt("none") && len(req.prompt) > 1 {
+		return invalidRequest("prompt none can't be combined with other values")
+	}
+	if req.maxAge != "" {
+		maxAge, err := strconv.ParseInt(req.maxAge, 10, 64)
+		if err != nil || maxAge < 0 {
+			return invalidRequest("max_age must be non-negative integer")
+		}
+	}
+	if len(req.nonce) > maxNonceLength {
+		return invalidRequest("nonce is too long")
+	}
+
+	if req.codeChallenge == "" {
+		if !req.client.IsConfidential() {
+			return invalidRequest("code_challenge is required for public client")
+		}
+		return nil
+	}
+	if req.codeChallengeMethod != codeChallengeS256 {
+		return invalidRequest("code_challenge_method must be S256")
+	}
+	if !validPKCEValue(req.codeChallenge) {
+		return invalidRequest("code_challenge is malformed")
+	}
+
+	return nil
+}
+
+// session user of browser session cookie, nil is returned if there is no valid session
+func (h *Handler) session(ctx context.Context, r *http.Request) (*models.User, *models.JWT, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return nil, nil, nil
+	}
+
+	session, err := h.tokenSrv.ParseJWT(ctx, cookie.Value)
+	if err != nil {
+		return nil, nil, nil
+	}
+	if err := h.tokenSrv.Validate(session, models.OAuthSessionToken); err != nil {
+		return nil, nil, nil
+	}
+
+	user, err := h.db.GetUserByID(session.Identity)
+	if isNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("h.db.GetUserByID error: %w", err)
+	}
+	if user.IsDeactivated() {
+		return nil, nil, nil
+	}
+
+	return user, session, nil
+}
+
+// login check email and password from login form and start browser session,
+// message for user is returned if credentials are rejected
+func (h *Handler) login(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.User, *models.JWT, string, error) {
+	user, err := h.accounts.Authenticate(ctx, strings.TrimSpace(r.PostForm.Get("email")), r.PostForm.Get("password"))
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Internal:
+		return nil, nil, "", fmt.Errorf("h.accounts.Authenticate error: %w", err)
+	case codes.PermissionDenied:
+		return nil, nil, "Account is deactivated", nil
+	default:
+		return nil, nil, "Invalid email or password", nil
+	}
+
+	session, err := h.tokenSrv.NewJWT(ctx, models.OAuthSessionToken, user.ID, user.Email, nil)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("h.tokenSrv.NewJWT error: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.ToJWTString(),
+		Path:     h.cookiePath(),
+		MaxAge:   int(models.Expirations[models.OAuthSessionToken].Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return user, session, "", nil
+}
+
+// authTime time of login of browser session as unix time
+func authTime(session *models.JWT) int64 {
+	if session == nil || session.TokenObject == nil || session.TokenObject.CreatedAt.IsZero() {
+		return time.Now().Unix()
+	}
+
+	return session.TokenObject.CreatedAt.Unix()
+}
+
+// cookiePath path of session cookie
+func (h *Handler) cookiePath() string {
+	if h.basePath == "" {
+		return "/"
+	}
+
+	return h.basePath
+}
+
+// hasConsent check user allowed client to access requested scopes, trusted clients don't need consent
+func (h *Handler) hasConsent(user *models.User, req *authorizeRequest) (bool, error) {
+	if req.client.Trusted {
+		return true, nil
+	}
+
+	consent, err := h.db.GetConsent(user.ID, req.client.ID)
+	if err != nil {
+		return false, fmt.Errorf("h.db.GetConsent error: %w", err)
+	}
+
+	return consent != nil && consent.Covers(req.scopes), nil
+}
+
+// grantConsent save consent of user for requested scopes in addition to previously allowed scopes
+func (h *Handler) grantConsent(user *models.User, req *authorizeRequest) error {
+	consent, err := h.db.GetConsent(user.ID, req.client.ID)
+	if err != nil {
+		return fmt.Errorf("h.db.GetConsent error: %w", err)
+	}
+
+	scopes := req.scopes
+	if consent != nil {
+		scopes = uniqueScopes(append(strings.Fields(consent.Scopes), req.scopes...))
+	}
+
+	_, err = h.db.SaveConsent(user.ID, req.client.ID, strings.Join(scopes, " "))
+	if err != nil {
+		return fmt.Errorf("h.db.SaveConsent error: %w", err)
+	}
+	h.audit(user, "consent")
+
+	return nil
+}
+
+// csrfToken token of consent form bound to browser session
+func (h *Handler) csrfToken(session *models.JWT) string {
+	if session == nil {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.cfg.JwtSecret))
+	mac.Write([]byte("oauth-consent:" + session.ID))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// redirect return parameters to redirect uri of client with state of request
+func redirect(w http.ResponseWriter, r *http.Request, req *authorizeRequest, params url.Values) {
+	target, err := url.Parse(req.effectiveRedirect)
+	if err != nil {
+		renderPage(w, http.StatusBadRequest, errorPage, pageData{Error: "redirect_uri is malformed"})
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if req.state != "" {
+		query.Set("state", req.state)
+	}
+	target.RawQuery = query.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// redirectError return error of authorization request to redirect uri of client
+func redirectError(w http.ResponseWriter, r *http.Request, req *authorizeRequest, oe *oauthError) {
+	params := url.Values{"error": {oe.Code}}
+	if oe.Description != "" {
+		params.Set("error_description", oe.Description)
+	}
+
+	redirect(w, r, req, params)
+}
+
+// uniqueScopes scopes without duplicates in original order
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	return result
+}
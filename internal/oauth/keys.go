@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"math/big"
+	"os"
+)
+
+// Signer RSA key signing id tokens, key id is RFC 7638 thumbprint of public key
+type Signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// NewSigner load RSA private key from PEM file in PKCS #1 or PKCS #8 form,
+// key is required because id tokens signed by generated key are not valid after restart
+func NewSigner(path string) (*Signer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("signing key path is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile error: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM file", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newSigner(key)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParsePKCS8PrivateKey error: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not RSA private key", path)
+	}
+
+	return newSigner(key)
+}
+
+// newSigner signer of key with computed key id
+func newSigner(key *rsa.PrivateKey) (*Signer, error) {
+	// members of thumbprint are in lexicographic order without whitespace
+	thumbprint, err := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   encodeInt(big.NewInt(int64(key.E))),
+		Kty: "RSA",
+		N:   encodeInt(key.N),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	sum := sha256.Sum256(thumbprint)
+
+	return &Signer{
+		key: key,
+		kid: base64.RawURLEncoding.EncodeToString(sum[:]),
+	}, nil
+}
+
+// Sign sign claims as RS256 jwt with key id in header
+func (s *Signer) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("token.SignedString error: %w", err)
+	}
+
+	return signed, nil
+}
+
+// Parse verify signature of jwt signed by signer, expiration is not checked
+func (s *Signer) Parse(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}, SkipClaimsValidation: true}
+
+	_, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return &s.key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parser.ParseWithClaims error: %w", err)
+	}
+
+	return claims, nil
+}
+
+// jwk public key in JSON Web Key form
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS key set with public key of signer
+func (s *Signer) JWKS() interface{} {
+	return map[string][]jwk{
+		"keys": {{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: s.kid,
+			N:   encodeInt(s.key.N),
+			E:   encodeInt(big.NewInt(int64(s.key.E))),
+		}},
+	}
+}
+
+// encodeInt base64url encoding of big-endian bytes of integer
+func encodeInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
@@ -0,0 +1,194 @@
+package oauth
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+)
+
+const (
+	// sessionCookie name of cookie with browser session of authorization server
+	sessionCookie = "oauth_session"
+	// maxBodySize max size of request body
+	maxBodySize = 64 * 1024
+)
+
+// Repository repository of oauth clients, consents and users
+type Repository interface {
+	GetOAuthClientByID(id string) (*models.OAuthClient, error)
+	GetConsent(userID, clientID string) (*models.OAuthConsent, error)
+	SaveConsent(userID, clientID, scopes string) (*models.OAuthConsent, error)
+	GetUserByID(id string) (*models.User, error)
+	GetRolesPermissions(roleIDs []uint32) (map[uint32][]string, error)
+	AddAuditEntry(entry *models.AuditEntry) error
+}
+
+// TokenService service issuing authorization codes, sessions and tokens
+type TokenService interface {
+	NewJWT(ctx context.Context, variety string, identity string, email string, extra jwt.MapClaims) (*models.JWT, error)
+	ParseJWT(ctx context.Context, token string) (*models.JWT, error)
+	Validate(j *models.JWT, variety string) error
+	Consume(ctx context.Context, j *models.JWT, variety string) error
+	CreateSessionJWT(ctx context.Context, identity string, email string, extra jwt.MapClaims, refreshExtra jwt.MapClaims) (*models.JWT, *models.JWT, error)
+	Revoke(j *models.JWT)
+}
+
+// Accounts authentication of users and claims of their access tokens
+type Accounts interface {
+	Authenticate(ctx context.Context, email, password string) (*models.User, error)
+	AccessClaims(user *models.User) (jwt.MapClaims, error)
+	AccountInfo(ctx context.Context, accessToken string) (*models.User, *models.JWT, error)
+}
+
+// SecretVerifier verify client secret by its hash
+type SecretVerifier interface {
+	Verify(hash, password string) (bool, error)
+}
+
+// Handler OAuth 2.0 authorization server with authorization code, refresh token and client credentials grants
+// and OpenID Connect provider, it must be mounted with stripped base path
+type Handler struct {
+	db       Repository
+	tokenSrv TokenService
+	accounts Accounts
+	secrets  SecretVerifier
+	signer   *Signer
+	trace    trace.Tracer
+	cfg      *config.Config
+	basePath string
+}
+
+// NewHandler create authorization server handler, basePath is path of session cookie
+func NewHandler(db Repository, t TokenService, accounts Accounts, secrets SecretVerifier, signer *Signer, tracer trace.Tracer, cfg *config.Config, basePath string) *Handler {
+	return &Handler{
+		db:       db,
+		tokenSrv: t,
+		accounts: accounts,
+		secrets:  secrets,
+		signer:   signer,
+		trace:    tracer,
+		cfg:      cfg,
+		basePath: strings.TrimSuffix(basePath, "/"),
+	}
+}
+
+// oauthError error response of RFC 6749
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+
+	status int
+}
+
+// Error text of error
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// newError create oauth error with http status of token endpoint
+func newError(status int, code, description string) *oauthError {
+	return &oauthError{
+		Code:        code,
+		Description: description,
+		status:      status,
+	}
+}
+
+var (
+	errInvalidClient        = newError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	errInvalidGrant         = newError(http.StatusBadRequest, "invalid_grant", "grant is invalid, expired or revoked")
+	errUnauthorizedClient   = newError(http.StatusBadRequest, "unauthorized_client", "client is not allowed to use grant type")
+	errUnsupportedGrantType = newError(http.StatusBadRequest, "unsupported_grant_type", "grant type is not supported")
+	errInvalidScope         = newError(http.StatusBadRequest, "invalid_scope", "scope is not allowed for client")
+	errServer               = newError(http.StatusInternalServerError, "server_error", "internal error")
+	errMethod               = newError(http.StatusMethodNotAllowed, "invalid_request", "method is not allowed")
+	errNotFound             = newError(http.StatusNotFound, "invalid_request", "endpoint is not found")
+)
+
+// invalidRequest error of missing or malformed parameter
+func invalidRequest(description string) *oauthError {
+	return newError(http.StatusBadRequest, "invalid_request", description)
+}
+
+// ServeHTTP route request to endpoint handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	var err error
+	switch strings.Trim(r.URL.Path, "/") {
+	case "authorize":
+		err = h.authorize(w, r)
+	case "token":
+		err = h.token(w, r)
+	case "revoke":
+		err = h.revoke(w, r)
+	case ".well-known/openid-configuration":
+		err = h.discovery(w, r)
+	case "jwks":
+		err = h.jwks(w, r)
+	case "userinfo":
+		err = h.userinfo(w, r)
+	case "logout":
+		err = h.logout(w, r)
+	default:
+		err = errNotFound
+	}
+
+	if err != nil {
+		var oe *oauthError
+		if !errors.As(err, &oe) {
+			hclog.Default().Error("[oauth.ServeHTTP] handle request", "method", r.Method, "path", r.URL.Path, "error", err)
+			oe = errServer
+		}
+		writeError(w, oe)
+	}
+}
+
+// writeJSON write json response which must not be cached
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		hclog.Default().Error("[oauth.writeJSON] json.Encode", "error", err)
+	}
+}
+
+// writeError write error response, basic authentication is requested on client authentication failure
+func writeError(w http.ResponseWriter, oe *oauthError) {
+	if oe.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	writeJSON(w, oe.status, oe)
+}
+
+// audit record action of user, failure is only logged because action is already done
+func (h *Handler) audit(user *models.User, method string) {
+	err := h.db.AddAuditEntry(&models.AuditEntry{
+		ActorID:    user.ID,
+		ActorEmail: user.Email,
+		SubjectID:  user.ID,
+		Method:     "oauth." + method,
+		Code:       "OK",
+	})
+	if err != nil {
+		hclog.Default().Error("[oauth.audit] h.db.AddAuditEntry", "method", method, "userID", user.ID, "error", err)
+	}
+}
+
+// isNotFound check error is not found error of repository
+func isNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,235 @@
+package oauth
+
+import (
+	"account-service/internal/models"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// idTokenTTL how long id token is valid
+const idTokenTTL = time.Hour
+
+// supportedScopes scopes of OpenID Connect claims
+var supportedScopes = []string{models.ScopeOpenID, models.ScopeProfile, models.ScopeEmail}
+
+// discovery handle OpenID provider configuration endpoint
+func (h *Handler) discovery(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return errMethod
+	}
+
+	issuer := h.cfg.OIDCIssuer
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"end_session_endpoint":                  issuer + "/logout",
+		"revocation_endpoint":                   issuer + "/revoke",
+		"response_types_supported":              []string{"code"},
+		"response_modes_supported":              []string{"query"},
+		"grant_types_supported":                 models.OAuthGrantTypes,
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodRS256.Alg()},
+		"scopes_supported":                      supportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{codeChallengeS256},
+		"prompt_values_supported":               []string{"none", "login", "consent"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "azp", "at_hash",
+			"email", "email_verified", "given_name", "family_name", "name", "updated_at",
+		},
+	})
+
+	return nil
+}
+
+// jwks handle key set endpoint with public key of id tokens
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return errMethod
+	}
+
+	writeJSON(w, http.StatusOK, h.signer.JWKS())
+
+	return nil
+}
+
+// userinfo handle userinfo endpoint, claims are returned for access token with openid scope
+func (h *Handler) userinfo(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return errMethod
+	}
+
+	ctx, span := h.trace.Start(r.Context(), "oauth.userinfo")
+	defer span.End()
+
+	accessToken := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if accessToken == "" || accessToken == r.Header.Get("Authorization") {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+		writeJSON(w, http.StatusUnauthorized, invalidRequest("bearer token is required"))
+		return nil
+	}
+
+	user, tok, err := h.accounts.AccountInfo(ctx, accessToken)
+	if status.Code(err) == codes.Internal {
+		return fmt.Errorf("h.accounts.AccountInfo error: %w", err)
+	}
+	if err != nil || user.IsDeactivated() {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, newError(http.StatusUnauthorized, "invalid_token", "access token is invalid"))
+		return nil
+	}
+
+	scopes := strings.Fields(claimString(tok, models.ScopeClaim))
+	if !containsScope(scopes, models.ScopeOpenID) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		writeJSON(w, http.StatusForbidden, newError(http.StatusForbidden, "insufficient_scope", "openid scope is required"))
+		return nil
+	}
+
+	writeJSON(w, http.StatusOK, userClaims(user, scopes, jwt.MapClaims{"sub": user.ID}))
+
+	return nil
+}
+
+// userClaims add claims of user allowed by scopes to claims
+func userClaims(user *models.User, scopes []string, claims jwt.MapClaims) jwt.MapClaims {
+	if containsScope(scopes, models.ScopeProfile) {
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+	if containsScope(scopes, models.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerified
+	}
+
+	return claims
+}
+
+// idToken signed id token of user for client, access token is bound by at_hash
+func (h *Handler) idToken(client *models.OAuthClient, user *models.User, scopes []string, grant idTokenGrant, accessToken string) (string, error) {
+	now := time.Now()
+	atHash := sha256.Sum256([]byte(accessToken))
+
+	claims := userClaims(user, scopes, jwt.MapClaims{
+		"iss":     h.cfg.OIDCIssuer,
+		"sub":     user.ID,
+		"aud":     client.ID,
+		"azp":     client.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(idTokenTTL).Unix(),
+		"at_hash": base64.RawURLEncoding.EncodeToString(atHash[:len(atHash)/2]),
+	})
+	if grant.authTime != 0 {
+		claims[models.AuthTimeClaim] = grant.authTime
+	}
+	if grant.nonce != "" {
+		claims[models.NonceClaim] = grant.nonce
+	}
+
+	signed, err := h.signer.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("h.signer.Sign error: %w", err)
+	}
+
+	return signed, nil
+}
+
+// idTokenGrant authentication of user carried from authorization request to id token
+type idTokenGrant struct {
+	authTime int64
+	nonce    string
+}
+
+// logout handle RP-initiated logout endpoint, browser session is ended
+// and user is returned to registered post logout redirect uri of client
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return errMethod
+	}
+
+	ctx, span := h.trace.Start(r.Context(), "oauth.logout")
+	defer span.End()
+
+	err := r.ParseForm()
+	if err != nil {
+		return invalidRequest("request is malformed")
+	}
+
+	clientID := r.Form.Get("client_id")
+	if hint := r.Form.Get("id_token_hint"); hint != "" {
+		claims, err := h.signer.Parse(hint)
+		if err != nil || claims["iss"] != h.cfg.OIDCIssuer {
+			renderPage(w, http.StatusBadRequest, errorPage, pageData{Error: "id_token_hint is invalid"})
+			return nil
+		}
+
+		audience, _ := claims["aud"].(string)
+		if clientID != "" && clientID != audience {
+			renderPage(w, http.StatusBadRequest, errorPage, pageData{Error: "client_id doesn't match id_token_hint"})
+			return nil
+		}
+		clientID = audience
+	}
+
+	var target *url.URL
+	if redirectURI := r.Form.Get("post_logout_redirect_uri"); redirectURI != "" {
+		client, err := h.db.GetOAuthClientByID(clientID)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("h.db.GetOAuthClientByID error: %w", err)
+		}
+		if client == nil || !client.HasPostLogoutRedirectURI(redirectURI) {
+			renderPage(w, http.StatusBadRequest, errorPage, pageData{Error: "post_logout_redirect_uri is not registered for client"})
+			return nil
+		}
+
+		target, err = url.Parse(redirectURI)
+		if err != nil {
+			return invalidRequest("post_logout_redirect_uri is malformed")
+		}
+	}
+
+	_, session, err := h.session(ctx, r)
+	if err != nil {
+		return err
+	}
+	if session != nil {
+		h.tokenSrv.Revoke(session)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     h.cookiePath(),
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if target == nil {
+		renderPage(w, http.StatusOK, logoutPage, pageData{})
+		return nil
+	}
+
+	if state := r.Form.Get("state"); state != "" {
+		query := target.Query()
+		query.Set("state", state)
+		target.RawQuery = query.Encode()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.String(), http.StatusFound)
+
+	return nil
+}
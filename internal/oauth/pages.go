@@ -0,0 +1,110 @@
+package oauth
+
+import (
+	"account-service/internal/models"
+	"github.com/hashicorp/go-hclog"
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+// pageData data of login, consent, error and logout pages
+type pageData struct {
+	ClientName string
+	Scopes     []string
+	Params     url.Values
+	CSRF       string
+	Error      string
+}
+
+// pageData data of page of authorization request
+func (h *Handler) pageData(req *authorizeRequest, session *models.JWT, message string) pageData {
+	return pageData{
+		ClientName: req.client.Name,
+		Scopes:     req.scopes,
+		Params:     req.values(),
+		CSRF:       h.csrfToken(session),
+		Error:      message,
+	}
+}
+
+// layout common markup of pages
+const layout = `{{define "layout"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{template "title" .}}</title>
+<style>
+body{font-family:sans-serif;background:#f4f5f7;margin:0}
+main{max-width:360px;margin:10vh auto;background:#fff;padding:24px;border-radius:8px;box-shadow:0 1px 4px rgba(0,0,0,.15)}
+label,input,button{display:block;width:100%;box-sizing:border-box}
+input{margin:4px 0 12px;padding:8px}
+button{padding:10px;margin-top:8px;cursor:pointer}
+.error{color:#b00020}
+</style>
+</head>
+<body><main>{{template "content" .}}</main></body>
+</html>{{end}}
+{{define "params"}}{{range $key, $values := .Params}}{{range $values}}<input type="hidden" name="{{$key}}" value="{{.}}">{{end}}{{end}}{{end}}`
+
+var (
+	loginPage = template.Must(template.New("login").Parse(layout + `
+{{define "title"}}Sign in{{end}}
+{{define "content"}}
+<h1>Sign in</h1>
+<p>to continue to {{.ClientName}}</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="authorize">
+{{template "params" .}}
+<label for="email">Email</label>
+<input id="email" name="email" type="email" autocomplete="username" required autofocus>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+<button type="submit" name="action" value="login">Sign in</button>
+</form>
+{{end}}`))
+
+	consentPage = template.Must(template.New("consent").Parse(layout + `
+{{define "title"}}Allow access{{end}}
+{{define "content"}}
+<h1>Allow access</h1>
+<p>{{.ClientName}} wants to access your account{{if .Scopes}} with scopes:{{end}}</p>
+{{if .Scopes}}<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<form method="post" action="authorize">
+{{template "params" .}}
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+<button type="submit" name="action" value="allow">Allow</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+{{end}}`))
+
+	errorPage = template.Must(template.New("error").Parse(layout + `
+{{define "title"}}Authorization error{{end}}
+{{define "content"}}
+<h1>Authorization error</h1>
+<p class="error">{{.Error}}</p>
+{{end}}`))
+
+	logoutPage = template.Must(template.New("logout").Parse(layout + `
+{{define "title"}}Signed out{{end}}
+{{define "content"}}
+<h1>Signed out</h1>
+<p>You have been signed out.</p>
+{{end}}`))
+)
+
+// renderPage write html page which must not be cached or framed
+func renderPage(w http.ResponseWriter, status int, page *template.Template, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+
+	err := page.ExecuteTemplate(w, "layout", data)
+	if err != nil {
+		hclog.Default().Error("[oauth.renderPage] page.ExecuteTemplate", "page", page.Name(), "error", err)
+	}
+}
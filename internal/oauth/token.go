@@ -0,0 +1,397 @@
+package oauth
+
+import (
+	"account-service/internal/models"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pkceRe code verifier and S256 code challenge of RFC 7636
+var pkceRe = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// tokenResponse successful response of token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// token handle token endpoint
+func (h *Handler) token(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return errMethod
+	}
+
+	ctx, span := h.trace.Start(r.Context(), "oauth.token")
+	defer span.End()
+
+	err := r.ParseForm()
+	if err != nil {
+		return invalidRequest("request is malformed")
+	}
+
+	client, err := h.authenticateClient(r)
+	if err != nil {
+		return err
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	switch grantType {
+	case models.GrantAuthorizationCode, models.GrantRefreshToken, models.GrantClientCredentials:
+	case "":
+		return invalidRequest("grant_type is required")
+	default:
+		return errUnsupportedGrantType
+	}
+	if !client.AllowsGrant(grantType) {
+		return errUnauthorizedClient
+	}
+
+	var resp *tokenResponse
+	switch grantType {
+	case models.GrantAuthorizationCode:
+		resp, err = h.exchangeCode(ctx, r, client)
+	case models.GrantRefreshToken:
+		resp, err = h.refresh(ctx, r, client)
+	case models.GrantClientCredentials:
+		resp, err = h.clientCredentials(ctx, client, r.PostForm)
+	}
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+
+	return nil
+}
+
+// exchangeCode exchange authorization code for tokens, code can be used only once
+func (h *Handler) exchangeCode(ctx context.Context, r *http.Request, client *models.OAuthClient) (*tokenResponse, error) {
+	form := r.PostForm
+	code := form.Get("code")
+	if code == "" {
+		return nil, invalidRequest("code is required")
+	}
+
+	grant, err := h.tokenSrv.ParseJWT(ctx, code)
+	if err != nil {
+		return nil, errInvalidGrant
+	}
+	if claimString(grant, models.ClientIDClaim) != client.ID {
+		return nil, errInvalidGrant
+	}
+	if err := h.tokenSrv.Consume(ctx, grant, models.AuthorizationCodeToken); err != nil {
+		return nil, errInvalidGrant
+	}
+
+	if form.Get("redirect_uri") != claimString(grant, models.RedirectURIClaim) {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "redirect_uri doesn't match authorization request")
+	}
+
+	if !verifyPKCE(claimString(grant, models.CodeChallengeClaim), form.Get("code_verifier")) {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "code_verifier doesn't match code_challenge")
+	}
+
+	user, err := h.activeUser(grant.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.issueTokens(ctx, r, client, user, strings.Fields(claimString(grant, models.ScopeClaim)), idTokenGrant{
+		authTime: claimInt(grant, models.AuthTimeClaim),
+		nonce:    claimString(grant, models.NonceClaim),
+	})
+}
+
+// refresh exchange refresh token for new tokens, refresh token is rotated and can be used only once,
+// consent of user must still cover scopes, id token of refresh has no nonce
+func (h *Handler) refresh(ctx context.Context, r *http.Request, client *models.OAuthClient) (*tokenResponse, error) {
+	form := r.PostForm
+	refreshToken := form.Get("refresh_token")
+	if refreshToken == "" {
+		return nil, invalidRequest("refresh_token is required")
+	}
+
+	grant, err := h.tokenSrv.ParseJWT(ctx, refreshToken)
+	if err != nil {
+		return nil, errInvalidGrant
+	}
+	if claimString(grant, models.ClientIDClaim) != client.ID {
+		return nil, errInvalidGrant
+	}
+
+	granted := strings.Fields(claimString(grant, models.ScopeClaim))
+	scopes := granted
+	if requested := strings.Fields(form.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !containsScope(granted, scope) {
+				return nil, errInvalidScope
+			}
+		}
+		scopes = uniqueScopes(requested)
+	}
+
+	if err := h.tokenSrv.Consume(ctx, grant, models.RefreshAccessToken); err != nil {
+		return nil, errInvalidGrant
+	}
+
+	user, err := h.activeUser(grant.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Trusted {
+		consent, err := h.db.GetConsent(user.ID, client.ID)
+		if err != nil {
+			return nil, fmt.Errorf("h.db.GetConsent error: %w", err)
+		}
+		if consent == nil || !consent.Covers(scopes) {
+			return nil, newError(http.StatusBadRequest, "invalid_grant", "consent is revoked")
+		}
+	}
+
+	return h.issueTokens(ctx, r, client, user, scopes, idTokenGrant{
+		authTime: claimInt(grant, models.AuthTimeClaim),
+	})
+}
+
+// clientCredentials issue access token of client itself with permissions of role of client
+func (h *Handler) clientCredentials(ctx context.Context, client *models.OAuthClient, form url.Values) (*tokenResponse, error) {
+	if !client.IsConfidential() {
+		return nil, errUnauthorizedClient
+	}
+
+	scopes := strings.Fields(form.Get("scope"))
+	if len(scopes) == 0 {
+		scopes = strings.Fields(client.Scopes)
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, errInvalidScope
+	}
+	scope := strings.Join(uniqueScopes(scopes), " ")
+
+	permissions, err := h.db.GetRolesPermissions([]uint32{client.RoleID})
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetRolesPermissions error: %w", err)
+	}
+
+	roles := []models.EffectiveRole{{RoleID: client.RoleID, Permissions: permissions[client.RoleID]}}
+	access, err := h.tokenSrv.NewJWT(ctx, models.AccessToken, client.ID, "", jwt.MapClaims{
+		models.ClientIDClaim:    client.ID,
+		models.ScopeClaim:       scope,
+		models.PermissionsClaim: models.GlobalPermissions(roles),
+		models.RolesClaim:       roles,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("h.tokenSrv.NewJWT error: %w", err)
+	}
+
+	return &tokenResponse{
+		AccessToken: access.ToJWTString(),
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(models.Expirations[models.AccessToken].Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// issueTokens issue access token of user for client, refresh token is issued if client may use refresh grant
+// and id token is issued if openid scope is granted
+func (h *Handler) issueTokens(ctx context.Context, r *http.Request, client *models.OAuthClient, user *models.User, scopes []string, grant idTokenGrant) (*tokenResponse, error) {
+	scope := strings.Join(scopes, " ")
+
+	claims, err := h.accounts.AccessClaims(user)
+	if err != nil {
+		return nil, fmt.Errorf("h.accounts.AccessClaims error: %w", err)
+	}
+	claims[models.ClientIDClaim] = client.ID
+	claims[models.ScopeClaim] = scope
+
+	resp := &tokenResponse{
+		TokenType: "Bearer",
+		ExpiresIn: int64(models.Expirations[models.AccessToken].Seconds()),
+		Scope:     scope,
+	}
+
+	if !client.AllowsGrant(models.GrantRefreshToken) {
+		access, err := h.tokenSrv.NewJWT(ctx, models.AccessToken, user.ID, user.Email, claims)
+		if err != nil {
+			return nil, fmt.Errorf("h.tokenSrv.NewJWT error: %w", err)
+		}
+		resp.AccessToken = access.ToJWTString()
+	} else {
+		access, refresh, err := h.tokenSrv.CreateSessionJWT(ctx, user.ID, user.Email, claims, jwt.MapClaims{
+			models.ClientIDClaim: client.ID,
+			models.ScopeClaim:    scope,
+			models.AuthTimeClaim: grant.authTime,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("h.tokenSrv.CreateSessionJWT error: %w", err)
+		}
+		resp.AccessToken = access.ToJWTString()
+		resp.RefreshToken = refresh.ToJWTString()
+	}
+
+	if containsScope(scopes, models.ScopeOpenID) {
+		resp.IDToken, err = h.idToken(client, user, scopes, grant, resp.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// revoke handle token revocation endpoint of RFC 7009, unknown tokens and tokens of other clients are ignored
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return errMethod
+	}
+
+	ctx, span := h.trace.Start(r.Context(), "oauth.revoke")
+	defer span.End()
+
+	err := r.ParseForm()
+	if err != nil {
+		return invalidRequest("request is malformed")
+	}
+
+	client, err := h.authenticateClient(r)
+	if err != nil {
+		return err
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return invalidRequest("token is required")
+	}
+
+	tok, err := h.tokenSrv.ParseJWT(ctx, token)
+	if err == nil && claimString(tok, models.ClientIDClaim) == client.ID {
+		h.tokenSrv.Revoke(tok)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+// authenticateClient find client by basic authentication or form parameters,
+// confidential clients must present secret and public clients must not
+func (h *Handler) authenticateClient(r *http.Request) (*models.OAuthClient, error) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// credentials of basic authentication are form encoded by RFC 6749
+		var err error
+		if clientID, err = url.QueryUnescape(clientID); err != nil {
+			return nil, errInvalidClient
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return nil, errInvalidClient
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	if clientID == "" {
+		return nil, errInvalidClient
+	}
+
+	client, err := h.db.GetOAuthClientByID(clientID)
+	if isNotFound(err) {
+		return nil, errInvalidClient
+	}
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetOAuthClientByID error: %w", err)
+	}
+
+	if !client.IsConfidential() {
+		if secret != "" {
+			return nil, errInvalidClient
+		}
+		return client, nil
+	}
+
+	if secret == "" {
+		return nil, errInvalidClient
+	}
+	valid, err := h.secrets.Verify(client.SecretHash, secret)
+	if err != nil {
+		return nil, fmt.Errorf("h.secrets.Verify error: %w", err)
+	}
+	if !valid {
+		return nil, errInvalidClient
+	}
+
+	return client, nil
+}
+
+// activeUser user of grant, grant is invalid if user is removed or deactivated
+func (h *Handler) activeUser(id string) (*models.User, error) {
+	user, err := h.db.GetUserByID(id)
+	if isNotFound(err) {
+		return nil, errInvalidGrant
+	}
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetUserByID error: %w", err)
+	}
+	if user.IsDeactivated() {
+		return nil, errInvalidGrant
+	}
+
+	return user, nil
+}
+
+// verifyPKCE check code verifier matches S256 code challenge, verifier must be absent if there is no challenge
+func verifyPKCE(challenge, verifier string) bool {
+	if challenge == "" {
+		return verifier == ""
+	}
+	if !validPKCEValue(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// validPKCEValue check code verifier or challenge has allowed characters and length
+func validPKCEValue(value string) bool {
+	return pkceRe.MatchString(value)
+}
+
+// claimString string claim of token, empty if claim is absent
+func claimString(tok *models.JWT, claim string) string {
+	value, _ := tok.Extra[claim].(string)
+	return value
+}
+
+// claimInt integer claim of token, zero if claim is absent
+func claimInt(tok *models.JWT, claim string) int64 {
+	value, _ := tok.Extra[claim].(float64)
+	return int64(value)
+}
+
+// containsScope check scope is in scopes
+func containsScope(scopes []string, scope string) bool {
+	for _, item := range scopes {
+		if item == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Engine policy engine with hot reload of policy file
+type Engine struct {
+	mu       sync.RWMutex
+	policy   *Policy
+	filename string
+	modTime  time.Time
+}
+
+// NewEngine create policy engine from file, engine without file denies everything
+func NewEngine(filename string) (*Engine, error) {
+	e := &Engine{
+		policy:   &Policy{},
+		filename: filename,
+	}
+
+	if filename == "" {
+		return e, nil
+	}
+
+	err := e.reload()
+	if err != nil {
+		return nil, fmt.Errorf("e.reload error: %w", err)
+	}
+
+	return e, nil
+}
+
+// Evaluate evaluate request against current policy
+func (e *Engine) Evaluate(req Request, explain bool) Decision {
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+
+	return p.Evaluate(req, explain)
+}
+
+// Watch reload policy file when it is changed until context is done,
+// invalid policy is logged and previous policy is kept
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	log := hclog.Default()
+
+	if e.filename == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(e.filename)
+			if err != nil {
+				log.Error("[policy.Watch] os.Stat", "filename", e.filename, "error", err)
+				continue
+			}
+
+			// modification time is remembered only by successful reload,
+			// so half-written file is loaded again on next tick
+			e.mu.RLock()
+			changed := !info.ModTime().Equal(e.modTime)
+			e.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			err = e.reload()
+			if err != nil {
+				log.Error("[policy.Watch] e.reload", "filename", e.filename, "error", err)
+				continue
+			}
+
+			log.Info("policy is reloaded", "filename", e.filename)
+		}
+	}
+}
+
+// reload load policy from file
+func (e *Engine) reload() error {
+	info, err := os.Stat(e.filename)
+	if err != nil {
+		return fmt.Errorf("os.Stat error: %w", err)
+	}
+
+	p, err := LoadPolicy(e.filename)
+	if err != nil {
+		return fmt.Errorf("LoadPolicy error: %w", err)
+	}
+
+	e.mu.Lock()
+	e.policy = p
+	e.modTime = info.ModTime()
+	e.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Effect effect of matched rule
+type Effect string
+
+const (
+	// Allow allow access
+	Allow Effect = "allow"
+	// Deny deny access
+	Deny Effect = "deny"
+)
+
+// Operator condition operator
+type Operator string
+
+const (
+	// Equals attribute has single value equal to one of expected
+	Equals Operator = "equals"
+	// NotEquals attribute is not single value equal to one of expected
+	NotEquals Operator = "not_equals"
+	// AnyOf any of attribute values is one of expected
+	AnyOf Operator = "any_of"
+	// NoneOf none of attribute values is one of expected
+	NoneOf Operator = "none_of"
+	// Prefix any of attribute values starts with one of expected
+	Prefix Operator = "prefix"
+	// Exists attribute has any value
+	Exists Operator = "exists"
+)
+
+// Policy ordered rules, first matched rule decides, access is denied if no rule matched
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule policy rule
+type Rule struct {
+	Name       string      `json:"name"`
+	Effect     Effect      `json:"effect"`
+	Actions    []string    `json:"actions"`
+	Resources  []string    `json:"resources"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// Condition condition over attributes, expected values are taken from value and values
+// or from another attribute named by value_from
+type Condition struct {
+	Attribute string   `json:"attribute"`
+	Operator  Operator `json:"operator"`
+	Value     string   `json:"value,omitempty"`
+	Values    []string `json:"values,omitempty"`
+	ValueFrom string   `json:"value_from,omitempty"`
+}
+
+// Attributes values of attributes by name, single value attribute is list of one value
+type Attributes map[string][]string
+
+// Request access request
+type Request struct {
+	Action     string
+	Resource   string
+	Attributes Attributes
+}
+
+// RuleTrace result of rule evaluation for explain mode
+type RuleTrace struct {
+	Rule    string
+	Matched bool
+	Reason  string
+}
+
+// Decision result of policy evaluation
+type Decision struct {
+	Allowed bool
+	Rule    string
+	Trace   []RuleTrace
+}
+
+// LoadPolicy read and validate policy file
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile error: %w", err)
+	}
+
+	var p Policy
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	err = p.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("p.Validate error: %w", err)
+	}
+
+	return &p, nil
+}
+
+// Validate check rules of policy
+func (p *Policy) Validate() error {
+	names := make(map[string]struct{}, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("rule %s is duplicated", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return fmt.Errorf("rule %s has invalid effect %q", rule.Name, rule.Effect)
+		}
+
+		if len(rule.Actions) == 0 || len(rule.Resources) == 0 {
+			return fmt.Errorf("rule %s must have actions and resources", rule.Name)
+		}
+
+		for _, pattern := range append(append([]string{}, rule.Actions...), rule.Resources...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s has invalid pattern %q: %w", rule.Name, pattern, err)
+			}
+		}
+
+		for _, condition := range rule.Conditions {
+			switch condition.Operator {
+			case Equals, NotEquals, AnyOf, NoneOf, Prefix, Exists:
+			default:
+				return fmt.Errorf("rule %s has invalid operator %q", rule.Name, condition.Operator)
+			}
+
+			if condition.Attribute == "" {
+				return fmt.Errorf("rule %s has condition without attribute", rule.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Evaluate evaluate request against policy, trace is filled in explain mode
+func (p *Policy) Evaluate(req Request, explain bool) Decision {
+	var decision Decision
+
+	for _, rule := range p.Rules {
+		matched, reason := rule.match(req)
+		if explain {
+			decision.Trace = append(decision.Trace, RuleTrace{
+				Rule:    rule.Name,
+				Matched: matched,
+				Reason:  reason,
+			})
+		}
+
+		if matched {
+			decision.Allowed = rule.Effect == Allow
+			decision.Rule = rule.Name
+			return decision
+		}
+	}
+
+	return decision
+}
+
+// match check rule is applied to request, reason explains result
+func (r *Rule) match(req Request) (bool, string) {
+	if !matchAny(r.Actions, req.Action) {
+		return false, fmt.Sprintf("action %q is not matched", req.Action)
+	}
+
+	if !matchAny(r.Resources, req.Resource) {
+		return false, fmt.Sprintf("resource %q is not matched", req.Resource)
+	}
+
+	for _, condition := range r.Conditions {
+		if !condition.match(req.Attributes) {
+			return false, fmt.Sprintf("condition %s %s is not satisfied", condition.Attribute, condition.Operator)
+		}
+	}
+
+	return true, fmt.Sprintf("effect %s", r.Effect)
+}
+
+// match check condition is satisfied by attributes
+func (c *Condition) match(attributes Attributes) bool {
+	actual := attributes[c.Attribute]
+
+	expected := c.Values
+	if c.Value != "" {
+		expected = append([]string{c.Value}, expected...)
+	}
+	if c.ValueFrom != "" {
+		expected = attributes[c.ValueFrom]
+	}
+
+	switch c.Operator {
+	case Equals:
+		return len(actual) == 1 && contains(expected, actual[0])
+	case NotEquals:
+		return len(actual) != 1 || !contains(expected, actual[0])
+	case AnyOf:
+		return intersects(actual, expected)
+	case NoneOf:
+		return !intersects(actual, expected)
+	case Prefix:
+		for _, value := range actual {
+			for _, prefix := range expected {
+				if strings.HasPrefix(value, prefix) {
+					return true
+				}
+			}
+		}
+		return false
+	case Exists:
+		return len(actual) > 0
+	}
+
+	return false
+}
+
+// matchAny check value is matched by any of patterns, * matches everything
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, value := range a {
+		if contains(b, value) {
+			return true
+		}
+	}
+
+	return false
+}
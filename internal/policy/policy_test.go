@@ -0,0 +1,210 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{Name: "deny-secrets", Effect: Deny, Actions: []string{"*"}, Resources: []string{"secrets/*"}},
+		{
+			Name: "read-documents", Effect: Allow, Actions: []string{"read", "list"}, Resources: []string{"documents/*"},
+			Conditions: []Condition{{Attribute: "user.permissions", Operator: AnyOf, Value: "documents:read"}},
+		},
+		{Name: "all", Effect: Allow, Actions: []string{"*"}, Resources: []string{"*"}, Conditions: []Condition{{Attribute: "user.admin", Operator: Exists}}},
+	}}
+
+	tests := []struct {
+		name       string
+		action     string
+		resource   string
+		attributes Attributes
+		allowed    bool
+		rule       string
+	}{
+		{name: "first matched rule denies", action: "read", resource: "secrets/key", attributes: Attributes{"user.admin": {"1"}}, rule: "deny-secrets"},
+		{name: "rule with satisfied condition", action: "read", resource: "documents/1", attributes: Attributes{"user.permissions": {"documents:read"}}, allowed: true, rule: "read-documents"},
+		{name: "action isn't matched", action: "write", resource: "documents/1", attributes: Attributes{"user.permissions": {"documents:read"}}},
+		{name: "later rule matches", action: "write", resource: "documents/1", attributes: Attributes{"user.admin": {"1"}}, allowed: true, rule: "all"},
+		{name: "no rule matches", action: "read", resource: "documents/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(Request{Action: tt.action, Resource: tt.resource, Attributes: tt.attributes}, true)
+			if decision.Allowed != tt.allowed || decision.Rule != tt.rule {
+				t.Errorf("decision = %+v, want allowed %v by rule %q", decision, tt.allowed, tt.rule)
+			}
+
+			// rules after matched one are not evaluated
+			last := decision.Trace[len(decision.Trace)-1]
+			if tt.rule != "" && (last.Rule != tt.rule || !last.Matched) {
+				t.Errorf("trace = %+v, want to end by matched rule %s", decision.Trace, tt.rule)
+			}
+			if tt.rule == "" && len(decision.Trace) != len(p.Rules) {
+				t.Errorf("trace = %+v, want all rules", decision.Trace)
+			}
+		})
+	}
+
+	if decision := p.Evaluate(Request{Action: "read", Resource: "secrets/key"}, false); decision.Trace != nil {
+		t.Errorf("trace = %+v, want none without explain", decision.Trace)
+	}
+	if decision := (&Policy{}).Evaluate(Request{Action: "read", Resource: "documents/1"}, false); decision.Allowed {
+		t.Errorf("empty policy allows %+v", decision)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	attributes := Attributes{
+		"user.id":          {"user-1"},
+		"user.roles":       {"1", "2"},
+		"user.path":        {"10", "20"},
+		"request.owner":    {"user-1"},
+		"request.path":     {"/teams/20/docs"},
+		"request.category": {},
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		matched   bool
+	}{
+		{name: "equals", condition: Condition{Attribute: "user.id", Operator: Equals, Value: "user-1"}, matched: true},
+		{name: "equals one of values", condition: Condition{Attribute: "user.id", Operator: Equals, Values: []string{"user-2", "user-1"}}, matched: true},
+		{name: "equals other value", condition: Condition{Attribute: "user.id", Operator: Equals, Value: "user-2"}},
+		{name: "equals isn't satisfied by many values", condition: Condition{Attribute: "user.roles", Operator: Equals, Value: "1"}},
+		{name: "equals missing attribute", condition: Condition{Attribute: "user.email", Operator: Equals, Value: ""}},
+		{name: "equals value from attribute", condition: Condition{Attribute: "user.id", Operator: Equals, ValueFrom: "request.owner"}, matched: true},
+		{name: "equals value from missing attribute", condition: Condition{Attribute: "user.id", Operator: Equals, ValueFrom: "request.group"}},
+		{name: "not equals", condition: Condition{Attribute: "user.id", Operator: NotEquals, Value: "user-2"}, matched: true},
+		{name: "not equals same value", condition: Condition{Attribute: "user.id", Operator: NotEquals, Value: "user-1"}},
+		{name: "not equals missing attribute", condition: Condition{Attribute: "user.email", Operator: NotEquals, Value: "user-1"}, matched: true},
+		{name: "any of", condition: Condition{Attribute: "user.roles", Operator: AnyOf, Values: []string{"2", "3"}}, matched: true},
+		{name: "any of no value", condition: Condition{Attribute: "user.roles", Operator: AnyOf, Values: []string{"3"}}},
+		{name: "none of", condition: Condition{Attribute: "user.roles", Operator: NoneOf, Values: []string{"3"}}, matched: true},
+		{name: "none of with value", condition: Condition{Attribute: "user.roles", Operator: NoneOf, Values: []string{"1"}}},
+		{name: "prefix", condition: Condition{Attribute: "request.path", Operator: Prefix, Value: "/teams/"}, matched: true},
+		{name: "prefix other value", condition: Condition{Attribute: "request.path", Operator: Prefix, Value: "/users/"}},
+		{name: "exists", condition: Condition{Attribute: "user.id", Operator: Exists}, matched: true},
+		{name: "exists empty attribute", condition: Condition{Attribute: "request.category", Operator: Exists}},
+		{name: "exists missing attribute", condition: Condition{Attribute: "user.email", Operator: Exists}},
+		{name: "unknown operator", condition: Condition{Attribute: "user.id", Operator: "like", Value: "user-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matched := tt.condition.match(attributes); matched != tt.matched {
+				t.Errorf("matched = %v, want %v", matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Rule{Name: "rule", Effect: Allow, Actions: []string{"read"}, Resources: []string{"*"}}
+
+	tests := []struct {
+		name   string
+		change func(rule *Rule)
+		err    string
+	}{
+		{name: "valid", change: func(*Rule) {}},
+		{name: "no name", change: func(r *Rule) { r.Name = "" }, err: "has no name"},
+		{name: "invalid effect", change: func(r *Rule) { r.Effect = "maybe" }, err: "invalid effect"},
+		{name: "no actions", change: func(r *Rule) { r.Actions = nil }, err: "must have actions and resources"},
+		{name: "invalid pattern", change: func(r *Rule) { r.Resources = []string{"documents/["} }, err: "invalid pattern"},
+		{name: "invalid operator", change: func(r *Rule) { r.Conditions = []Condition{{Attribute: "user.id", Operator: "like"}} }, err: "invalid operator"},
+		{name: "condition without attribute", change: func(r *Rule) { r.Conditions = []Condition{{Operator: Exists}} }, err: "without attribute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.change(&rule)
+
+			err := (&Policy{Rules: []Rule{rule}}).Validate()
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	err := (&Policy{Rules: []Rule{valid, valid}}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "duplicated") {
+		t.Errorf("duplicated rule error = %v", err)
+	}
+}
+
+func TestEngineWatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy := func(data string, modTime time.Time) {
+		t.Helper()
+
+		err := os.WriteFile(filename, []byte(data), 0o600)
+		if err != nil {
+			t.Fatalf("os.WriteFile error: %v", err)
+		}
+		err = os.Chtimes(filename, modTime, modTime)
+		if err != nil {
+			t.Fatalf("os.Chtimes error: %v", err)
+		}
+	}
+	allowed := func(e *Engine) bool {
+		return e.Evaluate(Request{Action: "read", Resource: "documents/1"}, false).Allowed
+	}
+	waitFor := func(e *Engine, want bool) {
+		t.Helper()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for allowed(e) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("allowed = %v, want %v after reload", !want, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	writePolicy(`{"rules": [{"name": "deny", "effect": "deny", "actions": ["*"], "resources": ["*"]}]}`, start)
+
+	e, err := NewEngine(filename)
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	if allowed(e) {
+		t.Fatalf("initial policy allows")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Watch(ctx, 10*time.Millisecond)
+
+	// changed modification time reloads policy
+	writePolicy(`{"rules": [{"name": "allow", "effect": "allow", "actions": ["*"], "resources": ["*"]}]}`, start.Add(time.Minute))
+	waitFor(e, true)
+
+	// invalid policy keeps previous one until file is fixed
+	writePolicy(`{"rules": [{"name": "broken"`, start.Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if !allowed(e) {
+		t.Fatalf("invalid policy replaced previous policy")
+	}
+
+	writePolicy(`{"rules": [{"name": "deny", "effect": "deny", "actions": ["*"], "resources": ["*"]}]}`, start.Add(2*time.Minute))
+	waitFor(e, false)
+
+	if _, err := NewEngine(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("NewEngine of missing file succeeded")
+	}
+
+	empty, err := NewEngine("")
+	if err != nil || allowed(empty) {
+		t.Errorf("engine without file = %v, %v, want denying engine", empty, err)
+	}
+}
@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"time"
+)
+
+// maxReportSize max users in erasure report
+const maxReportSize = 1000
+
+// Repository repository of users to erase
+type Repository interface {
+	GetErasureCandidates(deletedBefore time.Time, limit int) ([]models.ErasureCandidate, error)
+	EraseUser(id, mode, pseudonym string) error
+}
+
+// Worker erase personal data of users deleted longer than retention period or requested erasure
+type Worker struct {
+	db      Repository
+	cfg     *config.Config
+	trigger chan struct{}
+}
+
+// NewWorker create retention worker
+func NewWorker(db Repository, cfg *config.Config) *Worker {
+	return &Worker{
+		db:      db,
+		cfg:     cfg,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Run erase users by interval or trigger until context is done,
+// first run waits for interval so migrations of startup are finished and report can be checked
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.ErasureInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.trigger:
+		}
+
+		w.erase()
+	}
+}
+
+// Trigger run erasure without waiting for interval
+func (w *Worker) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Report users which would be erased by next run
+func (w *Worker) Report() ([]models.ErasureCandidate, error) {
+	candidates, err := w.db.GetErasureCandidates(w.DeletedBefore(), maxReportSize)
+	if err != nil {
+		return nil, fmt.Errorf("w.db.GetErasureCandidates error: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// DeletedBefore users deleted before this moment are out of retention period
+func (w *Worker) DeletedBefore() time.Time {
+	return time.Now().Add(-w.cfg.ErasureRetention)
+}
+
+// Pseudonym stable pseudonym of user id which can't be reversed without secret
+func (w *Worker) Pseudonym(userID string) string {
+	mac := hmac.New(sha256.New, []byte(w.cfg.AesSecret))
+	mac.Write([]byte(userID))
+
+	return "erased:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// erase erase all candidates by batches, in dry run candidates are only logged
+func (w *Worker) erase() {
+	log := hclog.Default()
+
+	for {
+		candidates, err := w.db.GetErasureCandidates(w.DeletedBefore(), w.cfg.ErasureBatchSize)
+		if err != nil {
+			log.Error("[retention.erase] w.db.GetErasureCandidates", "error", err)
+			return
+		}
+
+		erased := 0
+		for _, candidate := range candidates {
+			if w.cfg.ErasureDryRun {
+				log.Info("[retention.erase] dry run", "userID", candidate.UserID, "mode", w.cfg.ErasureMode,
+					"tokens", candidate.Tokens, "passwordHistory", candidate.PasswordHistory,
+					"roleAssignments", candidate.RoleAssignments, "auditEntries", candidate.AuditEntries)
+				continue
+			}
+
+			err = w.db.EraseUser(candidate.UserID, w.cfg.ErasureMode, w.Pseudonym(candidate.UserID))
+			if err != nil {
+				log.Error("[retention.erase] w.db.EraseUser", "userID", candidate.UserID, "error", err)
+				continue
+			}
+			erased++
+		}
+
+		if erased > 0 {
+			log.Info("[retention.erase] users are erased", "count", erased, "mode", w.cfg.ErasureMode)
+		}
+
+		// candidates are erased in place, so full batch without failures means there may be more
+		if w.cfg.ErasureDryRun || len(candidates) < w.cfg.ErasureBatchSize || erased < len(candidates) {
+			return
+		}
+	}
+}
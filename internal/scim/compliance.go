@@ -0,0 +1,478 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CheckResult result of compliance check
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+// complianceRunner client of SCIM endpoint which runs compliance checks in order, later checks use resources
+// created by earlier ones
+type complianceRunner struct {
+	client  *http.Client
+	baseURL string
+	token   string
+
+	userID      string
+	userName    string
+	userVersion string
+	results     []CheckResult
+}
+
+// complianceResponse response of SCIM endpoint with decoded json body
+type complianceResponse struct {
+	status int
+	header http.Header
+	body   map[string]interface{}
+}
+
+// RunCompliance run RFC 7643 and RFC 7644 compliance checks against SCIM endpoint at baseURL,
+// test user is created and deleted so default department must be configured on server
+func RunCompliance(client *http.Client, baseURL, token string) []CheckResult {
+	r := &complianceRunner{
+		client:   client,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    token,
+		userName: fmt.Sprintf("scim-compliance-%d@example.com", time.Now().UnixNano()),
+	}
+
+	r.check("unauthenticated request is rejected", r.checkUnauthorized)
+	r.check("service provider config is served", r.checkServiceProviderConfig)
+	r.check("schemas are served", r.checkSchemas)
+	r.check("resource types are served", r.checkResourceTypes)
+	r.check("user is created", r.checkCreateUser)
+	r.check("duplicate userName is rejected", r.checkDuplicateUser)
+	r.check("user is returned by id", r.checkGetUser)
+	r.check("not modified user is not returned", r.checkNotModified)
+	r.check("users are filtered by userName", r.checkFilterUsers)
+	r.check("invalid filter is rejected", r.checkInvalidFilter)
+	r.check("user is patched", r.checkPatchUser)
+	r.check("stale version is rejected", r.checkStaleVersion)
+	r.check("user is deactivated", r.checkDeactivateUser)
+	r.check("user is replaced", r.checkReplaceUser)
+	r.check("groups are listed", r.checkListGroups)
+	r.check("user is deleted", r.checkDeleteUser)
+	r.check("unknown user is not found", r.checkUnknownUser)
+
+	return r.results
+}
+
+// check run check, checks which depend on created user fail if user was not created
+func (r *complianceRunner) check(name string, fn func() error) {
+	r.results = append(r.results, CheckResult{Name: name, Err: fn()})
+}
+
+// do send request with json body and decode json response
+func (r *complianceRunner) do(method, path string, body interface{}, header map[string]string) (*complianceResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal error: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, r.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest error: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("r.client.Do error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	result := &complianceResponse{status: resp.StatusCode, header: resp.Header}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll error: %w", err)
+	}
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &result.body)
+		if err != nil {
+			return nil, fmt.Errorf("response is not json object: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// expect send request and check status of response
+func (r *complianceRunner) expect(status int, method, path string, body interface{}, header map[string]string) (*complianceResponse, error) {
+	resp, err := r.do(method, path, body, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status != status {
+		return nil, fmt.Errorf("%s %s: expected status %d, got %d: %v", method, path, status, resp.status, resp.body["detail"])
+	}
+
+	return resp, nil
+}
+
+// requireUser fail if test user was not created
+func (r *complianceRunner) requireUser() error {
+	if r.userID == "" {
+		return fmt.Errorf("test user was not created")
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkUnauthorized() error {
+	token := r.token
+	r.token = ""
+	defer func() { r.token = token }()
+
+	_, err := r.expect(http.StatusUnauthorized, http.MethodGet, "/Users", nil, nil)
+
+	return err
+}
+
+func (r *complianceRunner) checkServiceProviderConfig() error {
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/ServiceProviderConfig", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if !hasSchema(resp.body, SchemaServiceProviderConfig) {
+		return fmt.Errorf("schema %s is missing", SchemaServiceProviderConfig)
+	}
+	for _, feature := range []string{"patch", "filter", "etag"} {
+		value, _ := resp.body[feature].(map[string]interface{})
+		if supported, _ := value["supported"].(bool); !supported {
+			return fmt.Errorf("feature %s is not supported", feature)
+		}
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkSchemas() error {
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/Schemas", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	ids := resourceIDs(resp.body)
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if !ids[schema] {
+			return fmt.Errorf("schema %s is missing", schema)
+		}
+	}
+
+	_, err = r.expect(http.StatusOK, http.MethodGet, "/Schemas/"+SchemaUser, nil, nil)
+
+	return err
+}
+
+func (r *complianceRunner) checkResourceTypes() error {
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/ResourceTypes", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	ids := resourceIDs(resp.body)
+	if !ids["User"] || !ids["Group"] {
+		return fmt.Errorf("User and Group resource types are expected")
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkCreateUser() error {
+	resp, err := r.expect(http.StatusCreated, http.MethodPost, "/Users", r.testUser("Compliance", true), nil)
+	if err != nil {
+		return err
+	}
+
+	r.userID, _ = resp.body["id"].(string)
+	r.userVersion = resp.header.Get("ETag")
+	if r.userID == "" {
+		return fmt.Errorf("id of created user is missing")
+	}
+	if r.userVersion == "" {
+		return fmt.Errorf("ETag of created user is missing")
+	}
+	if resp.header.Get("Location") == "" {
+		return fmt.Errorf("Location of created user is missing")
+	}
+	if userName, _ := resp.body["userName"].(string); userName != r.userName {
+		return fmt.Errorf("expected userName %s, got %s", r.userName, userName)
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkDuplicateUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	resp, err := r.expect(http.StatusConflict, http.MethodPost, "/Users", r.testUser("Compliance", true), nil)
+	if err != nil {
+		return err
+	}
+	if scimType, _ := resp.body["scimType"].(string); scimType != "uniqueness" {
+		return fmt.Errorf("expected scimType uniqueness, got %s", scimType)
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkGetUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/Users/"+r.userID, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !hasSchema(resp.body, SchemaUser) {
+		return fmt.Errorf("schema %s is missing", SchemaUser)
+	}
+	if resp.header.Get("ETag") != r.userVersion {
+		return fmt.Errorf("ETag %s doesn't match ETag of created user %s", resp.header.Get("ETag"), r.userVersion)
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkNotModified() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	_, err := r.expect(http.StatusNotModified, http.MethodGet, "/Users/"+r.userID, nil, map[string]string{"If-None-Match": r.userVersion})
+
+	return err
+}
+
+func (r *complianceRunner) checkFilterUsers() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	filter := url.QueryEscape(fmt.Sprintf(`userName eq "%s"`, strings.ToUpper(r.userName)))
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/Users?filter="+filter, nil, nil)
+	if err != nil {
+		return err
+	}
+	if total, _ := resp.body["totalResults"].(float64); total != 1 {
+		return fmt.Errorf("expected 1 user matched case insensitive, got %v", total)
+	}
+
+	filter = url.QueryEscape(fmt.Sprintf(`userName eq "%s" and active eq false`, r.userName))
+	resp, err = r.expect(http.StatusOK, http.MethodGet, "/Users?filter="+filter, nil, nil)
+	if err != nil {
+		return err
+	}
+	if total, _ := resp.body["totalResults"].(float64); total != 0 {
+		return fmt.Errorf("expected no inactive users, got %v", total)
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkInvalidFilter() error {
+	resp, err := r.expect(http.StatusBadRequest, http.MethodGet, "/Users?filter="+url.QueryEscape(`userName eq`), nil, nil)
+	if err != nil {
+		return err
+	}
+	if scimType, _ := resp.body["scimType"].(string); scimType != "invalidFilter" {
+		return fmt.Errorf("expected scimType invalidFilter, got %s", scimType)
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkPatchUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	resp, err := r.expect(http.StatusOK, http.MethodPatch, "/Users/"+r.userID, map[string]interface{}{
+		"schemas": []string{SchemaPatchOp},
+		"Operations": []map[string]interface{}{
+			{"op": "replace", "path": "name.givenName", "value": "Patched"},
+		},
+	}, map[string]string{"If-Match": r.userVersion})
+	if err != nil {
+		return err
+	}
+
+	name, _ := resp.body["name"].(map[string]interface{})
+	if givenName, _ := name["givenName"].(string); givenName != "Patched" {
+		return fmt.Errorf("expected givenName Patched, got %s", givenName)
+	}
+
+	version := resp.header.Get("ETag")
+	if version == "" || version == r.userVersion {
+		return fmt.Errorf("ETag is not changed by patch")
+	}
+	r.userVersion = version
+
+	return nil
+}
+
+func (r *complianceRunner) checkStaleVersion() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	_, err := r.expect(http.StatusPreconditionFailed, http.MethodPatch, "/Users/"+r.userID, map[string]interface{}{
+		"schemas": []string{SchemaPatchOp},
+		"Operations": []map[string]interface{}{
+			{"op": "replace", "path": "name.familyName", "value": "Stale"},
+		},
+	}, map[string]string{"If-Match": `W/"0"`})
+
+	return err
+}
+
+func (r *complianceRunner) checkDeactivateUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	resp, err := r.expect(http.StatusOK, http.MethodPatch, "/Users/"+r.userID, map[string]interface{}{
+		"schemas": []string{SchemaPatchOp},
+		"Operations": []map[string]interface{}{
+			{"op": "replace", "value": map[string]interface{}{"active": "False"}},
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if active, _ := resp.body["active"].(bool); active {
+		return fmt.Errorf("user is still active")
+	}
+	r.userVersion = resp.header.Get("ETag")
+
+	return nil
+}
+
+func (r *complianceRunner) checkReplaceUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	resp, err := r.expect(http.StatusOK, http.MethodPut, "/Users/"+r.userID, r.testUser("Replaced", true), map[string]string{"If-Match": r.userVersion})
+	if err != nil {
+		return err
+	}
+
+	name, _ := resp.body["name"].(map[string]interface{})
+	if givenName, _ := name["givenName"].(string); givenName != "Replaced" {
+		return fmt.Errorf("expected givenName Replaced, got %s", givenName)
+	}
+	if active, _ := resp.body["active"].(bool); !active {
+		return fmt.Errorf("user is not active after replace")
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkListGroups() error {
+	resp, err := r.expect(http.StatusOK, http.MethodGet, "/Groups?count=1", nil, nil)
+	if err != nil {
+		return err
+	}
+	if !hasSchema(resp.body, SchemaListResponse) {
+		return fmt.Errorf("schema %s is missing", SchemaListResponse)
+	}
+	if resources, _ := resp.body["Resources"].([]interface{}); len(resources) > 1 {
+		return fmt.Errorf("count is not applied, got %d groups", len(resources))
+	}
+
+	return nil
+}
+
+func (r *complianceRunner) checkDeleteUser() error {
+	if err := r.requireUser(); err != nil {
+		return err
+	}
+
+	_, err := r.expect(http.StatusNoContent, http.MethodDelete, "/Users/"+r.userID, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.expect(http.StatusNotFound, http.MethodGet, "/Users/"+r.userID, nil, nil)
+
+	return err
+}
+
+func (r *complianceRunner) checkUnknownUser() error {
+	resp, err := r.expect(http.StatusNotFound, http.MethodGet, "/Users/00000000-0000-0000-0000-000000000000", nil, nil)
+	if err != nil {
+		return err
+	}
+	if !hasSchema(resp.body, SchemaError) {
+		return fmt.Errorf("schema %s is missing", SchemaError)
+	}
+
+	return nil
+}
+
+// testUser user resource of test user
+func (r *complianceRunner) testUser(givenName string, active bool) map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":  []string{SchemaUser},
+		"userName": r.userName,
+		"name": map[string]interface{}{
+			"givenName":  givenName,
+			"familyName": "Check",
+		},
+		"emails": []map[string]interface{}{{"value": r.userName, "primary": true}},
+		"active": active,
+	}
+}
+
+// hasSchema check response has schema
+func hasSchema(body map[string]interface{}, schema string) bool {
+	schemas, _ := body["schemas"].([]interface{})
+	for _, value := range schemas {
+		if value == schema {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceIDs ids of resources of list response
+func resourceIDs(body map[string]interface{}) map[string]bool {
+	ids := make(map[string]bool)
+	resources, _ := body["Resources"].([]interface{})
+	for _, resource := range resources {
+		object, _ := resource.(map[string]interface{})
+		if id, ok := object["id"].(string); ok {
+			ids[id] = true
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,241 @@
+package scim
+
+import (
+	"net/http"
+)
+
+// attribute definition of schema attribute
+type attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []attribute `json:"subAttributes,omitempty"`
+}
+
+// schemaResource definition of resource schema
+type schemaResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []attribute `json:"attributes"`
+	Meta        *meta       `json:"meta"`
+}
+
+// stringAttr string attribute with default characteristics
+func stringAttr(name string, required bool, mutability string, sub ...attribute) attribute {
+	attrType := "string"
+	if len(sub) > 0 {
+		attrType = "complex"
+	}
+
+	return attribute{
+		Name:          name,
+		Type:          attrType,
+		Required:      required,
+		Mutability:    mutability,
+		Returned:      "default",
+		Uniqueness:    "none",
+		SubAttributes: sub,
+	}
+}
+
+// multiAttr multi-valued complex attribute
+func multiAttr(name string, mutability string, sub ...attribute) attribute {
+	attr := stringAttr(name, false, mutability, sub...)
+	attr.MultiValued = true
+
+	return attr
+}
+
+// schemas definitions of supported schemas
+func schemas(basePath string) []interface{} {
+	userName := stringAttr("userName", true, "readWrite")
+	userName.Uniqueness = "server"
+
+	active := stringAttr("active", false, "readWrite")
+	active.Type = "boolean"
+
+	members := multiAttr("members", "readWrite",
+		stringAttr("value", false, "immutable"),
+		stringAttr("display", false, "readOnly"),
+		stringAttr("type", false, "immutable"),
+		stringAttr("$ref", false, "immutable"),
+	)
+
+	definitions := []*schemaResource{
+		{
+			ID:          SchemaUser,
+			Name:        "User",
+			Description: "User account",
+			Attributes: []attribute{
+				userName,
+				stringAttr("name", true, "readWrite",
+					stringAttr("formatted", false, "readOnly"),
+					stringAttr("givenName", true, "readWrite"),
+					stringAttr("familyName", true, "readWrite"),
+				),
+				stringAttr("displayName", false, "readOnly"),
+				active,
+				multiAttr("emails", "readWrite",
+					stringAttr("value", false, "readWrite"),
+					stringAttr("type", false, "readOnly"),
+				),
+				multiAttr("roles", "readWrite",
+					stringAttr("value", false, "readWrite"),
+				),
+				multiAttr("groups", "readOnly",
+					stringAttr("value", false, "readOnly"),
+					stringAttr("display", false, "readOnly"),
+					stringAttr("$ref", false, "readOnly"),
+				),
+			},
+		},
+		{
+			ID:          SchemaEnterpriseUser,
+			Name:        "EnterpriseUser",
+			Description: "Enterprise user with department",
+			Attributes: []attribute{
+				stringAttr("department", false, "readWrite"),
+			},
+		},
+		{
+			ID:          SchemaGroup,
+			Name:        "Group",
+			Description: "Department or role with its users",
+			Attributes: []attribute{
+				stringAttr("displayName", true, "readWrite"),
+				members,
+			},
+		},
+	}
+
+	resources := make([]interface{}, 0, len(definitions))
+	for _, definition := range definitions {
+		definition.Schemas = []string{SchemaSchema}
+		definition.Meta = &meta{ResourceType: "Schema", Location: basePath + "/Schemas/" + definition.ID}
+		resources = append(resources, definition)
+	}
+
+	return resources
+}
+
+// resourceType definition of resource endpoint
+type resourceType struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []schemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             *meta             `json:"meta"`
+}
+
+// schemaExtension extension of resource schema
+type schemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// resourceTypes definitions of users and groups endpoints
+func resourceTypes(basePath string) []interface{} {
+	return []interface{}{
+		&resourceType{
+			Schemas:          []string{SchemaResourceType},
+			ID:               "User",
+			Name:             "User",
+			Endpoint:         "/Users",
+			Description:      "User account",
+			Schema:           SchemaUser,
+			SchemaExtensions: []schemaExtension{{Schema: SchemaEnterpriseUser}},
+			Meta:             &meta{ResourceType: "ResourceType", Location: basePath + "/ResourceTypes/User"},
+		},
+		&resourceType{
+			Schemas:     []string{SchemaResourceType},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Department or role with its users",
+			Schema:      SchemaGroup,
+			Meta:        &meta{ResourceType: "ResourceType", Location: basePath + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+// supported feature of service provider
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+// serviceProviderConfig features supported by handler
+func serviceProviderConfig(basePath string) interface{} {
+	return map[string]interface{}{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          supported{Supported: true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxResults},
+		"changePassword": supported{Supported: false},
+		"sort":           supported{Supported: false},
+		"etag":           supported{Supported: true},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Authentication with bearer token configured in SCIM_TOKEN",
+			"primary":     true,
+		}},
+		"meta": &meta{ResourceType: "ServiceProviderConfig", Location: basePath + "/ServiceProviderConfig"},
+	}
+}
+
+// serveStatic write static resource
+func (h *Handler) serveStatic(w http.ResponseWriter, r *http.Request, found bool, resource interface{}) error {
+	if r.Method != http.MethodGet {
+		return errMethod
+	}
+	if !found {
+		return errNotFound
+	}
+
+	writeJSON(w, http.StatusOK, "", resource)
+
+	return nil
+}
+
+// serveDiscovery write list of discovery resources or resource by id
+func (h *Handler) serveDiscovery(w http.ResponseWriter, r *http.Request, id string, resources []interface{}) error {
+	if r.Method != http.MethodGet {
+		return errMethod
+	}
+
+	if id == "" {
+		writeJSON(w, http.StatusOK, "", &listResponse{
+			Schemas:      []string{SchemaListResponse},
+			TotalResults: int64(len(resources)),
+			StartIndex:   1,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		})
+		return nil
+	}
+
+	for _, resource := range resources {
+		switch v := resource.(type) {
+		case *schemaResource:
+			if v.ID == id {
+				return h.serveStatic(w, r, true, v)
+			}
+		case *resourceType:
+			if v.ID == id {
+				return h.serveStatic(w, r, true, v)
+			}
+		}
+	}
+
+	return errNotFound
+}
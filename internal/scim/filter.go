@@ -0,0 +1,450 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// filterNode node of parsed filter expression
+type filterNode interface{}
+
+// logicalNode "and" or "or" of two filters
+type logicalNode struct {
+	op    string
+	left  filterNode
+	right filterNode
+}
+
+// notNode negation of filter
+type notNode struct {
+	node filterNode
+}
+
+// compareNode comparison of attribute with value, value is nil for "pr" operator
+type compareNode struct {
+	attr  string
+	op    string
+	value interface{}
+}
+
+// compareOps operators of attribute comparison
+var compareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "lt": true, "ge": true, "le": true, "pr": true,
+}
+
+// filterToken token of filter expression
+type filterToken struct {
+	text     string
+	isString bool
+}
+
+// filterParser recursive descent parser of RFC 7644 filters
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	// prefix attribute path of value path filter, e.g. "emails." inside emails[...]
+	prefix string
+}
+
+// parseFilter parse filter expression, attribute names are lower cased
+func parseFilter(filter string) (filterNode, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter is empty")
+	}
+
+	p := &filterParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	return node, nil
+}
+
+// tokenizeFilter split filter to words, brackets and quoted strings
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+
+			value, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", string(runes[i:j+1]))
+			}
+			tokens = append(tokens, filterToken{text: value, isString: true})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()[]\"", runes[j]); j++ {
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i:j])})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// peek current token in lower case, empty at end of filter
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].isString {
+		return ""
+	}
+
+	return strings.ToLower(p.tokens[p.pos].text)
+}
+
+// expect skip token or fail if current token is different
+func (p *filterParser) expect(text string) error {
+	if p.peek() != text {
+		return fmt.Errorf("expected %q", text)
+	}
+	p.pos++
+
+	return nil
+}
+
+// parseOr parse filters joined by "or"
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parse filters joined by "and"
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parse negation, group in parentheses, value path or comparison
+func (p *filterParser) parseUnary() (filterNode, error) {
+	switch p.peek() {
+	case "not":
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	case "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "":
+		return nil, fmt.Errorf("attribute is expected")
+	}
+
+	attr := p.prefix + normalizeAttr(p.peek())
+	p.pos++
+
+	// value path, e.g. emails[type eq "work"], is filter of sub-attributes
+	if p.peek() == "[" {
+		if p.prefix != "" {
+			return nil, fmt.Errorf("nested value path is not supported")
+		}
+		p.pos++
+		p.prefix = attr + "."
+		node, err := p.parseOr()
+		p.prefix = ""
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	op := p.peek()
+	if !compareOps[op] {
+		return nil, fmt.Errorf("invalid operator after %s", attr)
+	}
+	p.pos++
+
+	if op == "pr" {
+		return &compareNode{attr: attr, op: op}, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("value is expected after %s %s", attr, op)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	value, err := tokenValue(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compareNode{attr: attr, op: op, value: value}, nil
+}
+
+// tokenValue value of comparison: string, bool, number or nil
+func tokenValue(token filterToken) (interface{}, error) {
+	if token.isString {
+		return token.text, nil
+	}
+
+	switch strings.ToLower(token.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(token.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", token.text)
+	}
+
+	return number, nil
+}
+
+// attrKind kind of values of attribute
+type attrKind int
+
+const (
+	kindString attrKind = iota
+	kindTime
+	// kindActive active flag stored as nullable deactivation time
+	kindActive
+)
+
+// sqlAttr column of attribute in sql
+type sqlAttr struct {
+	column string
+	kind   attrKind
+}
+
+// filterSQL translate filter to sql condition with placeholders by attribute columns
+func filterSQL(node filterNode, attrs map[string]sqlAttr) (string, []interface{}, error) {
+	switch n := node.(type) {
+	case *logicalNode:
+		left, leftArgs, err := filterSQL(n.left, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := filterSQL(n.right, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(n.op), right), append(leftArgs, rightArgs...), nil
+	case *notNode:
+		condition, args, err := filterSQL(n.node, attrs)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("NOT %s", condition), args, nil
+	case *compareNode:
+		attr, ok := attrs[n.attr]
+		if !ok {
+			return "", nil, fmt.Errorf("attribute %s can't be filtered", n.attr)
+		}
+		return compareSQL(attr, n)
+	default:
+		return "", nil, fmt.Errorf("unknown filter node")
+	}
+}
+
+// compareSQL sql condition of comparison, strings are compared case insensitive
+func compareSQL(attr sqlAttr, n *compareNode) (string, []interface{}, error) {
+	switch attr.kind {
+	case kindActive:
+		active, ok := n.value.(bool)
+		if n.op == "pr" {
+			return "TRUE", nil, nil
+		}
+		if !ok || (n.op != "eq" && n.op != "ne") {
+			return "", nil, fmt.Errorf("active can be compared only with eq or ne and boolean")
+		}
+		if active == (n.op == "eq") {
+			return fmt.Sprintf("(%s IS NULL)", attr.column), nil, nil
+		}
+		return fmt.Sprintf("(%s IS NOT NULL)", attr.column), nil, nil
+	case kindTime:
+		if n.op == "pr" {
+			return fmt.Sprintf("(%s IS NOT NULL)", attr.column), nil, nil
+		}
+		text, ok := n.value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("time is expected for %s", n.attr)
+		}
+		moment, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid time %s", text)
+		}
+		op, ok := sqlOps[n.op]
+		if !ok {
+			return "", nil, fmt.Errorf("operator %s is not supported for %s", n.op, n.attr)
+		}
+		return fmt.Sprintf("(%s %s ?)", attr.column, op), []interface{}{moment}, nil
+	}
+
+	if n.op == "pr" {
+		return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", attr.column, attr.column), nil, nil
+	}
+	if n.value == nil && (n.op == "eq" || n.op == "ne") {
+		// attribute equal to null is not present
+		if n.op == "eq" {
+			return fmt.Sprintf("(%s IS NULL OR %s = '')", attr.column, attr.column), nil, nil
+		}
+		return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", attr.column, attr.column), nil, nil
+	}
+
+	text, ok := n.value.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("string is expected for %s", n.attr)
+	}
+
+	switch n.op {
+	case "co":
+		return fmt.Sprintf("(%s ILIKE ?)", attr.column), []interface{}{"%" + escapeLike(text) + "%"}, nil
+	case "sw":
+		return fmt.Sprintf("(%s ILIKE ?)", attr.column), []interface{}{escapeLike(text) + "%"}, nil
+	case "ew":
+		return fmt.Sprintf("(%s ILIKE ?)", attr.column), []interface{}{"%" + escapeLike(text)}, nil
+	}
+
+	return fmt.Sprintf("(LOWER(%s) %s LOWER(?))", attr.column, sqlOps[n.op]), []interface{}{text}, nil
+}
+
+// sqlOps sql operators of comparison operators
+var sqlOps = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "lt": "<", "ge": ">=", "le": "<=",
+}
+
+// escapeLike escape wildcards of LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// matchFilter evaluate filter on string attributes, attributes are compared case insensitive
+func matchFilter(node filterNode, attrs map[string]string) (bool, error) {
+	switch n := node.(type) {
+	case *logicalNode:
+		left, err := matchFilter(n.left, attrs)
+		if err != nil {
+			return false, err
+		}
+		right, err := matchFilter(n.right, attrs)
+		if err != nil {
+			return false, err
+		}
+		if n.op == "and" {
+			return left && right, nil
+		}
+		return left || right, nil
+	case *notNode:
+		matched, err := matchFilter(n.node, attrs)
+		return !matched, err
+	case *compareNode:
+		actual, ok := attrs[n.attr]
+		if !ok {
+			return false, fmt.Errorf("attribute %s can't be filtered", n.attr)
+		}
+		if n.op == "pr" {
+			return actual != "", nil
+		}
+
+		var expected string
+		switch value := n.value.(type) {
+		case string:
+			expected = value
+		case nil:
+			expected = ""
+		default:
+			return false, fmt.Errorf("string is expected for %s", n.attr)
+		}
+
+		actual, expected = strings.ToLower(actual), strings.ToLower(expected)
+		switch n.op {
+		case "eq":
+			return actual == expected, nil
+		case "ne":
+			return actual != expected, nil
+		case "co":
+			return strings.Contains(actual, expected), nil
+		case "sw":
+			return strings.HasPrefix(actual, expected), nil
+		case "ew":
+			return strings.HasSuffix(actual, expected), nil
+		case "gt":
+			return actual > expected, nil
+		case "lt":
+			return actual < expected, nil
+		case "ge":
+			return actual >= expected, nil
+		default:
+			return actual <= expected, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown filter node")
+	}
+}
@@ -0,0 +1,154 @@
+package scim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterSQL(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		filter    string
+		condition string
+		args      []interface{}
+	}{
+		{
+			name:      "equal is case insensitive",
+			filter:    `userName eq "Bob@Example.com"`,
+			condition: "(LOWER(email) = LOWER(?))",
+			args:      []interface{}{"Bob@Example.com"},
+		},
+		{
+			name:      "schema prefix and operator case are ignored",
+			filter:    `urn:ietf:params:scim:schemas:core:2.0:User:userName EQ "bob@example.com"`,
+			condition: "(LOWER(email) = LOWER(?))",
+			args:      []interface{}{"bob@example.com"},
+		},
+		{
+			name:      "like wildcards are escaped",
+			filter:    `name.familyName co "50%_off"`,
+			condition: "(last_name ILIKE ?)",
+			args:      []interface{}{`%50\%\_off%`},
+		},
+		{
+			name:      "starts with",
+			filter:    `emails.value sw "bob"`,
+			condition: "(email ILIKE ?)",
+			args:      []interface{}{"bob%"},
+		},
+		{
+			name:      "value path is filter of sub-attribute",
+			filter:    `emails[value ew "@example.com"]`,
+			condition: "(email ILIKE ?)",
+			args:      []interface{}{"%@example.com"},
+		},
+		{
+			name:      "and binds tighter than or",
+			filter:    `userName eq "a" or userName eq "b" and active eq true`,
+			condition: "((LOWER(email) = LOWER(?)) OR ((LOWER(email) = LOWER(?)) AND (deactivated_at IS NULL)))",
+			args:      []interface{}{"a", "b"},
+		},
+		{
+			name:      "parentheses and negation",
+			filter:    `not (active eq false) and (name.givenName pr)`,
+			condition: "(NOT (deactivated_at IS NOT NULL) AND (first_name IS NOT NULL AND first_name <> ''))",
+		},
+		{
+			name:      "null is not present",
+			filter:    `name.givenName eq null`,
+			condition: "(first_name IS NULL OR first_name = '')",
+		},
+		{
+			name:      "time comparison",
+			filter:    `meta.created gt "2024-01-02T03:04:05Z"`,
+			condition: "(created_at > ?)",
+			args:      []interface{}{created},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q) error: %v", tt.filter, err)
+			}
+
+			condition, args, err := filterSQL(node, userAttrs)
+			if err != nil {
+				t.Fatalf("filterSQL(%q) error: %v", tt.filter, err)
+			}
+			if condition != tt.condition {
+				t.Errorf("condition = %q, want %q", condition, tt.condition)
+			}
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{name: "unknown operator", filter: `userName is "bob"`},
+		{name: "missing value", filter: `userName eq`},
+		{name: "unclosed parenthesis", filter: `(userName eq "bob"`},
+		{name: "unterminated string", filter: `userName eq "bob`},
+		{name: "nested value path", filter: `emails[value[type eq "work"]]`},
+		{name: "unknown attribute", filter: `password eq "secret"`},
+		{name: "active is not boolean", filter: `active eq "yes"`},
+		{name: "invalid time", filter: `meta.lastModified gt "yesterday"`},
+		{name: "number for string attribute", filter: `userName eq 42`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseFilter(tt.filter)
+			if err == nil {
+				_, _, err = filterSQL(node, userAttrs)
+			}
+			if err == nil {
+				t.Fatalf("filter %q is accepted", tt.filter)
+			}
+		})
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	attrs := map[string]string{"displayname": "Engineering", "id": "7"}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{filter: `displayName eq "engineering"`, want: true},
+		{filter: `displayName sw "Eng" and id eq "7"`, want: true},
+		{filter: `displayName ne "Engineering"`, want: false},
+		{filter: `not (id eq "7") or displayName co "sales"`, want: false},
+		{filter: `displayName pr`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			node, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q) error: %v", tt.filter, err)
+			}
+
+			matched, err := matchFilter(node, attrs)
+			if err != nil {
+				t.Fatalf("matchFilter(%q) error: %v", tt.filter, err)
+			}
+			if matched != tt.want {
+				t.Errorf("matchFilter(%q) = %v, want %v", tt.filter, matched, tt.want)
+			}
+		})
+	}
+}
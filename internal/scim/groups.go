@@ -0,0 +1,813 @@
+package scim
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// group department or role exposed as SCIM group
+type group struct {
+	ID   uint32
+	Name string
+}
+
+// groupStore departments or roles exposed as SCIM groups, each user is member of exactly one group
+type groupStore interface {
+	list() ([]group, error)
+	get(id uint32) (*group, error)
+	getByName(name string) (*group, error)
+	create(name string) (*group, error)
+	rename(id uint32, name string) (*group, error)
+	remove(id, reassignID uint32) error
+	// column user column with group id
+	column() string
+	// defaultName name of group of users removed from their group, empty if not configured
+	defaultName() string
+	userGroup(user *models.User, names *nameIndex) (uint32, string)
+}
+
+// groupResource SCIM group
+type groupResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []multiValue `json:"members,omitempty"`
+	Meta        *meta        `json:"meta,omitempty"`
+}
+
+// groupVersion weak etag of group by name and members because departments and roles don't keep change time
+func groupVersion(g *group, members []models.User) string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.ID)
+	}
+	sort.Strings(ids)
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(g.Name))
+	for _, id := range ids {
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write([]byte(id))
+	}
+
+	return fmt.Sprintf(`W/"%x"`, hash.Sum64())
+}
+
+// listGroups return page of groups matched by filter, groups are filtered in memory because there are few of them
+func (h *Handler) listGroups(w http.ResponseWriter, r *http.Request) error {
+	_, span := h.trace.Start(r.Context(), "scim.ListGroups")
+	defer span.End()
+
+	startIndex, count, err := pageOf(r)
+	if err != nil {
+		return err
+	}
+
+	node, err := parseFilterParam(r)
+	if err != nil {
+		return err
+	}
+
+	groups, err := h.groups.list()
+	if err != nil {
+		return fmt.Errorf("h.groups.list error: %w", err)
+	}
+
+	matched := make([]group, 0, len(groups))
+	for _, g := range groups {
+		if node != nil {
+			ok, err := matchFilter(node, map[string]string{
+				"id":          strconv.FormatUint(uint64(g.ID), 10),
+				"displayname": g.Name,
+			})
+			if err != nil {
+				return newError(http.StatusBadRequest, "invalidFilter", err.Error())
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, g)
+	}
+
+	page := matched[min(startIndex-1, len(matched)):min(startIndex-1+count, len(matched))]
+	// members are not returned in listing when client asks only for names as Azure AD and Okta do
+	withMembers := !strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members")
+
+	resources := make([]interface{}, 0, len(page))
+	for i := range page {
+		var members []models.User
+		if withMembers {
+			members, err = h.groupMembers(page[i].ID)
+			if err != nil {
+				return err
+			}
+		}
+		resource := h.groupToResource(&page[i], members)
+		if !withMembers {
+			resource.Members, resource.Meta.Version = nil, ""
+		}
+		resources = append(resources, resource)
+	}
+
+	writeJSON(w, http.StatusOK, "", &listResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(matched)),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+
+	return nil
+}
+
+// getGroup return group by id with members
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request, id uint32) error {
+	_, span := h.trace.Start(r.Context(), "scim.GetGroup")
+	defer span.End()
+
+	g, members, err := h.findGroup(id)
+	if err != nil {
+		return err
+	}
+
+	version := groupVersion(g, members)
+	if notModified(r, version) {
+		w.Header().Set("ETag", version)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	writeJSON(w, http.StatusOK, version, h.groupToResource(g, members))
+
+	return nil
+}
+
+// createGroup create group and move members to it
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request) error {
+	_, span := h.trace.Start(r.Context(), "scim.CreateGroup")
+	defer span.End()
+
+	var resource groupResource
+	err := decodeBody(r, &resource)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(resource.DisplayName)
+	if err = validators.ValidateName(name); err != nil {
+		return invalidValue("displayName is not valid")
+	}
+
+	_, err = h.groups.getByName(name)
+	if err == nil {
+		return errUniqueness
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("h.groups.getByName error: %w", err)
+	}
+
+	g, err := h.groups.create(name)
+	if errors.Is(err, models.AlreadyExistError) {
+		return errUniqueness
+	}
+	if err != nil {
+		return fmt.Errorf("h.groups.create error: %w", err)
+	}
+	h.audit("CreateGroup", "")
+
+	err = h.moveMembers(g.ID, memberIDs(resource.Members))
+	if err != nil {
+		return err
+	}
+
+	g, members, err := h.findGroup(g.ID)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", h.location("Groups", strconv.FormatUint(uint64(g.ID), 10)))
+	writeJSON(w, http.StatusCreated, groupVersion(g, members), h.groupToResource(g, members))
+
+	return nil
+}
+
+// replaceGroup rename group and replace its members, removed members are moved to default group
+func (h *Handler) replaceGroup(w http.ResponseWriter, r *http.Request, id uint32) error {
+	_, span := h.trace.Start(r.Context(), "scim.ReplaceGroup")
+	defer span.End()
+
+	var resource groupResource
+	err := decodeBody(r, &resource)
+	if err != nil {
+		return err
+	}
+
+	g, members, err := h.findGroup(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(r, groupVersion(g, members))
+	if err != nil {
+		return err
+	}
+
+	err = h.renameGroup(g, resource.DisplayName)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for _, memberID := range memberIDs(resource.Members) {
+		keep[memberID] = true
+	}
+
+	var removed []string
+	for _, member := range members {
+		if !keep[member.ID] {
+			removed = append(removed, member.ID)
+		}
+	}
+
+	err = h.removeMembers(id, removed)
+	if err != nil {
+		return err
+	}
+
+	err = h.moveMembers(id, memberIDs(resource.Members))
+	if err != nil {
+		return err
+	}
+	h.audit("ReplaceGroup", "")
+
+	return h.writeGroup(w, id)
+}
+
+// patchGroup apply patch operations to name and members of group
+func (h *Handler) patchGroup(w http.ResponseWriter, r *http.Request, id uint32) error {
+	_, span := h.trace.Start(r.Context(), "scim.PatchGroup")
+	defer span.End()
+
+	var request patchRequest
+	err := decodeBody(r, &request)
+	if err != nil {
+		return err
+	}
+
+	g, members, err := h.findGroup(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(r, groupVersion(g, members))
+	if err != nil {
+		return err
+	}
+
+	for _, operation := range request.Operations {
+		err = h.applyGroupOperation(g, members, operation)
+		if err != nil {
+			return err
+		}
+
+		// next operation works with result of previous one
+		g, members, err = h.findGroup(id)
+		if err != nil {
+			return err
+		}
+	}
+	h.audit("PatchGroup", "")
+
+	return h.writeGroup(w, id)
+}
+
+// applyGroupOperation apply patch operation to group
+func (h *Handler) applyGroupOperation(g *group, members []models.User, operation patchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return invalidValue("unsupported operation %s", operation.Op)
+	}
+
+	attr, valueFilter, _ := splitValuePath(operation.Path)
+	switch {
+	case attr == "" && op != "remove":
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return invalidValue("value of operation without path must be object")
+		}
+		for name, value := range values {
+			err := h.applyGroupOperation(g, members, patchOperation{Op: op, Path: name, Value: value})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case attr == "":
+		return errNoTarget
+	case attr == "displayname":
+		if op == "remove" {
+			return newError(http.StatusBadRequest, "mutability", "displayName is required and can't be removed")
+		}
+		name, ok := operation.Value.(string)
+		if !ok {
+			return invalidValue("displayName must be string")
+		}
+		return h.renameGroup(g, name)
+	case attr != "members":
+		return newError(http.StatusBadRequest, "invalidPath", fmt.Sprintf("path %s is not supported", operation.Path))
+	}
+
+	ids, err := patchMemberIDs(operation.Value)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "add":
+		return h.moveMembers(g.ID, ids)
+	case "replace":
+		keep := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			keep[id] = true
+		}
+
+		var removed []string
+		for _, member := range members {
+			if !keep[member.ID] {
+				removed = append(removed, member.ID)
+			}
+		}
+
+		err = h.removeMembers(g.ID, removed)
+		if err != nil {
+			return err
+		}
+		return h.moveMembers(g.ID, ids)
+	}
+
+	// members are removed by value filter of path, by value or all of them
+	var removed []string
+	switch {
+	case valueFilter != "":
+		node, err := parseFilter(valueFilter)
+		if err != nil {
+			return newError(http.StatusBadRequest, "invalidFilter", err.Error())
+		}
+		for _, member := range members {
+			ok, err := matchFilter(node, map[string]string{"value": member.ID})
+			if err != nil {
+				return newError(http.StatusBadRequest, "invalidFilter", err.Error())
+			}
+			if ok {
+				removed = append(removed, member.ID)
+			}
+		}
+	case len(ids) > 0:
+		removed = ids
+	default:
+		for _, member := range members {
+			removed = append(removed, member.ID)
+		}
+	}
+
+	return h.removeMembers(g.ID, removed)
+}
+
+// deleteGroup remove group, its members are moved to default group
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request, id uint32) error {
+	_, span := h.trace.Start(r.Context(), "scim.DeleteGroup")
+	defer span.End()
+
+	g, members, err := h.findGroup(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(r, groupVersion(g, members))
+	if err != nil {
+		return err
+	}
+
+	if _, isRole := h.groups.(*roleGroups); isRole && models.BuiltinRoles[g.Name] != nil {
+		return newError(http.StatusBadRequest, "mutability", "built-in role can't be removed")
+	}
+
+	reassignID := uint32(0)
+	if defaultName := h.groups.defaultName(); defaultName != "" && defaultName != g.Name {
+		defaultGroup, err := h.groups.getByName(defaultName)
+		if err != nil {
+			return fmt.Errorf("h.groups.getByName default error: %w", err)
+		}
+		reassignID = defaultGroup.ID
+	}
+
+	err = h.groups.remove(id, reassignID)
+	if isNotFound(err) {
+		return errNotFound
+	}
+	if errors.Is(err, models.DepartmentInUseError) || errors.Is(err, models.RoleInUseError) || errors.Is(err, models.DepartmentHasChildrenError) {
+		return newError(http.StatusBadRequest, "mutability", "group has members or subgroups and default group is not configured")
+	}
+	if err != nil {
+		return fmt.Errorf("h.groups.remove error: %w", err)
+	}
+	h.audit("DeleteGroup", "")
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// renameGroup rename group if name is changed
+func (h *Handler) renameGroup(g *group, name string) error {
+	name = strings.TrimSpace(name)
+	if name == g.Name {
+		return nil
+	}
+	if err := validators.ValidateName(name); err != nil {
+		return invalidValue("displayName is not valid")
+	}
+	if _, isRole := h.groups.(*roleGroups); isRole && models.BuiltinRoles[g.Name] != nil {
+		return newError(http.StatusBadRequest, "mutability", "built-in role can't be renamed")
+	}
+
+	_, err := h.groups.rename(g.ID, name)
+	if errors.Is(err, models.AlreadyExistError) {
+		return errUniqueness
+	}
+	if err != nil {
+		return fmt.Errorf("h.groups.rename error: %w", err)
+	}
+
+	return nil
+}
+
+// moveMembers move users to group, unknown users are rejected
+func (h *Handler) moveMembers(id uint32, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	// ids are normalized so primary key index is used
+	normalized := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		parsed, err := uuid.Parse(userID)
+		if err != nil {
+			return invalidValue("members contain unknown users")
+		}
+		normalized = append(normalized, parsed.String())
+	}
+	userIDs = uniqueStrings(normalized)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIDs)), ",")
+	args := make([]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+
+	users, _, err := h.db.SearchUsers("id IN ("+placeholders+")", args, 0, -1)
+	if err != nil {
+		return fmt.Errorf("h.db.SearchUsers error: %w", err)
+	}
+	if len(users) != len(userIDs) {
+		return invalidValue("members contain unknown users")
+	}
+
+	err = h.db.UpdateUsersByIDs(userIDs, map[string]interface{}{h.groups.column(): id})
+	if err != nil {
+		return fmt.Errorf("h.db.UpdateUsersByIDs error: %w", err)
+	}
+
+	return nil
+}
+
+// removeMembers move users out of group to default group
+func (h *Handler) removeMembers(id uint32, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	defaultName := h.groups.defaultName()
+	if defaultName == "" {
+		return errNoDefaultGroup
+	}
+
+	defaultGroup, err := h.groups.getByName(defaultName)
+	if err != nil {
+		return fmt.Errorf("h.groups.getByName default error: %w", err)
+	}
+	if defaultGroup.ID == id {
+		return newError(http.StatusBadRequest, "mutability", "members can't be removed from default group")
+	}
+
+	err = h.db.UpdateUsersByIDs(userIDs, map[string]interface{}{h.groups.column(): defaultGroup.ID})
+	if err != nil {
+		return fmt.Errorf("h.db.UpdateUsersByIDs error: %w", err)
+	}
+
+	return nil
+}
+
+// findGroup get group and its members by id
+func (h *Handler) findGroup(id uint32) (*group, []models.User, error) {
+	g, err := h.groups.get(id)
+	if isNotFound(err) {
+		return nil, nil, errNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("h.groups.get error: %w", err)
+	}
+
+	members, err := h.groupMembers(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return g, members, nil
+}
+
+// groupMembers users of group
+func (h *Handler) groupMembers(id uint32) ([]models.User, error) {
+	members, _, err := h.db.SearchUsers(h.groups.column()+" = ?", []interface{}{id}, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("h.db.SearchUsers members error: %w", err)
+	}
+
+	return members, nil
+}
+
+// writeGroup write current state of group
+func (h *Handler) writeGroup(w http.ResponseWriter, id uint32) error {
+	g, members, err := h.findGroup(id)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, groupVersion(g, members), h.groupToResource(g, members))
+
+	return nil
+}
+
+// groupToResource SCIM group of group, members are nil when they are not loaded
+func (h *Handler) groupToResource(g *group, members []models.User) *groupResource {
+	id := strconv.FormatUint(uint64(g.ID), 10)
+
+	resource := &groupResource{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		DisplayName: g.Name,
+		Members:     make([]multiValue, 0, len(members)),
+		Meta: &meta{
+			ResourceType: "Group",
+			Location:     h.location("Groups", id),
+			Version:      groupVersion(g, members),
+		},
+	}
+
+	for _, member := range members {
+		resource.Members = append(resource.Members, multiValue{
+			Value:   member.ID,
+			Display: strings.TrimSpace(member.FirstName + " " + member.LastName),
+			Type:    "User",
+			Ref:     h.location("Users", member.ID),
+		})
+	}
+
+	return resource
+}
+
+// memberIDs ids of members
+func memberIDs(members []multiValue) []string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		if member.Value != "" {
+			ids = append(ids, member.Value)
+		}
+	}
+
+	return uniqueStrings(ids)
+}
+
+// patchMemberIDs ids of members in value of patch operation given as array or single object
+func patchMemberIDs(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, invalidValue("members must be objects")
+		}
+
+		id, ok := object["value"].(string)
+		if !ok || id == "" {
+			return nil, invalidValue("value of member must be user id")
+		}
+		ids = append(ids, id)
+	}
+
+	return uniqueStrings(ids), nil
+}
+
+// uniqueStrings values without duplicates in original order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// min smaller of two ints
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// departmentGroups departments as SCIM groups
+type departmentGroups struct {
+	db           Repository
+	defaultGroup string
+}
+
+// list departments as groups sorted by id
+func (s *departmentGroups) list() ([]group, error) {
+	departments, err := s.db.GetUserDepartments()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]group, 0, len(*departments))
+	for _, department := range *departments {
+		groups = append(groups, group{ID: department.ID, Name: department.Name})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	return groups, nil
+}
+
+// get department by id
+func (s *departmentGroups) get(id uint32) (*group, error) {
+	department, err := s.db.GetUserDepartmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: department.ID, Name: department.Name}, nil
+}
+
+// getByName get department by name
+func (s *departmentGroups) getByName(name string) (*group, error) {
+	department, err := s.db.GetUserDepartmentByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: department.ID, Name: department.Name}, nil
+}
+
+// create department
+func (s *departmentGroups) create(name string) (*group, error) {
+	department, err := s.db.AddUserDepartment(name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: department.ID, Name: department.Name}, nil
+}
+
+// rename department
+func (s *departmentGroups) rename(id uint32, name string) (*group, error) {
+	department, err := s.db.RenameUserDepartment(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: department.ID, Name: department.Name}, nil
+}
+
+// remove department moving its users to reassignID
+func (s *departmentGroups) remove(id, reassignID uint32) error {
+	return s.db.RemoveUserDepartment(id, reassignID)
+}
+
+// column user column with department id
+func (s *departmentGroups) column() string {
+	return "department_id"
+}
+
+// defaultName name of default department
+func (s *departmentGroups) defaultName() string {
+	return s.defaultGroup
+}
+
+// userGroup department of user
+func (s *departmentGroups) userGroup(user *models.User, names *nameIndex) (uint32, string) {
+	return user.DepartmentID, names.departments[user.DepartmentID]
+}
+
+// roleGroups roles as SCIM groups
+type roleGroups struct {
+	db           Repository
+	defaultGroup string
+}
+
+// list roles as groups sorted by id
+func (s *roleGroups) list() ([]group, error) {
+	roles, err := s.db.GetUserRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]group, 0, len(*roles))
+	for _, role := range *roles {
+		groups = append(groups, group{ID: role.ID, Name: role.Name})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	return groups, nil
+}
+
+// get role by id
+func (s *roleGroups) get(id uint32) (*group, error) {
+	role, err := s.db.GetUserRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: role.ID, Name: role.Name}, nil
+}
+
+// getByName get role by name
+func (s *roleGroups) getByName(name string) (*group, error) {
+	role, err := s.db.GetUserRoleByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: role.ID, Name: role.Name}, nil
+}
+
+// create role
+func (s *roleGroups) create(name string) (*group, error) {
+	role, err := s.db.AddUserRole(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: role.ID, Name: role.Name}, nil
+}
+
+// rename role
+func (s *roleGroups) rename(id uint32, name string) (*group, error) {
+	role, err := s.db.RenameUserRole(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{ID: role.ID, Name: role.Name}, nil
+}
+
+// remove role moving its users to reassignID
+func (s *roleGroups) remove(id, reassignID uint32) error {
+	return s.db.RemoveUserRole(id, reassignID)
+}
+
+// column user column with role id
+func (s *roleGroups) column() string {
+	return "role_id"
+}
+
+// defaultName name of default role
+func (s *roleGroups) defaultName() string {
+	return s.defaultGroup
+}
+
+// userGroup role of user
+func (s *roleGroups) userGroup(user *models.User, names *nameIndex) (uint32, string) {
+	return user.RoleID, names.roles[user.RoleID]
+}
@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// patchRequest SCIM patch request
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+// patchOperation operation of patch request
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// valuePathRe path with value filter, e.g. emails[type eq "work"].value
+var valuePathRe = regexp.MustCompile(`^([^\[\]]+)\[(.*)\](?:\.([^\[\]]+))?$`)
+
+// errNoTarget remove operation without path
+var errNoTarget = newError(http.StatusBadRequest, "noTarget", "path is required for remove operation")
+
+// splitValuePath attribute, value filter and sub-attribute of path
+func splitValuePath(path string) (string, string, string) {
+	matches := valuePathRe.FindStringSubmatch(path)
+	if matches == nil {
+		return normalizeAttr(path), "", ""
+	}
+
+	return normalizeAttr(matches[1]), matches[2], strings.ToLower(matches[3])
+}
+
+// userPatchChanges changes of user by patch operations, attributes which are not stored are ignored
+func (h *Handler) userPatchChanges(operations []patchOperation) (*userChanges, error) {
+	changes := &userChanges{}
+
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return nil, invalidValue("unsupported operation %s", operation.Op)
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return nil, errNoTarget
+			}
+
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return nil, invalidValue("value of operation without path must be object")
+			}
+			for name, value := range values {
+				err := h.setUserAttr(changes, normalizeAttr(name), value)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		attr, _, subAttr := splitValuePath(operation.Path)
+		if subAttr != "" {
+			attr += "." + subAttr
+		}
+
+		var err error
+		if op == "remove" {
+			err = h.removeUserAttr(changes, attr)
+		} else {
+			err = h.setUserAttr(changes, attr, operation.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+// setUserAttr set attribute of user changes by value of patch operation
+func (h *Handler) setUserAttr(changes *userChanges, attr string, value interface{}) error {
+	switch attr {
+	case "username", "emails.value", "roles.value", "department", "name.givenname", "name.familyname":
+		text, ok := value.(string)
+		if !ok {
+			return invalidValue("%s must be string", attr)
+		}
+
+		switch attr {
+		case "username", "emails.value":
+			changes.email = &text
+		case "roles.value":
+			changes.role = &text
+		case "department":
+			changes.department = &text
+		case "name.givenname":
+			changes.firstName = &text
+		case "name.familyname":
+			changes.lastName = &text
+		}
+	case "active":
+		active, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+		changes.active = &active
+	case "name", strings.ToLower(SchemaEnterpriseUser):
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return invalidValue("%s must be object", attr)
+		}
+
+		prefix := attr + "."
+		if attr != "name" {
+			prefix = ""
+		}
+		for name, subValue := range values {
+			err := h.setUserAttr(changes, prefix+strings.ToLower(name), subValue)
+			if err != nil {
+				return err
+			}
+		}
+	case "emails", "roles":
+		primary, err := primaryOfValue(value)
+		if err != nil {
+			return invalidValue("%s: %s", attr, err.Error())
+		}
+		if primary == "" {
+			return nil
+		}
+
+		return h.setUserAttr(changes, attr+".value", primary)
+	}
+
+	return nil
+}
+
+// removeUserAttr remove attribute of user, department and role are reset to configured defaults
+func (h *Handler) removeUserAttr(changes *userChanges, attr string) error {
+	switch attr {
+	case "username", "emails", "emails.value", "name", "name.givenname", "name.familyname", "active":
+		return newError(http.StatusBadRequest, "mutability", attr+" is required and can't be removed")
+	case "department":
+		if h.cfg.ScimDefaultDepartment == "" {
+			return errNoDefaultGroup
+		}
+		changes.department = &h.cfg.ScimDefaultDepartment
+	case "roles", "roles.value":
+		changes.role = &h.cfg.ScimDefaultRole
+	}
+
+	return nil
+}
+
+// boolValue boolean of value, some clients send booleans as strings
+func boolValue(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, invalidValue("%s is not boolean", v)
+		}
+		return parsed, nil
+	default:
+		return false, invalidValue("boolean is expected")
+	}
+}
+
+// primaryOfValue value of primary or first item of multi-valued attribute given as array or single object
+func primaryOfValue(value interface{}) (string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	values := make([]multiValue, 0, len(items))
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return "", invalidValue("items must be objects")
+		}
+
+		text, _ := object["value"].(string)
+		primary, _ := object["primary"].(bool)
+		values = append(values, multiValue{Value: text, Primary: primary})
+	}
+
+	return primaryValue(values), nil
+}
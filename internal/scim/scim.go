@@ -0,0 +1,406 @@
+package scim
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemaUser core user schema
+	SchemaUser = "urn:ietf:params:scim:schemas:core:2.0:User"
+	// SchemaEnterpriseUser enterprise user extension with department
+	SchemaEnterpriseUser = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	// SchemaGroup core group schema
+	SchemaGroup = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	// SchemaListResponse list response message
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	// SchemaPatchOp patch request message
+	SchemaPatchOp = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	// SchemaError error response message
+	SchemaError = "urn:ietf:params:scim:api:messages:2.0:Error"
+	// SchemaServiceProviderConfig service provider configuration schema
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	// SchemaResourceType resource type schema
+	SchemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	// SchemaSchema schema of schema definitions
+	SchemaSchema = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+const (
+	// contentType content type of SCIM messages
+	contentType = "application/scim+json"
+	// maxResults max resources in one list response
+	maxResults = 200
+	// maxBodySize max size of request body
+	maxBodySize = 1024 * 1024
+)
+
+// Repository repository of users, departments and roles managed by SCIM
+type Repository interface {
+	SearchUsers(condition string, args []interface{}, offset, limit int) ([]models.User, int64, error)
+	CreateUser(user *models.User) (*models.User, error)
+	UpdateUserByID(id string, fields map[string]interface{}, expectedUpdatedAt time.Time) (*models.User, error)
+	UpdateUsersByIDs(ids []string, fields map[string]interface{}) error
+	RemoveUserByID(id string) error
+	EmailExist(email string) (bool, error)
+	AddAuditEntry(entry *models.AuditEntry) error
+	GetUserDepartmentByID(id uint32) (*models.Department, error)
+	GetUserDepartmentByName(name string) (*models.Department, error)
+	GetUserDepartments() (*[]models.Department, error)
+	AddUserDepartment(name string, parentID *uint32) (*models.Department, error)
+	RenameUserDepartment(id uint32, name string) (*models.Department, error)
+	RemoveUserDepartment(id, reassignID uint32) error
+	GetUserRoleByID(id uint32) (*models.Role, error)
+	GetUserRoleByName(name string) (*models.Role, error)
+	GetUserRoles() (*[]models.Role, error)
+	AddUserRole(name string) (*models.Role, error)
+	RenameUserRole(id uint32, name string) (*models.Role, error)
+	RemoveUserRole(id, reassignID uint32) error
+}
+
+// TokenRevoker revoke tokens of deactivated and deleted users
+type TokenRevoker interface {
+	RevokeAll(ctx context.Context, identity string, keep *models.JWT) error
+}
+
+// Handler SCIM 2.0 http handler of users and groups, it must be mounted with stripped base path
+type Handler struct {
+	db       Repository
+	tokenSrv TokenRevoker
+	groups   groupStore
+	trace    trace.Tracer
+	cfg      *config.Config
+	basePath string
+}
+
+// NewHandler create SCIM handler, basePath is used in locations of resources
+func NewHandler(db Repository, t TokenRevoker, tracer trace.Tracer, cfg *config.Config, basePath string) *Handler {
+	var groups groupStore = &departmentGroups{db: db, defaultGroup: cfg.ScimDefaultDepartment}
+	if cfg.ScimGroups == models.ScimGroupsRoles {
+		groups = &roleGroups{db: db, defaultGroup: cfg.ScimDefaultRole}
+	}
+
+	return &Handler{
+		db:       db,
+		tokenSrv: t,
+		groups:   groups,
+		trace:    tracer,
+		cfg:      cfg,
+		basePath: strings.TrimSuffix(basePath, "/"),
+	}
+}
+
+// scimError error response of SCIM
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+
+	status int
+}
+
+// Error text of error
+func (e *scimError) Error() string {
+	return e.Detail
+}
+
+// newError create SCIM error with http status
+func newError(status int, scimType, detail string) *scimError {
+	return &scimError{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+		status:   status,
+	}
+}
+
+var (
+	errUnauthorized    = newError(http.StatusUnauthorized, "", "bearer token is invalid")
+	errNotFound        = newError(http.StatusNotFound, "", "resource is not found")
+	errMethod          = newError(http.StatusMethodNotAllowed, "", "method is not allowed")
+	errInternal        = newError(http.StatusInternalServerError, "", "internal error")
+	errPrecondition    = newError(http.StatusPreconditionFailed, "", "resource is changed, version doesn't match")
+	errUniqueness      = newError(http.StatusConflict, "uniqueness", "resource with same unique attribute exists")
+	errNoDefaultGroup  = newError(http.StatusBadRequest, "mutability", "membership can't be removed because default group is not configured")
+	errUserDepartment  = newError(http.StatusBadRequest, "invalidValue", "department is not found")
+	errUserRole        = newError(http.StatusBadRequest, "invalidValue", "role is not found")
+	errRequiredMissing = newError(http.StatusBadRequest, "invalidValue", "userName, name.givenName and name.familyName are required")
+)
+
+// invalidValue error of invalid attribute value
+func invalidValue(format string, args ...interface{}) *scimError {
+	return newError(http.StatusBadRequest, "invalidValue", fmt.Sprintf(format, args...))
+}
+
+// ServeHTTP route request to resource handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+		writeError(w, errUnauthorized)
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) > 2 {
+		writeError(w, errNotFound)
+		return
+	}
+
+	id := ""
+	if len(segments) == 2 {
+		id = segments[1]
+	}
+
+	var err error
+	switch segments[0] {
+	case "Users":
+		err = h.serveUsers(w, r, id)
+	case "Groups":
+		err = h.serveGroups(w, r, id)
+	case "ServiceProviderConfig":
+		err = h.serveStatic(w, r, id == "", serviceProviderConfig(h.basePath))
+	case "ResourceTypes":
+		err = h.serveDiscovery(w, r, id, resourceTypes(h.basePath))
+	case "Schemas":
+		err = h.serveDiscovery(w, r, id, schemas(h.basePath))
+	default:
+		err = errNotFound
+	}
+
+	if err != nil {
+		var se *scimError
+		if !errors.As(err, &se) {
+			hclog.Default().Error("[scim.ServeHTTP] handle request", "method", r.Method, "path", r.URL.Path, "error", err)
+			se = errInternal
+		}
+		writeError(w, se)
+	}
+}
+
+// authorized check bearer token of request in constant time
+func (h *Handler) authorized(r *http.Request) bool {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if h.cfg.ScimToken == "" || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.ScimToken)) == 1
+}
+
+// serveUsers handle users collection or user by id
+func (h *Handler) serveUsers(w http.ResponseWriter, r *http.Request, id string) error {
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			return h.listUsers(w, r)
+		case http.MethodPost:
+			return h.createUser(w, r)
+		default:
+			return errMethod
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return h.getUser(w, r, id)
+	case http.MethodPut:
+		return h.replaceUser(w, r, id)
+	case http.MethodPatch:
+		return h.patchUser(w, r, id)
+	case http.MethodDelete:
+		return h.deleteUser(w, r, id)
+	default:
+		return errMethod
+	}
+}
+
+// serveGroups handle groups collection or group by id
+func (h *Handler) serveGroups(w http.ResponseWriter, r *http.Request, id string) error {
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			return h.listGroups(w, r)
+		case http.MethodPost:
+			return h.createGroup(w, r)
+		default:
+			return errMethod
+		}
+	}
+
+	groupID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return errNotFound
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return h.getGroup(w, r, uint32(groupID))
+	case http.MethodPut:
+		return h.replaceGroup(w, r, uint32(groupID))
+	case http.MethodPatch:
+		return h.patchGroup(w, r, uint32(groupID))
+	case http.MethodDelete:
+		return h.deleteGroup(w, r, uint32(groupID))
+	default:
+		return errMethod
+	}
+}
+
+// listResponse list of resources
+type listResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// pageOf start index and count of list request, start index is one based
+func pageOf(r *http.Request) (int, int, error) {
+	startIndex, count := 1, maxResults
+
+	if value := r.URL.Query().Get("startIndex"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, invalidValue("startIndex is not a number")
+		}
+		if parsed > 1 {
+			startIndex = parsed
+		}
+	}
+
+	if value := r.URL.Query().Get("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, invalidValue("count is not a number")
+		}
+		if parsed < 0 {
+			parsed = 0
+		}
+		if parsed < count {
+			count = parsed
+		}
+	}
+
+	return startIndex, count, nil
+}
+
+// parseFilterParam parse filter query parameter, nil if filter is not set
+func parseFilterParam(r *http.Request) (filterNode, error) {
+	value := r.URL.Query().Get("filter")
+	if value == "" {
+		return nil, nil
+	}
+
+	node, err := parseFilter(value)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+
+	return node, nil
+}
+
+// normalizeAttr lower case attribute path without schema of core resources and enterprise extension
+func normalizeAttr(attr string) string {
+	attr = strings.ToLower(attr)
+	for _, schema := range []string{SchemaUser, SchemaGroup, SchemaEnterpriseUser} {
+		prefix := strings.ToLower(schema) + ":"
+		if strings.HasPrefix(attr, prefix) {
+			return strings.TrimPrefix(attr, prefix)
+		}
+	}
+
+	return attr
+}
+
+// decodeBody decode json body of request
+func decodeBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	decoder.UseNumber()
+
+	err := decoder.Decode(v)
+	if err != nil {
+		return newError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("invalid json: %s", err))
+	}
+
+	return nil
+}
+
+// writeJSON write SCIM resource with status and version
+func writeJSON(w http.ResponseWriter, status int, version string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	if version != "" {
+		w.Header().Set("ETag", version)
+	}
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		hclog.Default().Error("[scim.writeJSON] json.Encode", "error", err)
+	}
+}
+
+// writeError write SCIM error
+func writeError(w http.ResponseWriter, err *scimError) {
+	writeJSON(w, err.status, "", err)
+}
+
+// checkVersion check If-Match header matches current version of resource
+func checkVersion(r *http.Request, version string) error {
+	match := r.Header.Get("If-Match")
+	if match == "" || match == "*" {
+		return nil
+	}
+
+	for _, candidate := range strings.Split(match, ",") {
+		if strings.TrimSpace(candidate) == version {
+			return nil
+		}
+	}
+
+	return errPrecondition
+}
+
+// notModified check If-None-Match header matches current version of resource
+func notModified(r *http.Request, version string) bool {
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if strings.TrimSpace(candidate) == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// audit record change made by SCIM client, failure is only logged because change is already done
+func (h *Handler) audit(method, subjectID string) {
+	err := h.db.AddAuditEntry(&models.AuditEntry{
+		ActorEmail: "scim",
+		SubjectID:  subjectID,
+		Method:     "scim." + method,
+		Code:       "OK",
+	})
+	if err != nil {
+		hclog.Default().Error("[scim.audit] h.db.AddAuditEntry", "method", method, "subjectID", subjectID, "error", err)
+	}
+}
+
+// isNotFound check error is not found error of repository
+func isNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
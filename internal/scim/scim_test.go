@@ -0,0 +1,550 @@
+package scim
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "scim-secret"
+
+// fakeRepository in-memory repository, it understands only conditions made by handler
+type fakeRepository struct {
+	users       []models.User
+	departments []models.Department
+	roles       []models.Role
+	nextUser    int
+	now         time.Time
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		departments: []models.Department{{ID: 1, Name: "Staff"}, {ID: 2, Name: "Engineering"}},
+		roles:       []models.Role{{ID: 1, Name: "user"}, {ID: 2, Name: "admin"}},
+		now:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// tick time of next change, each change gets new version
+func (r *fakeRepository) tick() time.Time {
+	r.now = r.now.Add(time.Second)
+	return r.now
+}
+
+func (r *fakeRepository) SearchUsers(condition string, args []interface{}, offset, limit int) ([]models.User, int64, error) {
+	var matched []models.User
+	for _, user := range r.users {
+		var ok bool
+		switch {
+		case condition == "":
+			ok = true
+		case condition == "id = ?":
+			ok = user.ID == args[0]
+		case strings.HasPrefix(condition, "id IN "):
+			for _, arg := range args {
+				ok = ok || user.ID == arg
+			}
+		case condition == "department_id = ?":
+			ok = user.DepartmentID == args[0]
+		case condition == "role_id = ?":
+			ok = user.RoleID == args[0]
+		default:
+			return nil, 0, fmt.Errorf("unsupported condition %s", condition)
+		}
+		if ok {
+			matched = append(matched, user)
+		}
+	}
+
+	total := int64(len(matched))
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if limit >= 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+
+	return matched, total, nil
+}
+
+func (r *fakeRepository) CreateUser(user *models.User) (*models.User, error) {
+	r.nextUser++
+	user.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", r.nextUser)
+	user.CreatedAt = r.tick()
+	user.UpdatedAt = user.CreatedAt
+	r.users = append(r.users, *user)
+
+	return user, nil
+}
+
+func (r *fakeRepository) UpdateUserByID(id string, fields map[string]interface{}, expectedUpdatedAt time.Time) (*models.User, error) {
+	for i := range r.users {
+		user := &r.users[i]
+		if user.ID != id {
+			continue
+		}
+		if !user.UpdatedAt.Equal(expectedUpdatedAt) {
+			return nil, models.VersionConflictError
+		}
+		r.apply(user, fields)
+
+		result := *user
+		return &result, nil
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) UpdateUsersByIDs(ids []string, fields map[string]interface{}) error {
+	for i := range r.users {
+		for _, id := range ids {
+			if r.users[i].ID == id {
+				r.apply(&r.users[i], fields)
+			}
+		}
+	}
+
+	return nil
+}
+
+// apply set changed columns of user
+func (r *fakeRepository) apply(user *models.User, fields map[string]interface{}) {
+	for column, value := range fields {
+		switch column {
+		case "email":
+			user.Email = value.(string)
+		case "first_name":
+			user.FirstName = value.(string)
+		case "last_name":
+			user.LastName = value.(string)
+		case "deactivated_at":
+			user.DeactivatedAt = value.(*time.Time)
+		case "department_id":
+			user.DepartmentID = value.(uint32)
+		case "role_id":
+			user.RoleID = value.(uint32)
+		}
+	}
+	user.UpdatedAt = r.tick()
+}
+
+func (r *fakeRepository) RemoveUserByID(id string) error {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			r.users = append(r.users[:i], r.users[i+1:]...)
+			return nil
+		}
+	}
+
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) EmailExist(email string) (bool, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *fakeRepository) AddAuditEntry(*models.AuditEntry) error {
+	return nil
+}
+
+func (r *fakeRepository) GetUserDepartmentByID(id uint32) (*models.Department, error) {
+	for i := range r.departments {
+		if r.departments[i].ID == id {
+			return &r.departments[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserDepartmentByName(name string) (*models.Department, error) {
+	for i := range r.departments {
+		if r.departments[i].Name == name {
+			return &r.departments[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserDepartments() (*[]models.Department, error) {
+	return &r.departments, nil
+}
+
+func (r *fakeRepository) AddUserDepartment(name string, parentID *uint32) (*models.Department, error) {
+	if _, err := r.GetUserDepartmentByName(name); err == nil {
+		return nil, models.AlreadyExistError
+	}
+	department := models.Department{ID: uint32(len(r.departments) + 1), Name: name, ParentID: parentID}
+	r.departments = append(r.departments, department)
+
+	return &department, nil
+}
+
+func (r *fakeRepository) RenameUserDepartment(id uint32, name string) (*models.Department, error) {
+	department, err := r.GetUserDepartmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	department.Name = name
+
+	return department, nil
+}
+
+func (r *fakeRepository) RemoveUserDepartment(id, reassignID uint32) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r *fakeRepository) GetUserRoleByID(id uint32) (*models.Role, error) {
+	for i := range r.roles {
+		if r.roles[i].ID == id {
+			return &r.roles[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserRoleByName(name string) (*models.Role, error) {
+	for i := range r.roles {
+		if r.roles[i].Name == name {
+			return &r.roles[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserRoles() (*[]models.Role, error) {
+	return &r.roles, nil
+}
+
+func (r *fakeRepository) AddUserRole(name string) (*models.Role, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *fakeRepository) RenameUserRole(id uint32, name string) (*models.Role, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *fakeRepository) RemoveUserRole(id, reassignID uint32) error {
+	return fmt.Errorf("not implemented")
+}
+
+// fakeRevoker remember users whose tokens are revoked
+type fakeRevoker struct {
+	revoked []string
+}
+
+func (f *fakeRevoker) RevokeAll(_ context.Context, identity string, _ *models.JWT) error {
+	f.revoked = append(f.revoked, identity)
+	return nil
+}
+
+func newTestHandler(db *fakeRepository, revoker *fakeRevoker) *Handler {
+	cfg := &config.Config{
+		ScimToken:             testToken,
+		ScimGroups:            models.ScimGroupsDepartments,
+		ScimDefaultDepartment: "Staff",
+		ScimDefaultRole:       "user",
+	}
+
+	return NewHandler(db, revoker, trace.NewNoopTracerProvider().Tracer(""), cfg, "/scim/v2")
+}
+
+// serve send request to handler, headers are given as name and value pairs
+func serve(h *Handler, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func decodeUser(t *testing.T, w *httptest.ResponseRecorder) *userResource {
+	t.Helper()
+
+	var resource userResource
+	err := json.NewDecoder(w.Body).Decode(&resource)
+	if err != nil {
+		t.Fatalf("decode user error: %v", err)
+	}
+
+	return &resource
+}
+
+func createTestUser(t *testing.T, h *Handler) *userResource {
+	t.Helper()
+
+	w := serve(h, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "Bob@Example.com",
+		"name": {"givenName": "Bob", "familyName": "Smith"}
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create user status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	return decodeUser(t, w)
+}
+
+func TestUnauthorized(t *testing.T) {
+	h := newTestHandler(newFakeRepository(), &fakeRevoker{})
+
+	r := httptest.NewRequest(http.MethodGet, "/Users", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCreateUserDefaults(t *testing.T) {
+	db := newFakeRepository()
+	h := newTestHandler(db, &fakeRevoker{})
+
+	user := createTestUser(t, h)
+
+	if user.UserName != "bob@example.com" {
+		t.Errorf("userName = %q, want lower case email", user.UserName)
+	}
+	if user.Enterprise == nil || user.Enterprise.Department != "Staff" {
+		t.Errorf("department = %+v, want default department", user.Enterprise)
+	}
+	if len(user.Roles) != 1 || user.Roles[0].Value != "user" {
+		t.Errorf("roles = %+v, want default role", user.Roles)
+	}
+	if user.Meta == nil || user.Meta.Version != userVersion(&db.users[0]) {
+		t.Errorf("meta = %+v, want version of stored user", user.Meta)
+	}
+
+	w := serve(h, http.MethodPost, "/Users", `{"userName": "bob@example.com", "name": {"givenName": "Bob", "familyName": "Smith"}}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate user status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	h := newTestHandler(newFakeRepository(), &fakeRevoker{})
+	user := createTestUser(t, h)
+
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		status int
+	}{
+		{name: "current", path: "/Users/" + user.ID, status: http.StatusOK},
+		{name: "upper case id", path: "/Users/" + strings.ToUpper(user.ID), status: http.StatusOK},
+		{name: "not modified", path: "/Users/" + user.ID, header: []string{"If-None-Match", user.Meta.Version}, status: http.StatusNotModified},
+		{name: "modified", path: "/Users/" + user.ID, header: []string{"If-None-Match", `W/"1"`}, status: http.StatusOK},
+		{name: "not uuid", path: "/Users/bob", status: http.StatusNotFound},
+		{name: "unknown", path: "/Users/00000000-0000-0000-0000-000000000099", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, http.MethodGet, tt.path, "", tt.header...)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusNotFound && w.Header().Get("ETag") != user.Meta.Version {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), user.Meta.Version)
+			}
+		})
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		check  func(t *testing.T, user *userResource)
+	}{
+		{
+			name: "replace name and department by path",
+			body: `{"Operations": [
+				{"op": "replace", "path": "name.givenName", "value": "Robert"},
+				{"op": "Replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Engineering"}
+			]}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *userResource) {
+				if user.Name.GivenName != "Robert" || user.Enterprise.Department != "Engineering" {
+					t.Errorf("user = %+v %+v, want Robert of Engineering", user.Name, user.Enterprise)
+				}
+			},
+		},
+		{
+			name:   "replace without path",
+			body:   `{"Operations": [{"op": "replace", "value": {"active": "False", "roles": [{"value": "admin", "primary": true}]}}]}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *userResource) {
+				if *user.Active || user.Roles[0].Value != "admin" {
+					t.Errorf("user active %v role %+v, want inactive admin", *user.Active, user.Roles)
+				}
+			},
+		},
+		{
+			name:   "value path",
+			body:   `{"Operations": [{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "robert@example.com"}]}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *userResource) {
+				if user.UserName != "robert@example.com" {
+					t.Errorf("userName = %q, want robert@example.com", user.UserName)
+				}
+			},
+		},
+		{
+			name:   "remove department resets default",
+			body:   `{"Operations": [{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Engineering"}, {"op": "remove", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department"}]}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *userResource) {
+				if user.Enterprise.Department != "Staff" {
+					t.Errorf("department = %q, want Staff", user.Enterprise.Department)
+				}
+			},
+		},
+		{
+			name:   "remove required attribute",
+			body:   `{"Operations": [{"op": "remove", "path": "userName"}]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "remove without path",
+			body:   `{"Operations": [{"op": "remove"}]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported operation",
+			body:   `{"Operations": [{"op": "move", "path": "userName", "value": "x"}]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown department",
+			body:   `{"Operations": [{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Sales"}]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "active is not boolean",
+			body:   `{"Operations": [{"op": "replace", "path": "active", "value": "maybe"}]}`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoker := &fakeRevoker{}
+			h := newTestHandler(newFakeRepository(), revoker)
+			user := createTestUser(t, h)
+
+			w := serve(h, http.MethodPatch, "/Users/"+user.ID, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.check == nil {
+				return
+			}
+
+			patched := decodeUser(t, w)
+			tt.check(t, patched)
+			if w.Header().Get("ETag") != patched.Meta.Version {
+				t.Errorf("ETag = %q, want version of patched user %q", w.Header().Get("ETag"), patched.Meta.Version)
+			}
+			if !*patched.Active && (len(revoker.revoked) != 1 || revoker.revoked[0] != user.ID) {
+				t.Errorf("revoked = %v, want tokens of deactivated user", revoker.revoked)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	replace := `{"userName": "bob@example.com", "name": {"givenName": "Bobby", "familyName": "Smith"}}`
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		ifMatch func(version string) string
+		status  int
+	}{
+		{name: "replace current", method: http.MethodPut, body: replace, ifMatch: func(v string) string { return v }, status: http.StatusOK},
+		{name: "replace any", method: http.MethodPut, body: replace, ifMatch: func(string) string { return "*" }, status: http.StatusOK},
+		{name: "replace one of list", method: http.MethodPut, body: replace, ifMatch: func(v string) string { return `W/"1", ` + v }, status: http.StatusOK},
+		{name: "replace stale", method: http.MethodPut, body: replace, ifMatch: func(string) string { return `W/"1"` }, status: http.StatusPreconditionFailed},
+		{name: "patch stale", method: http.MethodPatch, body: `{"Operations": [{"op": "replace", "path": "active", "value": false}]}`, ifMatch: func(string) string { return `W/"1"` }, status: http.StatusPreconditionFailed},
+		{name: "delete stale", method: http.MethodDelete, ifMatch: func(string) string { return `W/"1"` }, status: http.StatusPreconditionFailed},
+		{name: "delete current", method: http.MethodDelete, ifMatch: func(v string) string { return v }, status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			h := newTestHandler(db, &fakeRevoker{})
+			user := createTestUser(t, h)
+
+			w := serve(h, tt.method, "/Users/"+user.ID, tt.body, "If-Match", tt.ifMatch(user.Meta.Version))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+
+			if tt.status == http.StatusPreconditionFailed && db.users[0].UpdatedAt.UnixMicro() != user.Meta.LastModified.UnixMicro() {
+				t.Errorf("user is changed despite stale version")
+			}
+		})
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	db := newFakeRepository()
+	h := newTestHandler(db, &fakeRevoker{})
+	user := createTestUser(t, h)
+
+	tests := []struct {
+		name   string
+		member string
+		status int
+	}{
+		{name: "unknown user", member: "00000000-0000-0000-0000-000000000099", status: http.StatusBadRequest},
+		{name: "not uuid", member: "bob", status: http.StatusBadRequest},
+		{name: "upper case id", member: strings.ToUpper(user.ID), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"Operations": [{"op": "add", "path": "members", "value": [{"value": %q}]}]}`, tt.member)
+
+			w := serve(h, http.MethodPatch, "/Groups/2", body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	if db.users[0].DepartmentID != 2 {
+		t.Errorf("department of user = %d, want 2", db.users[0].DepartmentID)
+	}
+}
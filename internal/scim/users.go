@@ -0,0 +1,556 @@
+package scim
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// userAttrs sql columns of filterable user attributes
+var userAttrs = map[string]sqlAttr{
+	"id":                {column: "CAST(id AS TEXT)", kind: kindString},
+	"username":          {column: "email", kind: kindString},
+	"emails":            {column: "email", kind: kindString},
+	"emails.value":      {column: "email", kind: kindString},
+	"name.givenname":    {column: "first_name", kind: kindString},
+	"name.familyname":   {column: "last_name", kind: kindString},
+	"displayname":       {column: "(first_name || ' ' || last_name)", kind: kindString},
+	"active":            {column: "deactivated_at", kind: kindActive},
+	"meta.created":      {column: "created_at", kind: kindTime},
+	"meta.lastmodified": {column: "updated_at", kind: kindTime},
+}
+
+// userResource SCIM user
+type userResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	UserName    string          `json:"userName"`
+	Name        *nameAttr       `json:"name,omitempty"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Emails      []multiValue    `json:"emails,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+	Roles       []multiValue    `json:"roles,omitempty"`
+	Groups      []multiValue    `json:"groups,omitempty"`
+	Enterprise  *enterpriseAttr `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *meta           `json:"meta,omitempty"`
+}
+
+// nameAttr name of user
+type nameAttr struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+}
+
+// multiValue value of multi-valued attribute
+type multiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// enterpriseAttr attributes of enterprise user extension
+type enterpriseAttr struct {
+	Department string `json:"department,omitempty"`
+}
+
+// meta metadata of resource
+type meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+	Version      string     `json:"version,omitempty"`
+}
+
+// userChanges attributes of user set by request, nil attributes are not changed
+type userChanges struct {
+	email      *string
+	firstName  *string
+	lastName   *string
+	active     *bool
+	department *string
+	role       *string
+}
+
+// nameIndex names of departments and roles by id
+type nameIndex struct {
+	departments map[uint32]string
+	roles       map[uint32]string
+}
+
+// userVersion weak etag of user, microseconds are used because database keeps time with microsecond precision
+func userVersion(user *models.User) string {
+	return fmt.Sprintf(`W/"%d"`, user.UpdatedAt.UnixMicro())
+}
+
+// listUsers return page of users matched by filter
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) error {
+	_, span := h.trace.Start(r.Context(), "scim.ListUsers")
+	defer span.End()
+
+	startIndex, count, err := pageOf(r)
+	if err != nil {
+		return err
+	}
+
+	node, err := parseFilterParam(r)
+	if err != nil {
+		return err
+	}
+
+	var condition string
+	var args []interface{}
+	if node != nil {
+		condition, args, err = filterSQL(node, userAttrs)
+		if err != nil {
+			return newError(http.StatusBadRequest, "invalidFilter", err.Error())
+		}
+	}
+
+	users, total, err := h.db.SearchUsers(condition, args, startIndex-1, count)
+	if err != nil {
+		return fmt.Errorf("h.db.SearchUsers error: %w", err)
+	}
+
+	names, err := h.loadNames()
+	if err != nil {
+		return err
+	}
+
+	resources := make([]interface{}, 0, len(users))
+	for i := range users {
+		resources = append(resources, h.userToResource(&users[i], names))
+	}
+
+	writeJSON(w, http.StatusOK, "", &listResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+
+	return nil
+}
+
+// getUser return user by id
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request, id string) error {
+	_, span := h.trace.Start(r.Context(), "scim.GetUser")
+	defer span.End()
+
+	user, err := h.findUser(id)
+	if err != nil {
+		return err
+	}
+
+	version := userVersion(user)
+	if notModified(r, version) {
+		w.Header().Set("ETag", version)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	names, err := h.loadNames()
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, version, h.userToResource(user, names))
+
+	return nil
+}
+
+// createUser create user, department and role are taken from configured defaults if they are not set
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) error {
+	_, span := h.trace.Start(r.Context(), "scim.CreateUser")
+	defer span.End()
+
+	var resource userResource
+	err := decodeBody(r, &resource)
+	if err != nil {
+		return err
+	}
+
+	changes := resourceChanges(&resource)
+	if changes.email == nil || changes.firstName == nil || changes.lastName == nil {
+		return errRequiredMissing
+	}
+	if changes.department == nil {
+		changes.department = &h.cfg.ScimDefaultDepartment
+	}
+	if changes.role == nil {
+		changes.role = &h.cfg.ScimDefaultRole
+	}
+
+	fields, err := h.userFields(changes, nil)
+	if err != nil {
+		return err
+	}
+
+	user := &models.User{
+		Email:        fields["email"].(string),
+		FirstName:    fields["first_name"].(string),
+		LastName:     fields["last_name"].(string),
+		DepartmentID: fields["department_id"].(uint32),
+		RoleID:       fields["role_id"].(uint32),
+	}
+	if deactivatedAt, ok := fields["deactivated_at"].(*time.Time); ok {
+		user.DeactivatedAt = deactivatedAt
+	}
+
+	user, err = h.db.CreateUser(user)
+	if err != nil {
+		return fmt.Errorf("h.db.CreateUser error: %w", err)
+	}
+	h.audit("CreateUser", user.ID)
+
+	// user is read again to get time stored by database which is used as version
+	user, err = h.findUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	names, err := h.loadNames()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", h.location("Users", user.ID))
+	writeJSON(w, http.StatusCreated, userVersion(user), h.userToResource(user, names))
+
+	return nil
+}
+
+// replaceUser replace attributes of user, not set department and role are kept
+func (h *Handler) replaceUser(w http.ResponseWriter, r *http.Request, id string) error {
+	ctx, span := h.trace.Start(r.Context(), "scim.ReplaceUser")
+	defer span.End()
+
+	var resource userResource
+	err := decodeBody(r, &resource)
+	if err != nil {
+		return err
+	}
+
+	changes := resourceChanges(&resource)
+	if changes.email == nil || changes.firstName == nil || changes.lastName == nil {
+		return errRequiredMissing
+	}
+	if changes.active == nil {
+		active := true
+		changes.active = &active
+	}
+
+	return h.updateUser(ctx, w, r, id, "ReplaceUser", changes)
+}
+
+// patchUser apply patch operations to user
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request, id string) error {
+	ctx, span := h.trace.Start(r.Context(), "scim.PatchUser")
+	defer span.End()
+
+	var request patchRequest
+	err := decodeBody(r, &request)
+	if err != nil {
+		return err
+	}
+
+	changes, err := h.userPatchChanges(request.Operations)
+	if err != nil {
+		return err
+	}
+
+	return h.updateUser(ctx, w, r, id, "PatchUser", changes)
+}
+
+// updateUser change user if version matches, tokens of deactivated user are revoked
+func (h *Handler) updateUser(ctx context.Context, w http.ResponseWriter, r *http.Request, id, method string, changes *userChanges) error {
+	user, err := h.findUser(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(r, userVersion(user))
+	if err != nil {
+		return err
+	}
+
+	fields, err := h.userFields(changes, user)
+	if err != nil {
+		return err
+	}
+
+	wasActive := !user.IsDeactivated()
+	if len(fields) > 0 {
+		user, err = h.db.UpdateUserByID(id, fields, user.UpdatedAt)
+		if isNotFound(err) {
+			return errNotFound
+		}
+		if errors.Is(err, models.VersionConflictError) {
+			return errPrecondition
+		}
+		if err != nil {
+			return fmt.Errorf("h.db.UpdateUserByID error: %w", err)
+		}
+		h.audit(method, user.ID)
+	}
+
+	if wasActive && user.IsDeactivated() {
+		err = h.tokenSrv.RevokeAll(ctx, user.ID, nil)
+		if err != nil {
+			return fmt.Errorf("h.tokenSrv.RevokeAll error: %w", err)
+		}
+	}
+
+	names, err := h.loadNames()
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, userVersion(user), h.userToResource(user, names))
+
+	return nil
+}
+
+// deleteUser soft delete user and revoke its tokens
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request, id string) error {
+	ctx, span := h.trace.Start(r.Context(), "scim.DeleteUser")
+	defer span.End()
+
+	user, err := h.findUser(id)
+	if err != nil {
+		return err
+	}
+
+	err = checkVersion(r, userVersion(user))
+	if err != nil {
+		return err
+	}
+
+	err = h.db.RemoveUserByID(user.ID)
+	if isNotFound(err) {
+		return errNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("h.db.RemoveUserByID error: %w", err)
+	}
+	h.audit("DeleteUser", user.ID)
+
+	err = h.tokenSrv.RevokeAll(ctx, user.ID, nil)
+	if err != nil {
+		hclog.Default().Error("[scim.deleteUser] h.tokenSrv.RevokeAll", "userID", user.ID, "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// findUser get user by id, not found error if user doesn't exist or id is not uuid
+func (h *Handler) findUser(id string) (*models.User, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errNotFound
+	}
+
+	users, _, err := h.db.SearchUsers("id = ?", []interface{}{parsed.String()}, 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("h.db.SearchUsers error: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, errNotFound
+	}
+
+	return &users[0], nil
+}
+
+// userFields validate changes and convert them to changed columns of user, current is nil for new user
+func (h *Handler) userFields(changes *userChanges, current *models.User) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	if changes.email != nil {
+		email := strings.TrimSpace(strings.ToLower(*changes.email))
+		if err := validators.ValidateEmail(email); err != nil {
+			return nil, invalidValue("userName is not valid email")
+		}
+
+		if current == nil || current.Email != email {
+			exist, err := h.db.EmailExist(email)
+			if err != nil {
+				return nil, fmt.Errorf("h.db.EmailExist error: %w", err)
+			}
+			if exist {
+				return nil, errUniqueness
+			}
+			fields["email"] = email
+		}
+	}
+
+	if changes.firstName != nil && (current == nil || current.FirstName != *changes.firstName) {
+		if err := validators.ValidateFIO(*changes.firstName); err != nil {
+			return nil, invalidValue("name.givenName is not valid")
+		}
+		fields["first_name"] = *changes.firstName
+	}
+
+	if changes.lastName != nil && (current == nil || current.LastName != *changes.lastName) {
+		if err := validators.ValidateFIO(*changes.lastName); err != nil {
+			return nil, invalidValue("name.familyName is not valid")
+		}
+		fields["last_name"] = *changes.lastName
+	}
+
+	if changes.active != nil {
+		deactivated := current != nil && current.IsDeactivated()
+		if *changes.active && deactivated {
+			fields["deactivated_at"] = (*time.Time)(nil)
+		}
+		if !*changes.active && !deactivated {
+			now := time.Now()
+			fields["deactivated_at"] = &now
+		}
+	}
+
+	if changes.department != nil {
+		department, err := h.db.GetUserDepartmentByName(*changes.department)
+		if isNotFound(err) {
+			return nil, errUserDepartment
+		}
+		if err != nil {
+			return nil, fmt.Errorf("h.db.GetUserDepartmentByName error: %w", err)
+		}
+		if current == nil || current.DepartmentID != department.ID {
+			fields["department_id"] = department.ID
+		}
+	}
+
+	if changes.role != nil {
+		role, err := h.db.GetUserRoleByName(*changes.role)
+		if isNotFound(err) {
+			return nil, errUserRole
+		}
+		if err != nil {
+			return nil, fmt.Errorf("h.db.GetUserRoleByName error: %w", err)
+		}
+		if current == nil || current.RoleID != role.ID {
+			fields["role_id"] = role.ID
+		}
+	}
+
+	return fields, nil
+}
+
+// resourceChanges changes of user by SCIM user resource
+func resourceChanges(resource *userResource) *userChanges {
+	changes := &userChanges{active: resource.Active}
+
+	if resource.UserName != "" {
+		changes.email = &resource.UserName
+	}
+	if resource.Name != nil {
+		changes.firstName = &resource.Name.GivenName
+		changes.lastName = &resource.Name.FamilyName
+	}
+	if resource.Enterprise != nil && resource.Enterprise.Department != "" {
+		changes.department = &resource.Enterprise.Department
+	}
+	if role := primaryValue(resource.Roles); role != "" {
+		changes.role = &role
+	}
+
+	return changes
+}
+
+// primaryValue value of primary or first item of multi-valued attribute
+func primaryValue(values []multiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+
+	return ""
+}
+
+// userToResource SCIM user of user
+func (h *Handler) userToResource(user *models.User, names *nameIndex) *userResource {
+	active := !user.IsDeactivated()
+	created, lastModified := user.CreatedAt, user.UpdatedAt
+
+	resource := &userResource{
+		Schemas:  []string{SchemaUser, SchemaEnterpriseUser},
+		ID:       user.ID,
+		UserName: user.Email,
+		Name: &nameAttr{
+			Formatted:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		},
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Emails:      []multiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Roles:       []multiValue{{Value: names.roles[user.RoleID], Primary: true}},
+		Enterprise:  &enterpriseAttr{Department: names.departments[user.DepartmentID]},
+		Meta: &meta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &lastModified,
+			Location:     h.location("Users", user.ID),
+			Version:      userVersion(user),
+		},
+	}
+
+	groupID, groupName := h.groups.userGroup(user, names)
+	resource.Groups = []multiValue{{
+		Value:   fmt.Sprint(groupID),
+		Display: groupName,
+		Type:    "direct",
+		Ref:     h.location("Groups", fmt.Sprint(groupID)),
+	}}
+
+	return resource
+}
+
+// loadNames names of all departments and roles
+func (h *Handler) loadNames() (*nameIndex, error) {
+	departments, err := h.db.GetUserDepartments()
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetUserDepartments error: %w", err)
+	}
+
+	roles, err := h.db.GetUserRoles()
+	if err != nil {
+		return nil, fmt.Errorf("h.db.GetUserRoles error: %w", err)
+	}
+
+	names := &nameIndex{
+		departments: make(map[uint32]string, len(*departments)),
+		roles:       make(map[uint32]string, len(*roles)),
+	}
+	for _, department := range *departments {
+		names.departments[department.ID] = department.Name
+	}
+	for _, role := range *roles {
+		names.roles[role.ID] = role.Name
+	}
+
+	return names, nil
+}
+
+// location url of resource
+func (h *Handler) location(resourceType, id string) string {
+	return fmt.Sprintf("%s/%s/%s", h.basePath, resourceType, id)
+}
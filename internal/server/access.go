@@ -2,47 +2,178 @@ package server
 
 import (
 	"account-service/internal/models"
+	"account-service/internal/policy"
 	"context"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	protos "protos/account"
+	"strconv"
 )
 
-// CheckAccess check access token
+// CheckAccess check access token or api key, resource and action are evaluated by policy engine if they are set
 func (a *AccountService) CheckAccess(ctx context.Context, rr *protos.CheckAccessRequest) (*protos.CheckAccessResponse, error) {
 	log := hclog.Default()
 
 	ctx, span := a.trace.Start(ctx, "CheckAccess")
 	defer span.End()
 
-	accessToken := rr.GetToken()
+	principal, err := a.principalFromToken(ctx, rr.GetToken())
+	if err != nil {
+		log.Error("[server.CheckAccess] a.principalFromToken", "error", err)
+		return nil, err
+	}
 
-	tok, err := a.tokenSrv.ParseJWT(
-		ctx,
-		accessToken,
-	)
+	departmentPath, err := a.departmentPath(principal.DepartmentID)
 	if err != nil {
-		log.Error("[server.CheckAccess] a.tokenSrv.ParseJWT", "accessToken", accessToken, "error", err)
-		return nil, models.UnauthenticatedAuthTokenError
+		log.Error("[server.CheckAccess] a.departmentPath", "departmentID", principal.DepartmentID, "error", err)
+		return nil, models.InternalError
+	}
+
+	var decision *protos.PolicyDecision
+	if rr.GetResource() != "" || rr.GetAction() != "" {
+		decision = policyDecisionToProto(a.policy.Evaluate(policy.Request{
+			Action:     rr.GetAction(),
+			Resource:   rr.GetResource(),
+			Attributes: policyAttributes(principal, departmentPath, rr.GetAttributes()),
+		}, rr.GetExplain()))
+	}
+
+	return &protos.CheckAccessResponse{
+		UserId:         principal.UserID,
+		Email:          principal.Email,
+		DepartmentId:   principal.DepartmentID,
+		RoleId:         principal.RoleID,
+		Permissions:    principal.Permissions,
+		DepartmentPath: departmentPath,
+		Roles:          effectiveRolesToProto(principal.Roles),
+		Decision:       decision,
+		ClientId:       principal.ClientID,
+		ApiKeyId:       principal.APIKeyID,
+	}, nil
+}
+
+// ExplainAccess evaluate policy on behalf of user without its token and explain decision
+func (a *AccountService) ExplainAccess(ctx context.Context, rr *protos.ExplainAccessRequest) (*protos.PolicyDecision, error) {
+	log := hclog.Default()
+
+	ctx, span := a.trace.Start(ctx, "ExplainAccess")
+	defer span.End()
+
+	user, err := a.db.GetUserByID(rr.GetUserId())
+	if err != nil || user == nil {
+		log.Error("[server.ExplainAccess] a.db.GetUserByID", "userID", rr.GetUserId(), "error", err)
+		return nil, models.UserNotFoundError
 	}
 
-	if err := a.tokenSrv.Validate(tok, models.AccessToken); err != nil {
-		log.Error("[server.CheckAccess] a.tokenSrv.Validate", "accessToken", accessToken, "error", err)
-		return nil, models.UnauthenticatedAuthTokenError
+	principal, err := a.principalForUser(user)
+	if err != nil {
+		log.Error("[server.ExplainAccess] a.principalForUser", "userID", user.ID, "error", err)
+		return nil, models.InternalError
 	}
 
-	user, err := a.db.GetUserByID(tok.Identity)
+	departmentPath, err := a.departmentPath(principal.DepartmentID)
 	if err != nil {
-		log.Error("[server.CheckAccess] a.db.GetUserByID", "error", err)
+		log.Error("[server.ExplainAccess] a.departmentPath", "departmentID", principal.DepartmentID, "error", err)
 		return nil, models.InternalError
 	}
-	if user == nil {
-		return nil, models.UserNotFoundError
+
+	return policyDecisionToProto(a.policy.Evaluate(policy.Request{
+		Action:     rr.GetAction(),
+		Resource:   rr.GetResource(),
+		Attributes: policyAttributes(principal, departmentPath, rr.GetAttributes()),
+	}, true)), nil
+}
+
+// departmentPath ids of departments from root to department
+func (a *AccountService) departmentPath(departmentID uint32) ([]uint32, error) {
+	departments, err := a.db.GetDepartmentPath(departmentID)
+	if err != nil {
+		return nil, fmt.Errorf("a.db.GetDepartmentPath error: %w", err)
 	}
 
-	return &protos.CheckAccessResponse{
-		UserId:       tok.Identity,
-		Email:        user.Email,
-		DepartmentId: user.DepartmentID,
-		RoleId:       user.RoleID,
-	}, nil
+	departmentPath := make([]uint32, 0, len(departments))
+	for _, department := range departments {
+		departmentPath = append(departmentPath, department.ID)
+	}
+
+	return departmentPath, nil
+}
+
+// policyAttributes attributes of principal and request for policy engine,
+// request attributes are prefixed by "request."
+func policyAttributes(principal *models.Principal, departmentPath []uint32, requestAttributes map[string]string) policy.Attributes {
+	roleIDs := make([]string, 0, len(principal.Roles))
+	scopedRoles := make([]string, 0, len(principal.Roles))
+	for _, role := range principal.Roles {
+		if role.DepartmentID == nil {
+			roleIDs = append(roleIDs, formatID(role.RoleID))
+			continue
+		}
+		scopedRoles = append(scopedRoles, formatID(role.RoleID)+"@"+formatID(*role.DepartmentID))
+	}
+
+	path := make([]string, 0, len(departmentPath))
+	for _, id := range departmentPath {
+		path = append(path, formatID(id))
+	}
+
+	attributes := policy.Attributes{
+		"user.department_id":   {formatID(principal.DepartmentID)},
+		"user.department_path": path,
+		"user.role_id":         {formatID(principal.RoleID)},
+		"user.role_ids":        roleIDs,
+		"user.scoped_roles":    scopedRoles,
+		"user.permissions":     principal.Permissions,
+	}
+
+	// principal without user has no user attributes, so exists operator doesn't match empty user id
+	if principal.UserID != "" {
+		attributes["user.id"] = []string{principal.UserID}
+		attributes["user.email"] = []string{principal.Email}
+	}
+
+	for key, value := range requestAttributes {
+		attributes["request."+key] = []string{value}
+	}
+
+	return attributes
+}
+
+func formatID(id uint32) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func policyDecisionToProto(decision policy.Decision) *protos.PolicyDecision {
+	trace := make([]*protos.PolicyRuleTrace, 0, len(decision.Trace))
+	for _, ruleTrace := range decision.Trace {
+		trace = append(trace, &protos.PolicyRuleTrace{
+			Rule:    ruleTrace.Rule,
+			Matched: ruleTrace.Matched,
+			Reason:  ruleTrace.Reason,
+		})
+	}
+
+	return &protos.PolicyDecision{
+		Allowed:     decision.Allowed,
+		MatchedRule: decision.Rule,
+		Trace:       trace,
+	}
+}
+
+func effectiveRolesToProto(roles []models.EffectiveRole) []*protos.EffectiveRole {
+	result := make([]*protos.EffectiveRole, 0, len(roles))
+	for _, role := range roles {
+		var departmentID uint32
+		if role.DepartmentID != nil {
+			departmentID = *role.DepartmentID
+		}
+
+		result = append(result, &protos.EffectiveRole{
+			RoleId:       role.RoleID,
+			DepartmentId: departmentID,
+			Permissions:  role.Permissions,
+		})
+	}
+
+	return result
 }
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/policy"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyAttributes(t *testing.T) {
+	var engineering uint32 = 2
+
+	p := &policy.Policy{Rules: []policy.Rule{{
+		Name:       "users",
+		Effect:     policy.Allow,
+		Actions:    []string{"*"},
+		Resources:  []string{"*"},
+		Conditions: []policy.Condition{{Attribute: "user.id", Operator: policy.Exists}},
+	}}}
+
+	tests := []struct {
+		name      string
+		principal *models.Principal
+		want      policy.Attributes
+		allowed   bool
+	}{
+		{
+			name: "user",
+			principal: &models.Principal{
+				UserID: "user-1", Email: "ann@example.com", DepartmentID: 3, RoleID: 1,
+				Roles:       []models.EffectiveRole{{RoleID: 1}, {RoleID: 2, DepartmentID: &engineering}},
+				Permissions: []string{models.PermissionRolesRead},
+			},
+			want: policy.Attributes{
+				"user.id":              {"user-1"},
+				"user.email":           {"ann@example.com"},
+				"user.department_id":   {"3"},
+				"user.department_path": {"2", "3"},
+				"user.role_id":         {"1"},
+				"user.role_ids":        {"1"},
+				"user.scoped_roles":    {"2@2"},
+				"user.permissions":     {models.PermissionRolesRead},
+				"request.owner":        {"user-1"},
+			},
+			allowed: true,
+		},
+		{
+			// service principal acting without user
+			name: "principal without user",
+			principal: &models.Principal{
+				DepartmentID: 3,
+				Roles:        []models.EffectiveRole{{Permissions: []string{models.PermissionDepartmentsRead}}},
+				Permissions:  []string{models.PermissionDepartmentsRead},
+			},
+			want: policy.Attributes{
+				"user.department_id":   {"3"},
+				"user.department_path": {"2", "3"},
+				"user.role_id":         {"0"},
+				"user.role_ids":        {"0"},
+				"user.scoped_roles":    {},
+				"user.permissions":     {models.PermissionDepartmentsRead},
+				"request.owner":        {"user-1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes := policyAttributes(tt.principal, []uint32{2, 3}, map[string]string{"owner": "user-1"})
+			if !reflect.DeepEqual(attributes, tt.want) {
+				t.Errorf("attributes = %v, want %v", attributes, tt.want)
+			}
+
+			decision := p.Evaluate(policy.Request{Action: "read", Resource: "document", Attributes: attributes}, false)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("allowed = %v, want %v", decision.Allowed, tt.allowed)
+			}
+		})
+	}
+}
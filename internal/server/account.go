@@ -10,18 +10,32 @@ import (
 // AccountService grpc service declaration
 type AccountService struct {
 	protos.UnimplementedAccountServiceServer
-	db       interfaces.Repository
-	tokenSrv interfaces.TokenService
-	trace    trace.Tracer
-	cfg      *config.Config
+	db        interfaces.Repository
+	tokenSrv  interfaces.TokenService
+	policy    interfaces.PolicyEngine
+	breach    interfaces.BreachChecker
+	hasher    interfaces.PasswordHasher
+	erasure   interfaces.ErasureWorker
+	notifier  interfaces.Notifier
+	identity  interfaces.IdentityFederation
+	directory interfaces.Directory
+	trace     trace.Tracer
+	cfg       *config.Config
 }
 
 // NewAccount Creates a new Account server
-func NewAccount(db interfaces.Repository, t interfaces.TokenService, tracer trace.Tracer, cfg *config.Config) *AccountService {
+func NewAccount(db interfaces.Repository, t interfaces.TokenService, pe interfaces.PolicyEngine, bc interfaces.BreachChecker, ph interfaces.PasswordHasher, ew interfaces.ErasureWorker, n interfaces.Notifier, idf interfaces.IdentityFederation, dir interfaces.Directory, tracer trace.Tracer, cfg *config.Config) *AccountService {
 	return &AccountService{
-		db:       db,
-		tokenSrv: t,
-		trace:    tracer,
-		cfg:      cfg,
+		db:        db,
+		tokenSrv:  t,
+		policy:    pe,
+		breach:    bc,
+		hasher:    ph,
+		erasure:   ew,
+		notifier:  n,
+		identity:  idf,
+		directory: dir,
+		trace:     tracer,
+		cfg:       cfg,
 	}
 }
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+	protos "protos/account"
+)
+
+// DeactivateAccount block login and access of account keeping its data, all tokens of account are revoked
+func (a *AccountService) DeactivateAccount(ctx context.Context, rr *protos.AccountIDRequest) (*protos.GetAccountInfoResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "DeactivateAccount")
+	defer span.End()
+
+	err := a.checkNotSelf(ctx, rr.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.db.SetUserDeactivated(rr.GetUserId(), true)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.DeactivateAccount] a.db.SetUserDeactivated", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, user.ID, nil)
+	if err != nil {
+		log.Error("[server.DeactivateAccount] a.tokenSrv.RevokeAll", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return userToProto(user), nil
+}
+
+// ReactivateAccount allow login of deactivated account again
+func (a *AccountService) ReactivateAccount(ctx context.Context, rr *protos.AccountIDRequest) (*protos.GetAccountInfoResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ReactivateAccount")
+	defer span.End()
+
+	user, err := a.db.SetUserDeactivated(rr.GetUserId(), false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.ReactivateAccount] a.db.SetUserDeactivated", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return userToProto(user), nil
+}
+
+// DeleteAccount soft delete account, all tokens of account are revoked
+func (a *AccountService) DeleteAccount(ctx context.Context, rr *protos.AccountIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "DeleteAccount")
+	defer span.End()
+
+	err := a.checkNotSelf(ctx, rr.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.db.RemoveUserByID(rr.GetUserId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.DeleteAccount] a.db.RemoveUserByID", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, rr.GetUserId(), nil)
+	if err != nil {
+		log.Error("[server.DeleteAccount] a.tokenSrv.RevokeAll", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RestoreAccount restore soft deleted account
+func (a *AccountService) RestoreAccount(ctx context.Context, rr *protos.AccountIDRequest) (*protos.GetAccountInfoResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "RestoreAccount")
+	defer span.End()
+
+	user, err := a.db.RestoreUserByID(rr.GetUserId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RestoreAccount] a.db.RestoreUserByID", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return userToProto(user), nil
+}
+
+// checkNotSelf refuse actions of caller on own account which would lock caller out
+func (a *AccountService) checkNotSelf(ctx context.Context, userID string) error {
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if principal.UserID == userID {
+		return models.SelfAccountActionError
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	protos "protos/account"
+	"time"
+)
+
+const (
+	// defaultPageSize page size of listing if it is not set
+	defaultPageSize = 50
+	// maxPageSize max page size of listing
+	maxPageSize = 200
+)
+
+// accountSortFields sort fields of users by proto sort field
+var accountSortFields = map[protos.AccountSortField]string{
+	protos.AccountSortField_CREATED_AT: models.UserSortCreatedAt,
+	protos.AccountSortField_EMAIL:      models.UserSortEmail,
+	protos.AccountSortField_LAST_NAME:  models.UserSortLastName,
+}
+
+// ListAccounts return page of accounts matched by search and filters
+func (a *AccountService) ListAccounts(ctx context.Context, rr *protos.ListAccountsRequest) (*protos.ListAccountsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListAccounts")
+	defer span.End()
+
+	filter, err := accountsFilter(rr)
+	if err != nil {
+		log.Error("[server.ListAccounts] accountsFilter", "error", err)
+		return nil, err
+	}
+
+	pageSize := filter.Limit
+	// one more user is requested to know if there is next page
+	filter.Limit++
+
+	users, err := a.db.ListUsers(*filter)
+	if err != nil {
+		log.Error("[server.ListAccounts] a.db.ListUsers", "error", err)
+		return nil, models.InternalError
+	}
+
+	var nextPageToken string
+	if len(users) > pageSize {
+		users = users[:pageSize]
+
+		last := users[len(users)-1]
+		nextPageToken, err = encodeCursor(&models.UserCursor{
+			SortBy:     filter.SortBy,
+			Descending: filter.Descending,
+			Value:      last.SortValue(filter.SortBy),
+			ID:         last.ID,
+		})
+		if err != nil {
+			log.Error("[server.ListAccounts] encodeCursor", "error", err)
+			return nil, models.InternalError
+		}
+	}
+
+	accounts := make([]*protos.GetAccountInfoResponse, 0, len(users))
+	for i := range users {
+		accounts = append(accounts, userToProto(&users[i]))
+	}
+
+	return &protos.ListAccountsResponse{
+		Accounts:      accounts,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// accountsFilter users filter by listing request
+func accountsFilter(rr *protos.ListAccountsRequest) (*models.UserFilter, error) {
+	sortBy, ok := accountSortFields[rr.GetSortBy()]
+	if !ok {
+		return nil, models.BadRequestError
+	}
+
+	pageSize := int(rr.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	filter := &models.UserFilter{
+		Query:                 rr.GetQuery(),
+		DepartmentID:          rr.GetDepartmentId(),
+		IncludeSubdepartments: rr.GetIncludeSubdepartments(),
+		RoleID:                rr.GetRoleId(),
+		IsRegistered:          rr.IsRegistered,
+		EmailVerified:         rr.EmailVerified,
+		CreatedFrom:           optionalTime(rr.GetCreatedFrom()),
+		CreatedTo:             optionalTime(rr.GetCreatedTo()),
+		SortBy:                sortBy,
+		Descending:            rr.GetDescending(),
+		Limit:                 pageSize,
+	}
+
+	if rr.GetPageToken() != "" {
+		cursor, err := decodeCursor(rr.GetPageToken())
+		if err != nil {
+			return nil, models.InvalidPageTokenError
+		}
+
+		if cursor.SortBy != filter.SortBy || cursor.Descending != filter.Descending {
+			return nil, models.InvalidPageTokenError
+		}
+
+		if cursor.SortBy == models.UserSortCreatedAt {
+			if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+				return nil, models.InvalidPageTokenError
+			}
+		}
+
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+// encodeCursor opaque page token of cursor
+func encodeCursor(cursor *models.UserCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor cursor of opaque page token
+func decodeCursor(token string) (*models.UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("base64.DecodeString error: %w", err)
+	}
+
+	var cursor models.UserCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,321 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	protos "protos/account"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefixSize count of random bytes of public prefix of api key
+	apiKeyPrefixSize = 8
+	// apiKeySecretSize count of random bytes of secret of api key
+	apiKeySecretSize = 32
+	// apiKeyUseInterval min time between updates of last use of api key
+	apiKeyUseInterval = time.Minute
+)
+
+// CreateAPIKey create api key of service principal, caller can grant only permissions it has itself,
+// key is returned only once
+func (a *AccountService) CreateAPIKey(ctx context.Context, rr *protos.CreateAPIKeyRequest) (*protos.APIKeySecret, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "CreateAPIKey")
+	defer span.End()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(rr.GetName())
+	if name == "" {
+		return nil, models.InvalidAPIKeyError(fmt.Errorf("name is required"))
+	}
+
+	permissions := make([]string, 0, len(rr.GetPermissions()))
+	for _, permission := range rr.GetPermissions() {
+		permission = strings.TrimSpace(permission)
+		if permission == "" || containsPermission(permissions, permission) {
+			continue
+		}
+		permissions = append(permissions, permission)
+	}
+	if len(permissions) == 0 {
+		return nil, models.InvalidAPIKeyError(fmt.Errorf("permissions are required"))
+	}
+
+	if !principal.HasPermissions(permissions...) {
+		log.Error("[server.CreateAPIKey] principal.HasPermissions", "userID", principal.UserID, "permissions", permissions)
+		return nil, models.PermissionDeniedError
+	}
+
+	if rr.GetDepartmentId() != 0 {
+		department, err := a.db.GetUserDepartmentByID(rr.GetDepartmentId())
+		if err != nil || department == nil {
+			log.Error("[server.CreateAPIKey] a.db.GetUserDepartmentByID", "departmentID", rr.GetDepartmentId(), "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	prefix, err := randomHex(apiKeyPrefixSize)
+	if err != nil {
+		log.Error("[server.CreateAPIKey] randomHex", "error", err)
+		return nil, models.InternalError
+	}
+
+	secret, secretHash, err := newAPIKeySecret()
+	if err != nil {
+		log.Error("[server.CreateAPIKey] newAPIKeySecret", "error", err)
+		return nil, models.InternalError
+	}
+
+	key, err := a.db.AddAPIKey(&models.APIKey{
+		Name:         name,
+		Prefix:       prefix,
+		SecretHash:   secretHash,
+		Permissions:  strings.Join(permissions, " "),
+		DepartmentID: rr.GetDepartmentId(),
+		CreatedBy:    principal.UserID,
+	})
+	if err != nil {
+		log.Error("[server.CreateAPIKey] a.db.AddAPIKey", "error", err)
+		return nil, models.InternalError
+	}
+
+	return &protos.APIKeySecret{
+		Key:    apiKeyToProto(key),
+		ApiKey: formatAPIKey(key.Prefix, secret),
+	}, nil
+}
+
+// ListAPIKeys return all not revoked api keys without secrets
+func (a *AccountService) ListAPIKeys(ctx context.Context, _ *emptypb.Empty) (*protos.ListAPIKeysResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListAPIKeys")
+	defer span.End()
+
+	keys, err := a.db.GetAPIKeys()
+	if err != nil {
+		log.Error("[server.ListAPIKeys] a.db.GetAPIKeys", "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.APIKey, 0, len(keys))
+	for i := range keys {
+		result = append(result, apiKeyToProto(&keys[i]))
+	}
+
+	return &protos.ListAPIKeysResponse{
+		Keys: result,
+	}, nil
+}
+
+// RotateAPIKey replace secret of api key keeping its prefix, previous key stops working immediately,
+// caller must have all permissions of key as on creation
+func (a *AccountService) RotateAPIKey(ctx context.Context, rr *protos.APIKeyIDRequest) (*protos.APIKeySecret, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "RotateAPIKey")
+	defer span.End()
+
+	err := a.authorizeAPIKey(ctx, rr.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := newAPIKeySecret()
+	if err != nil {
+		log.Error("[server.RotateAPIKey] newAPIKeySecret", "error", err)
+		return nil, models.InternalError
+	}
+
+	key, err := a.db.UpdateAPIKeySecret(rr.GetId(), secretHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.APIKeyNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RotateAPIKey] a.db.UpdateAPIKeySecret", "keyID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &protos.APIKeySecret{
+		Key:    apiKeyToProto(key),
+		ApiKey: formatAPIKey(key.Prefix, secret),
+	}, nil
+}
+
+// RevokeAPIKey remove api key, it is rejected afterwards, caller must have all permissions of key
+func (a *AccountService) RevokeAPIKey(ctx context.Context, rr *protos.APIKeyIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "RevokeAPIKey")
+	defer span.End()
+
+	err := a.authorizeAPIKey(ctx, rr.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.db.RemoveAPIKey(rr.GetId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.APIKeyNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RevokeAPIKey] a.db.RemoveAPIKey", "keyID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// authorizeAPIKey check caller has all permissions of api key
+func (a *AccountService) authorizeAPIKey(ctx context.Context, id string) error {
+	log := hclog.Default()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := a.db.GetAPIKeyByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.APIKeyNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.authorizeAPIKey] a.db.GetAPIKeyByID", "keyID", id, "error", err)
+		return models.InternalError
+	}
+
+	if !principal.HasPermissions(key.PermissionList()...) {
+		log.Error("[server.authorizeAPIKey] principal.HasPermissions", "userID", principal.UserID, "keyID", key.ID)
+		return models.PermissionDeniedError
+	}
+
+	return nil
+}
+
+// principalForAPIKey get service principal of api key with its permissions and department,
+// permissions of key scoped to department are not global,
+// last use of key is updated at most once per apiKeyUseInterval
+func (a *AccountService) principalForAPIKey(prefix, secret string) (*models.Principal, error) {
+	log := hclog.Default()
+
+	key, err := a.db.GetAPIKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+	if err != nil {
+		log.Error("[server.principalForAPIKey] a.db.GetAPIKeyByPrefix", "prefix", prefix, "error", err)
+		return nil, models.InternalError
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		log.Error("[server.principalForAPIKey] secret doesn't match", "keyID", key.ID)
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyUseInterval {
+		err = a.db.SetAPIKeyUse(key.ID, now)
+		if err != nil {
+			log.Error("[server.principalForAPIKey] a.db.SetAPIKeyUse", "keyID", key.ID, "error", err)
+		}
+	}
+
+	principal := &models.Principal{
+		APIKeyID:     key.ID,
+		DepartmentID: key.DepartmentID,
+		Roles:        []models.EffectiveRole{{Permissions: key.PermissionList()}},
+	}
+	// permissions of scoped key are granted only in its department like scoped role assignments,
+	// so they aren't global permissions checked by administrative rpc
+	if key.DepartmentID != 0 {
+		departmentID := key.DepartmentID
+		principal.Roles[0].DepartmentID = &departmentID
+	}
+	principal.Permissions = models.GlobalPermissions(principal.Roles)
+
+	return principal, nil
+}
+
+// newAPIKeySecret random secret of api key and its hash
+func newAPIKeySecret() (string, string, error) {
+	data := make([]byte, apiKeySecretSize)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(data)
+
+	return secret, hashAPIKeySecret(secret), nil
+}
+
+// hashAPIKeySecret hash of secret of api key, secret is random so fast hash is enough
+// and key can be checked on every request
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatAPIKey api key given to service
+func formatAPIKey(prefix, secret string) string {
+	return models.APIKeyPrefix + prefix + "_" + secret
+}
+
+// randomHex hex of size random bytes
+func randomHex(size int) (string, error) {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, item := range permissions {
+		if item == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func apiKeyToProto(key *models.APIKey) *protos.APIKey {
+	result := &protos.APIKey{
+		Id:           key.ID,
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		Permissions:  key.PermissionList(),
+		DepartmentId: key.DepartmentID,
+		CreatedBy:    key.CreatedBy,
+		CreatedAt:    timestamppb.New(key.CreatedAt),
+	}
+	if key.LastUsedAt != nil {
+		result.LastUsedAt = timestamppb.New(*key.LastUsedAt)
+	}
+
+	return result
+}
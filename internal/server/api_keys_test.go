@@ -0,0 +1,114 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	protos "protos/account"
+	"testing"
+)
+
+func TestAPIKeyPrincipal(t *testing.T) {
+	tests := []struct {
+		name         string
+		departmentID uint32
+		// authorized rpc requires users:lookup permission
+		authorized bool
+	}{
+		{name: "global key", authorized: true},
+		{name: "key scoped to department", departmentID: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.apiKeys = []models.APIKey{{
+				ID:           "key-1",
+				Prefix:       "0123456789abcdef",
+				SecretHash:   hashAPIKeySecret("secret"),
+				Permissions:  models.PermissionUsersLookup,
+				DepartmentID: tt.departmentID,
+			}}
+			a := newTestService(db, nil)
+
+			principal, err := a.principalFromToken(context.Background(), formatAPIKey("0123456789abcdef", "secret"))
+			if err != nil {
+				t.Fatalf("principalFromToken error: %v", err)
+			}
+			if principal.APIKeyID != "key-1" || principal.UserID != "" || len(principal.Roles) != 1 {
+				t.Fatalf("principal = %+v", principal)
+			}
+
+			role := principal.Roles[0]
+			if tt.departmentID == 0 && role.DepartmentID != nil || tt.departmentID != 0 && (role.DepartmentID == nil || *role.DepartmentID != tt.departmentID) {
+				t.Errorf("role = %+v, want scope of key %d", role, tt.departmentID)
+			}
+
+			ctx := context.WithValue(context.Background(), principalKey{}, principal)
+			_, err = a.authorize(ctx, "/account.AccountService/GetAccountsByIDs")
+			if tt.authorized && err != nil {
+				t.Errorf("authorize error: %v", err)
+			}
+			if !tt.authorized && !errors.Is(err, models.PermissionDeniedError) {
+				t.Errorf("authorize error = %v, want %v", err, models.PermissionDeniedError)
+			}
+
+			// self-service rpc of key would otherwise act on user with empty id
+			_, err = a.authenticateUser(ctx)
+			if !errors.Is(err, models.PermissionDeniedError) {
+				t.Errorf("authenticateUser error = %v, want %v", err, models.PermissionDeniedError)
+			}
+		})
+	}
+
+	_, err := newTestService(newFakeRepository(), nil).principalFromToken(context.Background(), formatAPIKey("0123456789abcdef", "secret"))
+	if !errors.Is(err, models.UnauthenticatedAccessTokenError) {
+		t.Errorf("unknown key error = %v, want %v", err, models.UnauthenticatedAccessTokenError)
+	}
+}
+
+func TestManageAPIKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions string
+		id          string
+		err         error
+	}{
+		{name: "key with permissions of caller", permissions: models.PermissionUsersLookup, id: "key-1"},
+		{name: "key with permission caller doesn't have", permissions: models.PermissionUsersDelete, id: "key-1", err: models.PermissionDeniedError},
+		{name: "unknown key", permissions: models.PermissionUsersLookup, id: "key-2", err: models.APIKeyNotFoundError},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []string{"RotateAPIKey", "RevokeAPIKey"} {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				db := newFakeRepository()
+				db.roles = append(db.roles, models.Role{ID: 3, Name: "keys"})
+				db.rolePermissions[3] = []string{models.PermissionAPIKeysWrite, models.PermissionUsersLookup}
+				db.apiKeys = []models.APIKey{{ID: "key-1", Prefix: "0123456789abcdef", SecretHash: hashAPIKeySecret("secret"), Permissions: tt.permissions}}
+				caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: 3})
+
+				a := newTestService(db, nil)
+				ctx := userContext(t, a, caller)
+
+				var err error
+				if method == "RotateAPIKey" {
+					_, err = a.RotateAPIKey(ctx, &protos.APIKeyIDRequest{Id: tt.id})
+				} else {
+					_, err = a.RevokeAPIKey(ctx, &protos.APIKeyIDRequest{Id: tt.id})
+				}
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+
+				unchanged := len(db.apiKeys) == 1 && db.apiKeys[0].SecretHash == hashAPIKeySecret("secret")
+				if tt.err != nil && !unchanged {
+					t.Errorf("keys = %+v, want unchanged key", db.apiKeys)
+				}
+				if tt.err == nil && unchanged {
+					t.Errorf("keys = %+v, want rotated or revoked key", db.apiKeys)
+				}
+			})
+		}
+	}
+}
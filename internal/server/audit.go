@@ -0,0 +1,37 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/status"
+	"path"
+)
+
+// audit record call of principal, subject is user of request if request has user id,
+// failure is only logged because call is already done
+func (a *AccountService) audit(principal *models.Principal, fullMethod string, req interface{}, callErr error) {
+	var subjectID string
+	if rr, ok := req.(interface{ GetUserId() string }); ok {
+		subjectID = rr.GetUserId()
+	}
+
+	// oauth clients authenticated by client credentials and api keys have no user
+	actorID := principal.UserID
+	if actorID == "" {
+		actorID = principal.ClientID
+	}
+	if actorID == "" {
+		actorID = principal.APIKeyID
+	}
+
+	err := a.db.AddAuditEntry(&models.AuditEntry{
+		ActorID:    actorID,
+		ActorEmail: principal.Email,
+		SubjectID:  subjectID,
+		Method:     path.Base(fullMethod),
+		Code:       status.Code(callErr).String(),
+	})
+	if err != nil {
+		hclog.Default().Error("[server.audit] a.db.AddAuditEntry", "method", fullMethod, "userID", principal.UserID, "error", err)
+	}
+}
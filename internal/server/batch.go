@@ -0,0 +1,125 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	protos "protos/account"
+)
+
+// maxBatchIDs max ids in one batch lookup request
+const maxBatchIDs = 500
+
+// GetAccountsByIDs return accounts by ids, not existing ids are reported as missing
+func (a *AccountService) GetAccountsByIDs(ctx context.Context, rr *protos.GetAccountsByIDsRequest) (*protos.GetAccountsByIDsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetAccountsByIDs")
+	defer span.End()
+
+	response, err := a.accountsByIDs(rr.GetIds())
+	if err != nil {
+		log.Error("[server.GetAccountsByIDs] a.accountsByIDs", "error", err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// StreamAccountsByIDs return accounts for each batch of ids sent by client
+func (a *AccountService) StreamAccountsByIDs(stream protos.AccountService_StreamAccountsByIDsServer) error {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(stream.Context(), "StreamAccountsByIDs")
+	defer span.End()
+
+	for {
+		rr, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			log.Error("[server.StreamAccountsByIDs] stream.Recv", "error", err)
+			return err
+		}
+
+		response, err := a.accountsByIDs(rr.GetIds())
+		if err != nil {
+			log.Error("[server.StreamAccountsByIDs] a.accountsByIDs", "error", err)
+			return err
+		}
+
+		err = stream.Send(response)
+		if err != nil {
+			log.Error("[server.StreamAccountsByIDs] stream.Send", "error", err)
+			return err
+		}
+	}
+}
+
+// accountsByIDs get accounts by ids with single query, invalid and not existing ids are missing
+func (a *AccountService) accountsByIDs(ids []string) (*protos.GetAccountsByIDsResponse, error) {
+	if len(ids) > maxBatchIDs {
+		return nil, models.TooManyIDsError
+	}
+
+	// ids are normalized because postgres accepts uppercase, braced and urn forms of uuid,
+	// missing ids are reported as they were requested
+	seen := make(map[string]struct{}, len(ids))
+	validIDs := make([]string, 0, len(ids))
+	requestedIDs := make(map[string]string, len(ids))
+	missingIDs := make([]string, 0)
+	for _, id := range ids {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				missingIDs = append(missingIDs, id)
+			}
+			continue
+		}
+
+		normalized := parsed.String()
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		validIDs = append(validIDs, normalized)
+		requestedIDs[normalized] = id
+	}
+
+	var users []models.User
+	if len(validIDs) > 0 {
+		var err error
+		users, err = a.db.GetUsersByIDs(validIDs)
+		if err != nil {
+			hclog.Default().Error("[server.accountsByIDs] a.db.GetUsersByIDs", "error", err)
+			return nil, models.InternalError
+		}
+	}
+
+	found := make(map[string]*models.User, len(users))
+	for i := range users {
+		found[users[i].ID] = &users[i]
+	}
+
+	accounts := make([]*protos.GetAccountInfoResponse, 0, len(users))
+	for _, id := range validIDs {
+		user, ok := found[id]
+		if !ok {
+			missingIDs = append(missingIDs, requestedIDs[id])
+			continue
+		}
+		accounts = append(accounts, userToProto(user))
+	}
+
+	return &protos.GetAccountsByIDsResponse{
+		Accounts:   accounts,
+		MissingIds: missingIDs,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	protos "protos/account"
+)
+
+// ChangePassword change password of logged-in user and revoke all other sessions
+func (a *AccountService) ChangePassword(ctx context.Context, rr *protos.ChangePasswordRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "ChangePassword")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.db.GetUserByID(principal.UserID)
+	if err != nil {
+		log.Error("[server.ChangePassword] a.db.GetUserByID", "userID", principal.UserID, "error", err)
+		return nil, models.InternalError
+	}
+	if user == nil {
+		return nil, models.UserNotFoundError
+	}
+
+	isValid, err := a.hasher.Verify(user.Password, rr.GetCurrentPassword())
+	if err != nil {
+		log.Error("[server.ChangePassword] a.hasher.Verify", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+	if !isValid {
+		return nil, models.NotMatchError
+	}
+
+	password := rr.GetNewPassword()
+	err = a.validatePassword("new_password", password, validators.PasswordContext{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isSame, err := a.hasher.Verify(user.Password, password)
+	if err != nil {
+		log.Error("[server.ChangePassword] a.hasher.Verify", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+	if isSame {
+		return nil, models.PasswordReusedError
+	}
+
+	_, err = a.db.ChangePasswordByID(user.ID, password)
+	if errors.Is(err, models.PasswordReusedError) {
+		return nil, models.PasswordReusedError
+	}
+	if err != nil {
+		log.Error("[server.ChangePassword] a.db.ChangePasswordByID", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, user.ID, principal.Token)
+	if err != nil {
+		log.Error("[server.ChangePassword] a.tokenSrv.RevokeAll", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
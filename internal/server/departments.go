@@ -0,0 +1,295 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+	protos "protos/account"
+)
+
+// CreateDepartment create new department
+func (a *AccountService) CreateDepartment(ctx context.Context, rr *protos.CreateDepartmentRequest) (*protos.Department, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "CreateDepartment")
+	defer span.End()
+
+	name := rr.GetName()
+	err := validators.ValidateName(name)
+	if err != nil {
+		return nil, models.NameNotValidError(err)
+	}
+
+	parentID := rr.GetParentId()
+	if parentID != 0 {
+		parent, err := a.db.GetUserDepartmentByID(parentID)
+		if err != nil || parent == nil {
+			log.Error("[server.CreateDepartment] a.db.GetUserDepartmentByID", "departmentID", parentID, "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	department, err := a.db.AddUserDepartment(name, optionalID(parentID))
+	if errors.Is(err, models.AlreadyExistError) {
+		return nil, models.AlreadyExistError
+	}
+	if err != nil {
+		log.Error("[server.CreateDepartment] a.db.AddUserDepartment", "name", name, "error", err)
+		return nil, models.InternalError
+	}
+
+	return departmentToProto(department), nil
+}
+
+// RenameDepartment change name of department
+func (a *AccountService) RenameDepartment(ctx context.Context, rr *protos.RenameDepartmentRequest) (*protos.Department, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "RenameDepartment")
+	defer span.End()
+
+	name := rr.GetName()
+	err := validators.ValidateName(name)
+	if err != nil {
+		return nil, models.NameNotValidError(err)
+	}
+
+	department, err := a.db.RenameUserDepartment(rr.GetId(), name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.DepartmentNotFoundError
+	}
+	if errors.Is(err, models.AlreadyExistError) {
+		return nil, models.AlreadyExistError
+	}
+	if err != nil {
+		log.Error("[server.RenameDepartment] a.db.RenameUserDepartment", "departmentID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return departmentToProto(department), nil
+}
+
+// DeleteDepartment remove department, users of department are moved to reassign department if it is set
+func (a *AccountService) DeleteDepartment(ctx context.Context, rr *protos.DeleteDepartmentRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "DeleteDepartment")
+	defer span.End()
+
+	departmentID := rr.GetId()
+	reassignID := rr.GetReassignToId()
+	if reassignID == departmentID {
+		return nil, models.BadRequestError
+	}
+
+	if reassignID != 0 {
+		department, err := a.db.GetUserDepartmentByID(reassignID)
+		if err != nil || department == nil {
+			log.Error("[server.DeleteDepartment] a.db.GetUserDepartmentByID", "departmentID", reassignID, "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	err := a.db.RemoveUserDepartment(departmentID, reassignID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.DepartmentNotFoundError
+	}
+	if errors.Is(err, models.DepartmentInUseError) {
+		return nil, models.DepartmentInUseError
+	}
+	if errors.Is(err, models.DepartmentHasChildrenError) {
+		return nil, models.DepartmentHasChildrenError
+	}
+	if err != nil {
+		log.Error("[server.DeleteDepartment] a.db.RemoveUserDepartment", "departmentID", departmentID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetDepartment return department by id
+func (a *AccountService) GetDepartment(ctx context.Context, rr *protos.GetDepartmentRequest) (*protos.Department, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetDepartment")
+	defer span.End()
+
+	department, err := a.db.GetUserDepartmentByID(rr.GetId())
+	if err != nil {
+		log.Error("[server.GetDepartment] a.db.GetUserDepartmentByID", "departmentID", rr.GetId(), "error", err)
+		return nil, models.DepartmentNotFoundError
+	}
+
+	return departmentToProto(department), nil
+}
+
+// ListDepartments return all departments
+func (a *AccountService) ListDepartments(ctx context.Context, _ *emptypb.Empty) (*protos.ListDepartmentsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListDepartments")
+	defer span.End()
+
+	departments, err := a.db.GetUserDepartments()
+	if err != nil {
+		log.Error("[server.ListDepartments] a.db.GetUserDepartments", "error", err)
+		return nil, models.InternalError
+	}
+
+	return departmentsToProto(*departments), nil
+}
+
+// MoveDepartment set parent of department, zero parent makes department a root
+func (a *AccountService) MoveDepartment(ctx context.Context, rr *protos.MoveDepartmentRequest) (*protos.Department, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "MoveDepartment")
+	defer span.End()
+
+	departmentID := rr.GetId()
+	parentID := rr.GetParentId()
+	if parentID == departmentID {
+		return nil, models.DepartmentCycleError
+	}
+
+	if parentID != 0 {
+		parent, err := a.db.GetUserDepartmentByID(parentID)
+		if err != nil || parent == nil {
+			log.Error("[server.MoveDepartment] a.db.GetUserDepartmentByID", "departmentID", parentID, "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	department, err := a.db.MoveUserDepartment(departmentID, optionalID(parentID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.DepartmentNotFoundError
+	}
+	if errors.Is(err, models.DepartmentCycleError) {
+		return nil, models.DepartmentCycleError
+	}
+	if err != nil {
+		log.Error("[server.MoveDepartment] a.db.MoveUserDepartment", "departmentID", departmentID, "parentID", parentID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return departmentToProto(department), nil
+}
+
+// GetDepartmentAncestors return ancestors of department from root
+func (a *AccountService) GetDepartmentAncestors(ctx context.Context, rr *protos.GetDepartmentRequest) (*protos.ListDepartmentsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetDepartmentAncestors")
+	defer span.End()
+
+	departments, err := a.db.GetDepartmentPath(rr.GetId())
+	if err != nil {
+		log.Error("[server.GetDepartmentAncestors] a.db.GetDepartmentPath", "departmentID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+	if len(departments) == 0 {
+		return nil, models.DepartmentNotFoundError
+	}
+
+	// last department of path is requested department
+	return departmentsToProto(departments[:len(departments)-1]), nil
+}
+
+// GetDepartmentDescendants return all descendants of department
+func (a *AccountService) GetDepartmentDescendants(ctx context.Context, rr *protos.GetDepartmentRequest) (*protos.ListDepartmentsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetDepartmentDescendants")
+	defer span.End()
+
+	departments, err := a.db.GetDepartmentSubtree(rr.GetId())
+	if err != nil {
+		log.Error("[server.GetDepartmentDescendants] a.db.GetDepartmentSubtree", "departmentID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	descendants := make([]models.Department, 0, len(departments))
+	found := false
+	for _, department := range departments {
+		if department.ID == rr.GetId() {
+			found = true
+			continue
+		}
+		descendants = append(descendants, department)
+	}
+	if !found {
+		return nil, models.DepartmentNotFoundError
+	}
+
+	return departmentsToProto(descendants), nil
+}
+
+// ListDepartmentUsers return users of department and all its descendants
+func (a *AccountService) ListDepartmentUsers(ctx context.Context, rr *protos.GetDepartmentRequest) (*protos.ListDepartmentUsersResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListDepartmentUsers")
+	defer span.End()
+
+	users, err := a.db.GetDepartmentSubtreeUsers(rr.GetId())
+	if err != nil {
+		log.Error("[server.ListDepartmentUsers] a.db.GetDepartmentSubtreeUsers", "departmentID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.GetAccountInfoResponse, 0, len(users))
+	for i := range users {
+		result = append(result, userToProto(&users[i]))
+	}
+
+	return &protos.ListDepartmentUsersResponse{
+		Users: result,
+	}, nil
+}
+
+func departmentToProto(department *models.Department) *protos.Department {
+	var parentID uint32
+	if department.ParentID != nil {
+		parentID = *department.ParentID
+	}
+
+	return &protos.Department{
+		Id:       department.ID,
+		Name:     department.Name,
+		ParentId: parentID,
+	}
+}
+
+func departmentsToProto(departments []models.Department) *protos.ListDepartmentsResponse {
+	result := make([]*protos.Department, 0, len(departments))
+	for i := range departments {
+		result = append(result, departmentToProto(&departments[i]))
+	}
+
+	return &protos.ListDepartmentsResponse{
+		Departments: result,
+	}
+}
+
+// optionalID nil for zero id
+func optionalID(id uint32) *uint32 {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"comet/utils"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	protos "protos/account"
+)
+
+// RequestErasure erase personal data of account without waiting for retention period,
+// users can request erasure of own account, other accounts require users:delete permission
+func (a *AccountService) RequestErasure(ctx context.Context, rr *protos.AccountIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "RequestErasure")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := rr.GetUserId()
+	if userID == "" {
+		userID = principal.UserID
+	}
+	if userID != principal.UserID && !principal.HasPermissions(models.PermissionUsersDelete) {
+		return nil, models.PermissionDeniedError
+	}
+
+	err = a.db.RequestUserErasure(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RequestErasure] a.db.RequestUserErasure", "userID", userID, "error", err)
+		return nil, models.InternalError
+	}
+
+	a.audit(principal, "RequestErasure", &protos.AccountIDRequest{UserId: userID}, nil)
+
+	err = a.tokenSrv.RevokeAll(ctx, userID, nil)
+	if err != nil {
+		log.Error("[server.RequestErasure] a.tokenSrv.RevokeAll", "userID", userID, "error", err)
+		return nil, models.InternalError
+	}
+
+	a.erasure.Trigger()
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetErasureReport report users which personal data would be erased by next run of retention worker
+func (a *AccountService) GetErasureReport(ctx context.Context, _ *emptypb.Empty) (*protos.ErasureReport, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetErasureReport")
+	defer span.End()
+
+	candidates, err := a.erasure.Report()
+	if err != nil {
+		log.Error("[server.GetErasureReport] a.erasure.Report", "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.ErasureCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, &protos.ErasureCandidate{
+			UserId:             candidate.UserID,
+			Email:              utils.HideEmail(candidate.Email),
+			DeletedAt:          optionalTimestamp(candidate.DeletedAt),
+			ErasureRequestedAt: optionalTimestamp(candidate.ErasureRequestedAt),
+			Tokens:             candidate.Tokens,
+			PasswordHistory:    candidate.PasswordHistory,
+			RoleAssignments:    candidate.RoleAssignments,
+			AuditEntries:       candidate.AuditEntries,
+		})
+	}
+
+	return &protos.ErasureReport{
+		Mode:          a.cfg.ErasureMode,
+		DryRun:        a.cfg.ErasureDryRun,
+		DeletedBefore: timestamppb.New(a.erasure.DeletedBefore()),
+		Candidates:    result,
+	}, nil
+}
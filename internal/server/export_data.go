@@ -0,0 +1,377 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	protos "protos/account"
+	"strings"
+	"time"
+)
+
+// dataChunkSize max size of data in one chunk of stream
+const dataChunkSize = 64 * 1024
+
+// exportedProfile profile of user without password hash
+type exportedProfile struct {
+	ID                 string     `json:"id"`
+	Email              string     `json:"email"`
+	FirstName          string     `json:"first_name"`
+	LastName           string     `json:"last_name"`
+	EmailVerified      bool       `json:"email_verified"`
+	IsRegistered       bool       `json:"is_registered"`
+	DepartmentID       uint32     `json:"department_id"`
+	DepartmentName     string     `json:"department_name"`
+	RoleID             uint32     `json:"role_id"`
+	RoleName           string     `json:"role_name"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty"`
+	ErasureRequestedAt *time.Time `json:"erasure_requested_at,omitempty"`
+}
+
+// exportedSession token issued to user
+type exportedSession struct {
+	ID        string    `json:"id"`
+	Session   string    `json:"session"`
+	Variety   string    `json:"variety"`
+	IsRevoked bool      `json:"is_revoked"`
+	LastUse   time.Time `json:"last_use"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedRoleAssignment role granted to user with role name
+type exportedRoleAssignment struct {
+	RoleID       uint32     `json:"role_id"`
+	RoleName     string     `json:"role_name"`
+	DepartmentID *uint32    `json:"department_id,omitempty"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// exportedConsent access to account allowed to oauth client
+type exportedConsent struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// dataExport all personal data of user held by service
+type dataExport struct {
+	ExportedAt       time.Time                `json:"exported_at"`
+	Profile          exportedProfile          `json:"profile"`
+	RoleAssignments  []exportedRoleAssignment `json:"role_assignments"`
+	Sessions         []exportedSession        `json:"sessions"`
+	Consents         []exportedConsent        `json:"consents"`
+	LinkedIdentities []models.LinkedIdentity  `json:"linked_identities"`
+	AuditEntries     []models.AuditEntry      `json:"audit_entries"`
+}
+
+// ExportMyData stream export of personal data of caller
+func (a *AccountService) ExportMyData(rr *protos.ExportDataRequest, stream protos.AccountService_ExportMyDataServer) error {
+	ctx := stream.Context()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "ExportMyData")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.exportData(principal.UserID, rr.GetFormat(), stream)
+}
+
+// ExportUserData stream export of personal data of any user
+func (a *AccountService) ExportUserData(rr *protos.ExportDataRequest, stream protos.AccountService_ExportUserDataServer) error {
+	tr := a.trace
+	_, span := tr.Start(stream.Context(), "ExportUserData")
+	defer span.End()
+
+	return a.exportData(rr.GetUserId(), rr.GetFormat(), stream)
+}
+
+// dataChunkSender stream of data chunks
+type dataChunkSender interface {
+	Send(*protos.DataChunk) error
+}
+
+// exportData collect personal data of user and send it as json or zip by chunks
+func (a *AccountService) exportData(userID string, format protos.ExportFormat, stream dataChunkSender) error {
+	log := hclog.Default()
+
+	export, err := a.collectData(userID)
+	if err != nil {
+		return err
+	}
+
+	filename, contentType := "personal-data.json", "application/json"
+	if format == protos.ExportFormat_EXPORT_FORMAT_ZIP {
+		filename, contentType = "personal-data.zip", "application/zip"
+	}
+
+	writer := &chunkWriter{
+		send: func(data []byte) error {
+			return stream.Send(&protos.DataChunk{
+				Data:        data,
+				Filename:    filename,
+				ContentType: contentType,
+			})
+		},
+	}
+
+	switch format {
+	case protos.ExportFormat_EXPORT_FORMAT_JSON:
+		err = writeJSON(writer, export)
+	case protos.ExportFormat_EXPORT_FORMAT_ZIP:
+		err = writeZip(writer, export)
+	default:
+		return models.BadRequestError
+	}
+	if err != nil {
+		log.Error("[server.exportData] write export", "userID", userID, "error", err)
+		return models.InternalError
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		log.Error("[server.exportData] writer.Flush", "userID", userID, "error", err)
+		return models.InternalError
+	}
+
+	return nil
+}
+
+// collectData collect personal data of user
+func (a *AccountService) collectData(userID string) (*dataExport, error) {
+	log := hclog.Default()
+
+	// deleted user is exported until its data is erased
+	user, err := a.db.GetRetainedUserByID(userID)
+	if err != nil || user == nil {
+		log.Error("[server.collectData] a.db.GetRetainedUserByID", "userID", userID, "error", err)
+		return nil, models.UserNotFoundError
+	}
+
+	roleNames, err := a.roleNames()
+	if err != nil {
+		log.Error("[server.collectData] a.roleNames", "error", err)
+		return nil, models.InternalError
+	}
+
+	var departmentName string
+	department, err := a.db.GetUserDepartmentByID(user.DepartmentID)
+	if err == nil && department != nil {
+		departmentName = department.Name
+	}
+
+	assignments, err := a.db.GetRoleAssignments(user.ID)
+	if err != nil {
+		log.Error("[server.collectData] a.db.GetRoleAssignments", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	tokens, err := a.db.GetUserTokens(user.ID)
+	if err != nil {
+		log.Error("[server.collectData] a.db.GetUserTokens", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	consents, err := a.db.GetUserConsents(user.ID)
+	if err != nil {
+		log.Error("[server.collectData] a.db.GetUserConsents", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	identities, err := a.db.GetUserLinkedIdentities(user.ID)
+	if err != nil {
+		log.Error("[server.collectData] a.db.GetUserLinkedIdentities", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	auditEntries, err := a.db.GetUserAuditEntries(user.ID)
+	if err != nil {
+		log.Error("[server.collectData] a.db.GetUserAuditEntries", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	export := &dataExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: exportedProfile{
+			ID:                 user.ID,
+			Email:              user.Email,
+			FirstName:          user.FirstName,
+			LastName:           user.LastName,
+			EmailVerified:      user.EmailVerified,
+			IsRegistered:       user.IsRegistered,
+			DepartmentID:       user.DepartmentID,
+			DepartmentName:     departmentName,
+			RoleID:             user.RoleID,
+			RoleName:           roleNames[user.RoleID],
+			CreatedAt:          user.CreatedAt,
+			UpdatedAt:          user.UpdatedAt,
+			DeactivatedAt:      user.DeactivatedAt,
+			ErasureRequestedAt: user.ErasureRequestedAt,
+		},
+		RoleAssignments:  make([]exportedRoleAssignment, 0, len(assignments)),
+		Sessions:         make([]exportedSession, 0, len(tokens)),
+		Consents:         make([]exportedConsent, 0, len(consents)),
+		LinkedIdentities: identities,
+		AuditEntries:     auditEntries,
+	}
+
+	for _, assignment := range assignments {
+		export.RoleAssignments = append(export.RoleAssignments, exportedRoleAssignment{
+			RoleID:       assignment.RoleID,
+			RoleName:     roleNames[assignment.RoleID],
+			DepartmentID: assignment.DepartmentID,
+			StartsAt:     assignment.StartsAt,
+			EndsAt:       assignment.EndsAt,
+			CreatedAt:    assignment.CreatedAt,
+		})
+	}
+
+	for _, token := range tokens {
+		export.Sessions = append(export.Sessions, exportedSession{
+			ID:        token.ID,
+			Session:   token.Session,
+			Variety:   token.Variety,
+			IsRevoked: token.IsRevoked,
+			LastUse:   token.LastUse,
+			CreatedAt: token.CreatedAt,
+		})
+	}
+
+	for _, consent := range consents {
+		export.Consents = append(export.Consents, exportedConsent{
+			ClientID:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     strings.Fields(consent.Scopes),
+			CreatedAt:  consent.CreatedAt,
+			UpdatedAt:  consent.UpdatedAt,
+		})
+	}
+
+	return export, nil
+}
+
+// roleNames names of roles by id
+func (a *AccountService) roleNames() (map[uint32]string, error) {
+	roles, err := a.db.GetUserRoles()
+	if err != nil {
+		return nil, fmt.Errorf("a.db.GetUserRoles error: %w", err)
+	}
+
+	names := make(map[uint32]string, len(*roles))
+	for _, role := range *roles {
+		names[role.ID] = role.Name
+	}
+
+	return names, nil
+}
+
+// writeJSON write export as single json document
+func writeJSON(w *chunkWriter, export *dataExport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(export)
+	if err != nil {
+		return fmt.Errorf("encoder.Encode error: %w", err)
+	}
+
+	return nil
+}
+
+// writeZip write export as zip archive with json file for each section
+func writeZip(w *chunkWriter, export *dataExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"role_assignments.json", export.RoleAssignments},
+		{"sessions.json", export.Sessions},
+		{"consents.json", export.Consents},
+		{"linked_identities.json", export.LinkedIdentities},
+		{"audit_entries.json", export.AuditEntries},
+	}
+
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("archive.CreateHeader error: %w", err)
+		}
+
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return fmt.Errorf("encoder.Encode %s error: %w", file.name, err)
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return fmt.Errorf("archive.Close error: %w", err)
+	}
+
+	return nil
+}
+
+// chunkWriter writer which sends written data by chunks of dataChunkSize
+type chunkWriter struct {
+	send func(data []byte) error
+	buf  []byte
+}
+
+// Write buffer data and send full chunks
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := dataChunkSize - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(w.buf) == dataChunkSize {
+			err := w.Flush()
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// Flush send buffered data
+func (w *chunkWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.send(w.buf)
+	if err != nil {
+		return fmt.Errorf("w.send error: %w", err)
+	}
+	w.buf = make([]byte, 0, dataChunkSize)
+
+	return nil
+}
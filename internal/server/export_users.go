@@ -0,0 +1,66 @@
+package server
+
+import (
+	"account-service/internal/exporter"
+	"account-service/internal/models"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	protos "protos/account"
+)
+
+// ExportUsers stream all accounts matched by listing filter as csv or ndjson, page of filter is ignored
+func (a *AccountService) ExportUsers(rr *protos.ExportUsersRequest, stream protos.AccountService_ExportUsersServer) error {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(stream.Context(), "ExportUsers")
+	defer span.End()
+
+	format, ok := dataFormats[rr.GetFormat()]
+	if !ok {
+		return models.BadRequestError
+	}
+
+	filter, err := accountsFilter(&protos.ListAccountsRequest{
+		Query:                 rr.GetFilter().GetQuery(),
+		DepartmentId:          rr.GetFilter().GetDepartmentId(),
+		IncludeSubdepartments: rr.GetFilter().GetIncludeSubdepartments(),
+		RoleId:                rr.GetFilter().GetRoleId(),
+		IsRegistered:          rr.GetFilter().IsRegistered,
+		EmailVerified:         rr.GetFilter().EmailVerified,
+		CreatedFrom:           rr.GetFilter().GetCreatedFrom(),
+		CreatedTo:             rr.GetFilter().GetCreatedTo(),
+		SortBy:                rr.GetFilter().GetSortBy(),
+		Descending:            rr.GetFilter().GetDescending(),
+	})
+	if err != nil {
+		log.Error("[server.ExportUsers] accountsFilter", "error", err)
+		return err
+	}
+
+	contentType, extension := exporter.ContentType(format)
+	filename := fmt.Sprintf("users.%s", extension)
+	writer := &chunkWriter{
+		send: func(data []byte) error {
+			return stream.Send(&protos.DataChunk{
+				Data:        data,
+				Filename:    filename,
+				ContentType: contentType,
+			})
+		},
+	}
+
+	count, err := exporter.Export(a.db, writer, *filter, format)
+	if err != nil {
+		log.Error("[server.ExportUsers] exporter.Export", "exported", count, "error", err)
+		return models.InternalError
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		log.Error("[server.ExportUsers] writer.Flush", "error", err)
+		return models.InternalError
+	}
+
+	return nil
+}
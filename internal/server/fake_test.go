@@ -0,0 +1,426 @@
+package server
+
+import (
+	"account-service/config"
+	"account-service/internal/directory"
+	"account-service/internal/models"
+	"account-service/internal/server/interfaces"
+	"context"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRepository in-memory repository, methods which are not overridden panic on nil embedded interface
+type fakeRepository struct {
+	interfaces.Repository
+
+	users       []models.User
+	departments []models.Department
+	roles       []models.Role
+	identities  []models.LinkedIdentity
+	audit       []models.AuditEntry
+	apiKeys     []models.APIKey
+	assignments []models.RoleAssignment
+	invitations []models.Invitation
+	imported    []models.ImportRow
+	clients     []models.OAuthClient
+	// rolePermissions permissions of roles by role id
+	rolePermissions map[uint32][]string
+	nextUser        int
+	// emailErr error of lookup of users by email
+	emailErr error
+}
+
+// backendParentID parent of department Backend
+var backendParentID uint32 = 2
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		departments: []models.Department{{ID: 1, Name: "Staff"}, {ID: 2, Name: "Engineering"}, {ID: 3, Name: "Backend", ParentID: &backendParentID}},
+		roles:       []models.Role{{ID: 1, Name: models.EmployeeRole}, {ID: 2, Name: "admin"}},
+		rolePermissions: map[uint32][]string{
+			1: models.BuiltinRoles[models.EmployeeRole],
+			2: models.BuiltinRoles[models.AdminRole],
+		},
+	}
+}
+
+func newTestService(db *fakeRepository, cfg *config.Config) *AccountService {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+
+	return &AccountService{
+		db:     db,
+		hasher: fakeHasher{},
+		trace:  trace.NewNoopTracerProvider().Tracer(""),
+		cfg:    cfg,
+	}
+}
+
+// userContext context of rpc called by user with its effective roles
+func userContext(t *testing.T, a *AccountService, user *models.User) context.Context {
+	t.Helper()
+
+	principal, err := a.principalForUser(user)
+	if err != nil {
+		t.Fatalf("principalForUser error: %v", err)
+	}
+
+	return context.WithValue(context.Background(), principalKey{}, principal)
+}
+
+// addUser add existing user with generated id
+func (r *fakeRepository) addUser(user models.User) *models.User {
+	r.nextUser++
+	user.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", r.nextUser)
+	r.users = append(r.users, user)
+
+	return &r.users[len(r.users)-1]
+}
+
+func (r *fakeRepository) GetUserByID(id string) (*models.User, error) {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			return &r.users[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserByEmail(email string) (*models.User, error) {
+	if r.emailErr != nil {
+		return nil, r.emailErr
+	}
+	for i := range r.users {
+		if r.users[i].Email == email {
+			return &r.users[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) CreateUserWithIdentity(user *models.User, identity *models.LinkedIdentity) (*models.User, error) {
+	user = r.addUser(*user)
+	identity.UserID = user.ID
+	r.identities = append(r.identities, *identity)
+
+	return user, nil
+}
+
+func (r *fakeRepository) AddLinkedIdentity(identity *models.LinkedIdentity) (*models.LinkedIdentity, error) {
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, *identity)
+
+	return identity, nil
+}
+
+func (r *fakeRepository) AddAuditEntry(entry *models.AuditEntry) error {
+	r.audit = append(r.audit, *entry)
+	return nil
+}
+
+func (r *fakeRepository) GetUserDepartmentByName(name string) (*models.Department, error) {
+	for i := range r.departments {
+		if r.departments[i].Name == name {
+			return &r.departments[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserRoleByName(name string) (*models.Role, error) {
+	for i := range r.roles {
+		if r.roles[i].Name == name {
+			return &r.roles[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetUserDepartmentByID(id uint32) (*models.Department, error) {
+	for i := range r.departments {
+		if r.departments[i].ID == id {
+			return &r.departments[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) GetDepartmentPath(id uint32) ([]models.Department, error) {
+	var path []models.Department
+	for {
+		department, err := r.GetUserDepartmentByID(id)
+		if err != nil {
+			return path, nil
+		}
+		path = append([]models.Department{*department}, path...)
+		if department.ParentID == nil {
+			return path, nil
+		}
+		id = *department.ParentID
+	}
+}
+
+func (r *fakeRepository) GetUserRoleByID(id uint32) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.ID == id {
+			for _, permission := range r.rolePermissions[id] {
+				role.Permissions = append(role.Permissions, models.Permission{Name: permission})
+			}
+			return &role, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) SetRolePermissions(id uint32, permissions []string) (*models.Role, error) {
+	r.rolePermissions[id] = permissions
+	return r.GetUserRoleByID(id)
+}
+
+func (r *fakeRepository) GetUserRoles() (*[]models.Role, error) {
+	roles := append([]models.Role(nil), r.roles...)
+	return &roles, nil
+}
+
+func (r *fakeRepository) GetRolesPermissions(roleIDs []uint32) (map[uint32][]string, error) {
+	permissions := make(map[uint32][]string, len(roleIDs))
+	for _, id := range roleIDs {
+		permissions[id] = r.rolePermissions[id]
+	}
+
+	return permissions, nil
+}
+
+func (r *fakeRepository) GetActiveRoleAssignments(userID string, moment time.Time) ([]models.RoleAssignment, error) {
+	var result []models.RoleAssignment
+	for _, assignment := range r.assignments {
+		if assignment.UserID == userID && assignment.IsActive(moment) {
+			result = append(result, assignment)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fakeRepository) AddRoleAssignment(assignment *models.RoleAssignment) (*models.RoleAssignment, error) {
+	assignment.ID = uint32(len(r.assignments) + 1)
+	r.assignments = append(r.assignments, *assignment)
+
+	return assignment, nil
+}
+
+func (r *fakeRepository) EmailExist(email string) (bool, error) {
+	_, err := r.GetUserByEmail(email)
+	return err == nil, nil
+}
+
+func (r *fakeRepository) PendingInvitationExist(email string) (bool, error) {
+	for _, invitation := range r.invitations {
+		if invitation.Email == email && invitation.Status == models.InvitationPending {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *fakeRepository) AddInvitation(invitation *models.Invitation) (*models.Invitation, error) {
+	invitation.ID = fmt.Sprintf("10000000-0000-0000-0000-%012d", len(r.invitations)+1)
+	r.invitations = append(r.invitations, *invitation)
+
+	return invitation, nil
+}
+
+func (r *fakeRepository) ImportUsers(rows []models.ImportRow, options models.ImportOptions) (*models.ImportReport, error) {
+	r.imported = append(r.imported, rows...)
+	return &models.ImportReport{DryRun: options.DryRun, Created: len(rows), Committed: !options.DryRun}, nil
+}
+
+func (r *fakeRepository) GetOAuthClientByID(id string) (*models.OAuthClient, error) {
+	for i := range r.clients {
+		if r.clients[i].ID == id {
+			return &r.clients[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) UpdateOAuthClientSecret(id, secretHash string) (*models.OAuthClient, error) {
+	client, err := r.GetOAuthClientByID(id)
+	if err != nil {
+		return nil, err
+	}
+	client.SecretHash = secretHash
+
+	return client, nil
+}
+
+func (r *fakeRepository) RemoveOAuthClient(id string) error {
+	for i := range r.clients {
+		if r.clients[i].ID == id {
+			r.clients = append(r.clients[:i], r.clients[i+1:]...)
+			return nil
+		}
+	}
+
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) CreateUser(user *models.User) (*models.User, error) {
+	return r.addUser(*user), nil
+}
+
+func (r *fakeRepository) UpdateUserByID(id string, fields map[string]interface{}, _ time.Time) (*models.User, error) {
+	user, err := r.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	for column, value := range fields {
+		switch column {
+		case "first_name":
+			user.FirstName = value.(string)
+		case "last_name":
+			user.LastName = value.(string)
+		case "department_id":
+			user.DepartmentID = value.(uint32)
+		case "role_id":
+			user.RoleID = value.(uint32)
+		}
+	}
+
+	result := *user
+	return &result, nil
+}
+
+// fakeHasher hash is password with prefix, hash without prefix can't be verified
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(password string) (string, error) {
+	return "hash:" + password, nil
+}
+
+func (fakeHasher) Verify(hash, password string) (bool, error) {
+	if !strings.HasPrefix(hash, "hash:") {
+		return false, fmt.Errorf("unknown hash format")
+	}
+
+	return hash == "hash:"+password, nil
+}
+
+func (fakeHasher) NeedsRehash(string) bool {
+	return false
+}
+
+// fakeDirectory directory with accounts by email and password, calls are counted
+type fakeDirectory struct {
+	accounts map[string]models.DirectoryAccount
+	password string
+	err      error
+	calls    int
+}
+
+func (d *fakeDirectory) Authenticate(_ context.Context, email, password string) (*models.DirectoryAccount, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	account, ok := d.accounts[email]
+	if !ok {
+		return nil, directory.ErrUserNotFound
+	}
+	if password != d.password {
+		return nil, directory.ErrInvalidCredentials
+	}
+
+	return &account, nil
+}
+
+func (r *fakeRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	for i := range r.apiKeys {
+		if r.apiKeys[i].Prefix == prefix {
+			return &r.apiKeys[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) SetAPIKeyUse(id string, moment time.Time) error {
+	for i := range r.apiKeys {
+		if r.apiKeys[i].ID == id {
+			r.apiKeys[i].LastUsedAt = &moment
+		}
+	}
+
+	return nil
+}
+
+// fakeNotifier enabled notifier keeping sent messages
+type fakeNotifier struct {
+	messages []interface{}
+}
+
+func (n *fakeNotifier) Notify(_ string, message interface{}) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func (n *fakeNotifier) Enabled() bool {
+	return true
+}
+
+// fakeTokens token service issuing unsigned tokens, methods which are not overridden panic on nil embedded interface
+type fakeTokens struct {
+	interfaces.TokenService
+}
+
+func (fakeTokens) NewJWT(_ context.Context, variety string, identity string, username string, extra jwt.MapClaims) (*models.JWT, error) {
+	return &models.JWT{Variety: variety, Identity: identity, Email: username, Extra: extra}, nil
+}
+
+func (r *fakeRepository) GetAPIKeyByID(id string) (*models.APIKey, error) {
+	for i := range r.apiKeys {
+		if r.apiKeys[i].ID == id {
+			return &r.apiKeys[i], nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) UpdateAPIKeySecret(id, secretHash string) (*models.APIKey, error) {
+	key, err := r.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	key.SecretHash = secretHash
+
+	return key, nil
+}
+
+func (r *fakeRepository) RemoveAPIKey(id string) error {
+	for i := range r.apiKeys {
+		if r.apiKeys[i].ID == id {
+			r.apiKeys = append(r.apiKeys[:i], r.apiKeys[i+1:]...)
+			return nil
+		}
+	}
+
+	return gorm.ErrRecordNotFound
+}
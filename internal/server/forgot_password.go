@@ -5,6 +5,7 @@ import (
 	"account-service/internal/validators"
 	"comet/utils"
 	"context"
+	"errors"
 	"github.com/golang-jwt/jwt"
 	"github.com/hashicorp/go-hclog"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -80,13 +81,29 @@ func (a *AccountService) ResetPassword(ctx context.Context, rr *protos.ResetPass
 		return nil, models.InvalidResetPasswordError
 	}
 
+	user, err := a.db.GetUserByID(tok.Identity)
+	if err != nil {
+		log.Error("[server.ResetPassword] a.db.GetUserByID", "userID", tok.Identity, "error", err)
+		return nil, models.InternalError
+	}
+	if user == nil {
+		return nil, models.UserNotFoundError
+	}
+
 	password := rr.GetNewPassword()
-	err = validators.ValidatePassword(password)
+	err = a.validatePassword("new_password", password, validators.PasswordContext{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	})
 	if err != nil {
-		return nil, models.PasswordNotValidError(err)
+		return nil, err
 	}
 
-	user, err := a.db.ChangePasswordByID(tok.Identity, password)
+	user, err = a.db.ChangePasswordByID(tok.Identity, password)
+	if errors.Is(err, models.PasswordReusedError) {
+		return nil, models.PasswordReusedError
+	}
 	if err != nil {
 		log.Error("[server.ResetPassword] a.db.ChangePasswordByID", "userID", tok.Identity, "error", err)
 		return nil, models.InternalError
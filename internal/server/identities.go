@@ -0,0 +1,314 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	protos "protos/account"
+	"time"
+)
+
+// ListIdentityProviders return external identity providers users can log in with
+func (a *AccountService) ListIdentityProviders(ctx context.Context, _ *emptypb.Empty) (*protos.ListIdentityProvidersResponse, error) {
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListIdentityProviders")
+	defer span.End()
+
+	providers := a.identity.Providers()
+	result := make([]*protos.IdentityProvider, 0, len(providers))
+	for _, provider := range providers {
+		result = append(result, &protos.IdentityProvider{
+			Name:        provider.Name,
+			DisplayName: provider.DisplayName,
+			Issuer:      provider.Issuer,
+			ClientId:    provider.ClientID,
+			Scopes:      provider.Scopes,
+		})
+	}
+
+	return &protos.ListIdentityProvidersResponse{
+		Providers: result,
+	}, nil
+}
+
+// LoginWithIdentityProvider login user by id token of external provider, user is provisioned on first login
+// unless account with same email exists and provider is not trusted to link it
+func (a *AccountService) LoginWithIdentityProvider(ctx context.Context, rr *protos.IdentityProviderLoginRequest) (*protos.LoginUserResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "LoginWithIdentityProvider")
+	defer span.End()
+
+	provider, identity, err := a.verifyIdentity(ctx, rr)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	linked, err := a.db.GetLinkedIdentity(identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		user, err = a.db.GetUserByID(linked.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.UserNotFoundError
+		}
+		if err != nil {
+			log.Error("[server.LoginWithIdentityProvider] a.db.GetUserByID", "userID", linked.UserID, "error", err)
+			return nil, models.InternalError
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user, linked, err = a.linkOrProvision(provider, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		log.Error("[server.LoginWithIdentityProvider] a.db.GetLinkedIdentity", "provider", identity.Provider, "error", err)
+		return nil, models.InternalError
+	}
+
+	if user.IsDeactivated() {
+		return nil, models.AccountDeactivatedError
+	}
+
+	err = a.db.SetLinkedIdentityLogin(linked.ID, time.Now())
+	if err != nil {
+		log.Error("[server.LoginWithIdentityProvider] a.db.SetLinkedIdentityLogin", "identityID", linked.ID, "error", err)
+	}
+
+	return a.loginResponse(ctx, user)
+}
+
+// LinkIdentity link external identity to account of caller
+func (a *AccountService) LinkIdentity(ctx context.Context, rr *protos.IdentityProviderLoginRequest) (*protos.LinkedIdentity, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "LinkIdentity")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, identity, err := a.verifyIdentity(ctx, rr)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := a.db.AddLinkedIdentity(&models.LinkedIdentity{
+		UserID:   principal.UserID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, models.IdentityAlreadyLinkedError
+	}
+	if err != nil {
+		log.Error("[server.LinkIdentity] a.db.AddLinkedIdentity", "userID", principal.UserID, "provider", identity.Provider, "error", err)
+		return nil, models.InternalError
+	}
+	a.auditIdentity("LinkIdentity", identity.Provider, principal.UserID)
+
+	return linkedIdentityToProto(linked), nil
+}
+
+// ListMyIdentities return external identities linked to account of caller
+func (a *AccountService) ListMyIdentities(ctx context.Context, _ *emptypb.Empty) (*protos.ListLinkedIdentitiesResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "ListMyIdentities")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := a.db.GetUserLinkedIdentities(principal.UserID)
+	if err != nil {
+		log.Error("[server.ListMyIdentities] a.db.GetUserLinkedIdentities", "userID", principal.UserID, "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.LinkedIdentity, 0, len(identities))
+	for i := range identities {
+		result = append(result, linkedIdentityToProto(&identities[i]))
+	}
+
+	return &protos.ListLinkedIdentitiesResponse{
+		Identities: result,
+	}, nil
+}
+
+// UnlinkIdentity remove external identity from account of caller,
+// last identity of account without password can't be removed
+func (a *AccountService) UnlinkIdentity(ctx context.Context, rr *protos.UnlinkIdentityRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "UnlinkIdentity")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.db.GetUserByID(principal.UserID)
+	if err != nil {
+		log.Error("[server.UnlinkIdentity] a.db.GetUserByID", "userID", principal.UserID, "error", err)
+		return nil, models.InternalError
+	}
+
+	if user.Password == "" {
+		identities, err := a.db.GetUserLinkedIdentities(user.ID)
+		if err != nil {
+			log.Error("[server.UnlinkIdentity] a.db.GetUserLinkedIdentities", "userID", user.ID, "error", err)
+			return nil, models.InternalError
+		}
+		if len(identities) <= 1 {
+			return nil, models.LastLoginMethodError
+		}
+	}
+
+	err = a.db.RemoveLinkedIdentity(user.ID, uint(rr.GetId()))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.LinkedIdentityNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.UnlinkIdentity] a.db.RemoveLinkedIdentity", "userID", user.ID, "identityID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// verifyIdentity verify id token of login request by configured provider
+func (a *AccountService) verifyIdentity(ctx context.Context, rr *protos.IdentityProviderLoginRequest) (*models.IdentityProvider, *models.ExternalIdentity, error) {
+	log := hclog.Default()
+
+	provider, err := a.identity.Provider(rr.GetProvider())
+	if err != nil {
+		return nil, nil, models.IdentityProviderNotFoundError
+	}
+
+	identity, err := a.identity.Verify(ctx, provider.Name, rr.GetIdToken(), rr.GetNonce())
+	if err != nil {
+		log.Error("[server.verifyIdentity] a.identity.Verify", "provider", provider.Name, "error", err)
+		return nil, nil, models.InvalidIdentityTokenError
+	}
+
+	return provider, identity, nil
+}
+
+// linkOrProvision link identity to account with same email if provider is trusted to do it,
+// otherwise create user in default department and role of provider
+func (a *AccountService) linkOrProvision(provider *models.IdentityProvider, identity *models.ExternalIdentity) (*models.User, *models.LinkedIdentity, error) {
+	log := hclog.Default()
+
+	err := validators.ValidateEmail(identity.Email)
+	if err != nil {
+		log.Error("[server.linkOrProvision] validators.ValidateEmail", "provider", provider.Name, "error", err)
+		return nil, nil, models.EmailNotValidError
+	}
+
+	linked := &models.LinkedIdentity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	user, err := a.db.GetUserByEmail(identity.Email)
+	if err == nil {
+		if !provider.LinkByEmail || !identity.EmailVerified {
+			return nil, nil, models.IdentityNotLinkedError
+		}
+
+		linked.UserID = user.ID
+		linked, err = a.db.AddLinkedIdentity(linked)
+		if err != nil {
+			log.Error("[server.linkOrProvision] a.db.AddLinkedIdentity", "userID", user.ID, "provider", provider.Name, "error", err)
+			return nil, nil, models.InternalError
+		}
+		a.auditIdentity("LinkIdentity", provider.Name, user.ID)
+
+		return user, linked, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("[server.linkOrProvision] a.db.GetUserByEmail", "error", err)
+		return nil, nil, models.InternalError
+	}
+
+	if validators.ValidateFIO(identity.FirstName) != nil || validators.ValidateFIO(identity.LastName) != nil {
+		log.Error("[server.linkOrProvision] validators.ValidateFIO", "provider", provider.Name, "subject", identity.Subject)
+		return nil, nil, models.FioNotValidError
+	}
+
+	department, err := a.db.GetUserDepartmentByName(provider.DefaultDepartment)
+	if err != nil {
+		log.Error("[server.linkOrProvision] a.db.GetUserDepartmentByName", "name", provider.DefaultDepartment, "error", err)
+		return nil, nil, models.DepartmentNotFoundError
+	}
+
+	role, err := a.db.GetUserRoleByName(provider.DefaultRole)
+	if err != nil {
+		log.Error("[server.linkOrProvision] a.db.GetUserRoleByName", "name", provider.DefaultRole, "error", err)
+		return nil, nil, models.RoleNotFoundError
+	}
+
+	user, err = a.db.CreateUserWithIdentity(&models.User{
+		FirstName:     identity.FirstName,
+		LastName:      identity.LastName,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		DepartmentID:  department.ID,
+		RoleID:        role.ID,
+		IsRegistered:  true,
+	}, linked)
+	if err != nil {
+		log.Error("[server.linkOrProvision] a.db.CreateUserWithIdentity", "provider", provider.Name, "error", err)
+		return nil, nil, models.InternalError
+	}
+	a.auditIdentity("ProvisionUser", provider.Name, user.ID)
+
+	return user, linked, nil
+}
+
+// auditIdentity record change of account made by login with external provider,
+// failure is only logged because change is already done
+func (a *AccountService) auditIdentity(method, provider, userID string) {
+	err := a.db.AddAuditEntry(&models.AuditEntry{
+		ActorID:    userID,
+		ActorEmail: "idp:" + provider,
+		SubjectID:  userID,
+		Method:     "federation." + method,
+		Code:       "OK",
+	})
+	if err != nil {
+		hclog.Default().Error("[server.auditIdentity] a.db.AddAuditEntry", "method", method, "userID", userID, "error", err)
+	}
+}
+
+func linkedIdentityToProto(identity *models.LinkedIdentity) *protos.LinkedIdentity {
+	result := &protos.LinkedIdentity{
+		Id:        uint64(identity.ID),
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: timestamppb.New(identity.CreatedAt),
+	}
+	if identity.LastLoginAt != nil {
+		result.LastLoginAt = timestamppb.New(*identity.LastLoginAt)
+	}
+
+	return result
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestLinkOrProvision(t *testing.T) {
+	provider := &models.IdentityProvider{
+		Name:              "idp",
+		DefaultDepartment: "Engineering",
+		DefaultRole:       models.EmployeeRole,
+	}
+	linkingProvider := *provider
+	linkingProvider.LinkByEmail = true
+
+	identity := models.ExternalIdentity{
+		Provider:      "idp",
+		Subject:       "subject-1",
+		Email:         "bob@example.com",
+		EmailVerified: true,
+		FirstName:     "Bob",
+		LastName:      "Smith",
+	}
+
+	tests := []struct {
+		name      string
+		provider  *models.IdentityProvider
+		change    func(identity *models.ExternalIdentity)
+		existing  bool
+		err       error
+		provision bool
+	}{
+		{name: "link verified email", provider: &linkingProvider, existing: true},
+		{
+			name:     "unverified email isn't linked",
+			provider: &linkingProvider,
+			change:   func(i *models.ExternalIdentity) { i.EmailVerified = false },
+			existing: true,
+			err:      models.IdentityNotLinkedError,
+		},
+		{name: "provider isn't trusted to link", provider: provider, existing: true, err: models.IdentityNotLinkedError},
+		{name: "provision new user", provider: provider, provision: true},
+		{
+			name:      "provision user with unverified email",
+			provider:  provider,
+			change:    func(i *models.ExternalIdentity) { i.EmailVerified = false },
+			provision: true,
+		},
+		{
+			name:     "invalid email",
+			provider: provider,
+			change:   func(i *models.ExternalIdentity) { i.Email = "bob" },
+			err:      models.EmailNotValidError,
+		},
+		{
+			name:     "invalid name",
+			provider: provider,
+			change:   func(i *models.ExternalIdentity) { i.FirstName = "" },
+			err:      models.FioNotValidError,
+		},
+		{
+			name: "unknown default department",
+			provider: &models.IdentityProvider{
+				Name: "idp", DefaultDepartment: "Sales", DefaultRole: models.EmployeeRole,
+			},
+			err: models.DepartmentNotFoundError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			a := newTestService(db, nil)
+
+			var existing *models.User
+			if tt.existing {
+				existing = db.addUser(models.User{Email: "bob@example.com", FirstName: "Robert", LastName: "Smith", DepartmentID: 1, RoleID: 1})
+			}
+
+			external := identity
+			if tt.change != nil {
+				tt.change(&external)
+			}
+
+			user, linked, err := a.linkOrProvision(tt.provider, &external)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(db.identities) != 0 {
+					t.Errorf("identities = %+v, want none", db.identities)
+				}
+				return
+			}
+
+			if len(db.identities) != 1 || linked.UserID != user.ID || linked.Subject != "subject-1" {
+				t.Fatalf("linked = %+v, identities %+v", linked, db.identities)
+			}
+
+			if tt.provision {
+				if len(db.users) != 1 || user.DepartmentID != 2 || user.RoleID != 1 || !user.IsRegistered ||
+					user.EmailVerified != external.EmailVerified {
+					t.Errorf("provisioned user = %+v", user)
+				}
+				return
+			}
+
+			if user.ID != existing.ID || user.FirstName != "Robert" || len(db.users) != 1 {
+				t.Errorf("linked user = %+v, want existing user", user)
+			}
+		})
+	}
+}
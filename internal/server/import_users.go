@@ -0,0 +1,185 @@
+package server
+
+import (
+	"account-service/internal/importer"
+	"account-service/internal/models"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	protos "protos/account"
+)
+
+// maxImportSize max size of import data sent by client
+const maxImportSize = 32 * 1024 * 1024
+
+// errImportTooLarge import data is larger than maxImportSize
+var errImportTooLarge = fmt.Errorf("import data is larger than %d bytes", maxImportSize)
+
+// dataFormats import formats by proto format
+var dataFormats = map[protos.DataFormat]string{
+	protos.DataFormat_DATA_FORMAT_CSV:    models.FormatCSV,
+	protos.DataFormat_DATA_FORMAT_NDJSON: models.FormatNDJSON,
+}
+
+// ImportUsers create users of csv or ndjson rows sent by chunks, options are taken from first message,
+// caller must hold permissions of roles of imported users
+func (a *AccountService) ImportUsers(stream protos.AccountService_ImportUsersServer) error {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(stream.Context(), "ImportUsers")
+	defer span.End()
+
+	rr, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return models.BadRequestError
+	}
+	if err != nil {
+		log.Error("[server.ImportUsers] stream.Recv", "error", err)
+		return err
+	}
+
+	format, ok := dataFormats[rr.GetOptions().GetFormat()]
+	if !ok {
+		return models.BadRequestError
+	}
+
+	principal, err := a.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	// missing departments and roles are created as by department and role management
+	if rr.GetOptions().GetCreateMissing() && !principal.HasPermissions(models.PermissionDepartmentsWrite, models.PermissionRolesWrite) {
+		log.Error("[server.ImportUsers] principal.HasPermissions", "userID", principal.UserID, "createMissing", true)
+		return models.PermissionDeniedError
+	}
+
+	reader := &importReader{stream: stream, buf: rr.GetData(), size: len(rr.GetData())}
+	report, err := importer.Import(&authorizedImport{a: a, principal: principal}, reader, models.ImportOptions{
+		Format:        format,
+		DryRun:        rr.GetOptions().GetDryRun(),
+		CreateMissing: rr.GetOptions().GetCreateMissing(),
+		Atomic:        rr.GetOptions().GetAtomic(),
+	})
+	if reader.err != nil {
+		if errors.Is(reader.err, errImportTooLarge) {
+			return models.BadRequestError
+		}
+		log.Error("[server.ImportUsers] stream.Recv", "error", reader.err)
+		return reader.err
+	}
+	if errors.Is(err, models.PermissionDeniedError) {
+		return models.PermissionDeniedError
+	}
+	if errors.Is(err, importer.ErrMalformed) {
+		log.Error("[server.ImportUsers] importer.Import", "error", err)
+		return models.BadRequestError
+	}
+	if err != nil {
+		log.Error("[server.ImportUsers] importer.Import", "error", err)
+		return models.InternalError
+	}
+
+	response := &protos.ImportUsersResponse{
+		Results:            make([]*protos.ImportRowResult, 0, len(report.Results)),
+		Created:            int32(report.Created),
+		Failed:             int32(report.Failed),
+		CreatedDepartments: report.CreatedDepartments,
+		CreatedRoles:       report.CreatedRoles,
+		DryRun:             report.DryRun,
+		Committed:          report.Committed,
+	}
+	for _, result := range report.Results {
+		response.Results = append(response.Results, &protos.ImportRowResult{
+			Line:   int32(result.Line),
+			Email:  result.Email,
+			Status: result.Status,
+			Error:  result.Error,
+			UserId: result.UserID,
+		})
+	}
+
+	err = stream.SendAndClose(response)
+	if err != nil {
+		log.Error("[server.ImportUsers] stream.SendAndClose", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// authorizedImport repository of import which checks caller holds permissions of roles of imported users,
+// so users can't be imported with role caller can't grant
+type authorizedImport struct {
+	a         *AccountService
+	principal *models.Principal
+}
+
+// ImportUsers check roles of valid rows and create users, missing roles are created without permissions
+func (i *authorizedImport) ImportUsers(rows []models.ImportRow, options models.ImportOptions) (*models.ImportReport, error) {
+	roles, err := i.a.db.GetUserRoles()
+	if err != nil {
+		return nil, fmt.Errorf("i.a.db.GetUserRoles error: %w", err)
+	}
+
+	roleIDs := make(map[string]uint32, len(*roles))
+	for _, role := range *roles {
+		roleIDs[role.Name] = role.ID
+	}
+
+	checked := make(map[uint32]bool)
+	for _, row := range rows {
+		roleID, ok := roleIDs[row.Role]
+		if row.Error != "" || !ok || checked[roleID] {
+			continue
+		}
+		checked[roleID] = true
+
+		err = i.a.authorizeRole(i.principal, roleID, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return i.a.db.ImportUsers(rows, options)
+}
+
+// importReader reader of data of import stream messages, stream error is kept to distinguish it from parse errors
+type importReader struct {
+	stream protos.AccountService_ImportUsersServer
+	buf    []byte
+	size   int
+	err    error
+}
+
+// Read read buffered data and receive next message when buffer is empty
+func (r *importReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		rr, err := r.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+
+		r.size += len(rr.GetData())
+		if r.size > maxImportSize {
+			r.err = errImportTooLarge
+			return 0, r.err
+		}
+		r.buf = rr.GetData()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
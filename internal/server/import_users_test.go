@@ -0,0 +1,90 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"io"
+	protos "protos/account"
+	"testing"
+)
+
+// fakeImportStream import stream sending one message with data
+type fakeImportStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*protos.ImportUsersRequest
+	response *protos.ImportUsersResponse
+}
+
+func (s *fakeImportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeImportStream) Recv() (*protos.ImportUsersRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	request := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return request, nil
+}
+
+func (s *fakeImportStream) SendAndClose(response *protos.ImportUsersResponse) error {
+	s.response = response
+	return nil
+}
+
+func TestImportUsersRoles(t *testing.T) {
+	tests := []struct {
+		name          string
+		callerRole    uint32
+		role          string
+		createMissing bool
+		err           error
+	}{
+		{name: "admin imports admin", callerRole: 2, role: "admin"},
+		{name: "importer imports role with its permissions", callerRole: 3, role: models.EmployeeRole},
+		{name: "importer can't import admin", callerRole: 3, role: "admin", err: models.PermissionDeniedError},
+		{name: "importer can't create missing roles", callerRole: 3, role: "auditor", createMissing: true, err: models.PermissionDeniedError},
+		{name: "admin creates missing roles", callerRole: 2, role: "auditor", createMissing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "importer"})
+			db.rolePermissions[3] = []string{models.PermissionUsersImport, models.PermissionDepartmentsRead, models.PermissionRolesRead}
+			caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: tt.callerRole})
+
+			a := newTestService(db, nil)
+
+			data := "email,first_name,last_name,department,role\nbob@example.com,Bob,Smith,Staff," + tt.role + "\n"
+			stream := &fakeImportStream{
+				ctx: userContext(t, a, caller),
+				requests: []*protos.ImportUsersRequest{{
+					Options: &protos.ImportOptions{Format: protos.DataFormat_DATA_FORMAT_CSV, CreateMissing: tt.createMissing},
+					Data:    []byte(data),
+				}},
+			}
+
+			err := a.ImportUsers(stream)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(db.imported) != 0 {
+					t.Errorf("imported = %+v, want none", db.imported)
+				}
+				return
+			}
+
+			if len(db.imported) != 1 || db.imported[0].Role != tt.role || stream.response.GetCreated() != 1 {
+				t.Errorf("imported = %+v, response %+v", db.imported, stream.response)
+			}
+		})
+	}
+}
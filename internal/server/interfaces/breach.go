@@ -0,0 +1,6 @@
+package interfaces
+
+// BreachChecker breached passwords checker interface
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"account-service/internal/models"
+	"context"
+)
+
+// Directory LDAP or Active Directory authenticator interface
+type Directory interface {
+	Authenticate(ctx context.Context, email, password string) (*models.DirectoryAccount, error)
+}
@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"account-service/internal/models"
+	"time"
+)
+
+// ErasureWorker personal data erasure worker interface
+type ErasureWorker interface {
+	Trigger()
+	Report() ([]models.ErasureCandidate, error)
+	DeletedBefore() time.Time
+}
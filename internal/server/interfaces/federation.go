@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"account-service/internal/models"
+	"context"
+)
+
+// IdentityFederation external identity providers interface
+type IdentityFederation interface {
+	Providers() []models.IdentityProvider
+	Provider(name string) (*models.IdentityProvider, error)
+	Verify(ctx context.Context, provider, idToken, nonce string) (*models.ExternalIdentity, error)
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+// PasswordHasher password hashing interface
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+// Notifier notification publisher interface
+type Notifier interface {
+	Notify(subject string, message interface{}) error
+	Enabled() bool
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "account-service/internal/policy"
+
+// PolicyEngine attribute based policy engine interface
+type PolicyEngine interface {
+	Evaluate(req policy.Request, explain bool) policy.Decision
+}
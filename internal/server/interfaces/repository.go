@@ -1,21 +1,78 @@
 package interfaces
 
-import "account-service/internal/models"
+import (
+	"account-service/internal/models"
+	"time"
+)
 
 type Repository interface {
+	CreateUserIfNotExist(email, firstName, lastName, password string, departmentID, roleID uint32) (*models.User, error)
 	GetUserByID(id string) (*models.User, error)
+	GetUsersByIDs(ids []string) ([]models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	UpdatePasswordHash(id, oldHash, newHash string) error
 	ChangePasswordByID(id, password string) (*models.User, error)
+	UpdateUserByID(id string, fields map[string]interface{}, expectedUpdatedAt time.Time) (*models.User, error)
 	EmailExist(email string) (bool, error)
+	ListUsers(filter models.UserFilter) ([]models.User, error)
+	StreamUsers(filter models.UserFilter, fn func(user *models.ExportedUser) error) error
 	RemoveUserByID(id string) error
-	AddUserDepartment(name string) (*models.Department, error)
-	RemoveUserDepartment(id uint32) error
+	RestoreUserByID(id string) (*models.User, error)
+	SetUserDeactivated(id string, deactivated bool) (*models.User, error)
+	RequestUserErasure(id string) error
+	AddAuditEntry(entry *models.AuditEntry) error
+	GetRetainedUserByID(id string) (*models.User, error)
+	GetUserTokens(userID string) ([]models.Token, error)
+	GetUserAuditEntries(userID string) ([]models.AuditEntry, error)
+	AddInvitation(invitation *models.Invitation) (*models.Invitation, error)
+	GetInvitationByID(id string) (*models.Invitation, error)
+	GetInvitations(status string) ([]models.Invitation, error)
+	PendingInvitationExist(email string) (bool, error)
+	UpdatePendingInvitation(id string, fields map[string]interface{}) (*models.Invitation, error)
+	ImportUsers(rows []models.ImportRow, options models.ImportOptions) (*models.ImportReport, error)
+	AddUserDepartment(name string, parentID *uint32) (*models.Department, error)
+	RenameUserDepartment(id uint32, name string) (*models.Department, error)
+	RemoveUserDepartment(id, reassignID uint32) error
 	GetUserDepartmentByID(id uint32) (*models.Department, error)
+	GetUserDepartmentByName(name string) (*models.Department, error)
 	GetUserDepartments() (*[]models.Department, error)
+	MoveUserDepartment(id uint32, parentID *uint32) (*models.Department, error)
+	GetDepartmentPath(id uint32) ([]models.Department, error)
+	GetDepartmentSubtree(id uint32) ([]models.Department, error)
+	GetDepartmentSubtreeUsers(id uint32) ([]models.User, error)
 	AddUserRole(name string) (*models.Role, error)
-	RemoveUserRole(id uint32) error
+	RenameUserRole(id uint32, name string) (*models.Role, error)
+	RemoveUserRole(id, reassignID uint32) error
 	GetUserRoleByID(id uint32) (*models.Role, error)
+	GetUserRoleByName(name string) (*models.Role, error)
 	GetUserRoles() (*[]models.Role, error)
+	GetRolesPermissions(roleIDs []uint32) (map[uint32][]string, error)
+	SetRolePermissions(id uint32, permissions []string) (*models.Role, error)
+	AddRoleAssignment(assignment *models.RoleAssignment) (*models.RoleAssignment, error)
+	RemoveRoleAssignment(id uint32) error
+	GetRoleAssignments(userID string) ([]models.RoleAssignment, error)
+	GetActiveRoleAssignments(userID string, moment time.Time) ([]models.RoleAssignment, error)
+	AddOAuthClient(client *models.OAuthClient) (*models.OAuthClient, error)
+	GetOAuthClientByID(id string) (*models.OAuthClient, error)
+	GetOAuthClients() ([]models.OAuthClient, error)
+	UpdateOAuthClientSecret(id, secretHash string) (*models.OAuthClient, error)
+	RemoveOAuthClient(id string) error
+	GetUserConsents(userID string) ([]models.ConsentWithClient, error)
+	RemoveConsent(userID, clientID string) error
+	CreateUser(user *models.User) (*models.User, error)
+	CreateUserWithIdentity(user *models.User, identity *models.LinkedIdentity) (*models.User, error)
+	AddLinkedIdentity(identity *models.LinkedIdentity) (*models.LinkedIdentity, error)
+	GetLinkedIdentity(provider, subject string) (*models.LinkedIdentity, error)
+	GetUserLinkedIdentities(userID string) ([]models.LinkedIdentity, error)
+	SetLinkedIdentityLogin(id uint, moment time.Time) error
+	RemoveLinkedIdentity(userID string, id uint) error
+	AddAPIKey(key *models.APIKey) (*models.APIKey, error)
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, error)
+	GetAPIKeyByID(id string) (*models.APIKey, error)
+	GetAPIKeys() ([]models.APIKey, error)
+	UpdateAPIKeySecret(id, secretHash string) (*models.APIKey, error)
+	SetAPIKeyUse(id string, moment time.Time) error
+	RemoveAPIKey(id string) error
 	//UserAuth(email, password string) models.User
 	//UserVerify(email, token string) bool
 	//UserTokenRemove(email, token string)
@@ -12,7 +12,8 @@ type TokenService interface {
 	ParseJWT(ctx context.Context, token string) (*models.JWT, error)
 	ValidateRefreshJWT(ctx context.Context, token string) (*models.JWT, string, error)
 	CreateAuthJWT(ctx context.Context, identity string, email string) (*models.JWT, *models.JWT, error)
-	CreateAccessJWT(ctx context.Context, identity string, email string) (*models.JWT, *models.JWT, error)
+	CreateAccessJWT(ctx context.Context, identity string, email string, extra jwt.MapClaims) (*models.JWT, *models.JWT, error)
 	Validate(j *models.JWT, variety string) error
 	Revoke(j *models.JWT)
+	RevokeAll(ctx context.Context, identity string, keep *models.JWT) error
 }
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	protos "protos/account"
+	"strings"
+	"time"
+)
+
+// InviteUser create invitation with fixed email, department and role and send register token to invited user
+func (a *AccountService) InviteUser(ctx context.Context, rr *protos.InviteUserRequest) (*protos.Invitation, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "InviteUser")
+	defer span.End()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// register token is delivered only by notification
+	if !a.notifier.Enabled() {
+		return nil, models.NotificationsDisabledError
+	}
+
+	email := strings.TrimSpace(strings.ToLower(rr.GetEmail()))
+	err = validators.ValidateEmail(email)
+	if err != nil {
+		log.Error("[server.InviteUser] validators.ValidateEmail", "email", email, "error", err)
+		return nil, models.EmailNotValidError
+	}
+
+	emailExist, err := a.db.EmailExist(email)
+	if err != nil {
+		log.Error("[server.InviteUser] a.db.EmailExist", "error", err)
+		return nil, models.InternalError
+	}
+	if emailExist {
+		return nil, models.AlreadyExistError
+	}
+
+	invitationExist, err := a.db.PendingInvitationExist(email)
+	if err != nil {
+		log.Error("[server.InviteUser] a.db.PendingInvitationExist", "error", err)
+		return nil, models.InternalError
+	}
+	if invitationExist {
+		return nil, models.InvitationExistError
+	}
+
+	department, err := a.db.GetUserDepartmentByID(rr.GetDepartmentId())
+	if err != nil || department == nil {
+		log.Error("[server.InviteUser] a.db.GetUserDepartmentByID", "departmentID", rr.GetDepartmentId(), "error", err)
+		return nil, models.DepartmentNotFoundError
+	}
+
+	role, err := a.db.GetUserRoleByID(rr.GetRoleId())
+	if err != nil || role == nil {
+		log.Error("[server.InviteUser] a.db.GetUserRoleByID", "roleID", rr.GetRoleId(), "error", err)
+		return nil, models.RoleNotFoundError
+	}
+
+	// role is locked into register token, so caller can invite only with role whose permissions it has
+	err = a.authorizeRole(principal, role.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := a.db.AddInvitation(&models.Invitation{
+		Email:        email,
+		DepartmentID: department.ID,
+		RoleID:       role.ID,
+		InvitedBy:    principal.UserID,
+		Status:       models.InvitationPending,
+		ExpiresAt:    time.Now().Add(a.cfg.InvitationTTL),
+	})
+	if err != nil {
+		log.Error("[server.InviteUser] a.db.AddInvitation", "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.sendInvitation(ctx, invitation)
+	if err != nil {
+		log.Error("[server.InviteUser] a.sendInvitation", "invitationID", invitation.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return invitationToProto(invitation), nil
+}
+
+// ListInvitations return invitations with status or all invitations if status is empty
+func (a *AccountService) ListInvitations(ctx context.Context, rr *protos.ListInvitationsRequest) (*protos.ListInvitationsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListInvitations")
+	defer span.End()
+
+	// expired invitations are stored as pending
+	status := rr.GetStatus()
+	storedStatus := status
+	if status == models.InvitationExpired {
+		storedStatus = models.InvitationPending
+	}
+
+	invitations, err := a.db.GetInvitations(storedStatus)
+	if err != nil {
+		log.Error("[server.ListInvitations] a.db.GetInvitations", "error", err)
+		return nil, models.InternalError
+	}
+
+	now := time.Now()
+	result := make([]*protos.Invitation, 0, len(invitations))
+	for i := range invitations {
+		if status != "" && invitations[i].EffectiveStatus(now) != status {
+			continue
+		}
+		result = append(result, invitationToProto(&invitations[i]))
+	}
+
+	return &protos.ListInvitationsResponse{
+		Invitations: result,
+	}, nil
+}
+
+// ResendInvitation extend pending invitation and send new register token, previous tokens are revoked
+func (a *AccountService) ResendInvitation(ctx context.Context, rr *protos.InvitationIDRequest) (*protos.Invitation, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "ResendInvitation")
+	defer span.End()
+
+	if !a.notifier.Enabled() {
+		return nil, models.NotificationsDisabledError
+	}
+
+	invitation, err := a.db.UpdatePendingInvitation(rr.GetInvitationId(), map[string]interface{}{
+		"expires_at": time.Now().Add(a.cfg.InvitationTTL),
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.InvitationNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.ResendInvitation] a.db.UpdatePendingInvitation", "invitationID", rr.GetInvitationId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, invitation.ID, nil)
+	if err != nil {
+		log.Error("[server.ResendInvitation] a.tokenSrv.RevokeAll", "invitationID", invitation.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.sendInvitation(ctx, invitation)
+	if err != nil {
+		log.Error("[server.ResendInvitation] a.sendInvitation", "invitationID", invitation.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return invitationToProto(invitation), nil
+}
+
+// CancelInvitation cancel pending invitation and revoke its register tokens
+func (a *AccountService) CancelInvitation(ctx context.Context, rr *protos.InvitationIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "CancelInvitation")
+	defer span.End()
+
+	invitation, err := a.db.UpdatePendingInvitation(rr.GetInvitationId(), map[string]interface{}{
+		"status": models.InvitationCancelled,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.InvitationNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.CancelInvitation] a.db.UpdatePendingInvitation", "invitationID", rr.GetInvitationId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, invitation.ID, nil)
+	if err != nil {
+		log.Error("[server.CancelInvitation] a.tokenSrv.RevokeAll", "invitationID", invitation.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// sendInvitation create register token of invitation valid until invitation expires and notify invited user
+func (a *AccountService) sendInvitation(ctx context.Context, invitation *models.Invitation) error {
+	token, err := a.tokenSrv.NewJWT(
+		ctx,
+		models.RegisterToken,
+		invitation.ID,
+		invitation.Email,
+		jwt.MapClaims{
+			models.InvitationClaim:        invitation.ID,
+			models.InvitedDepartmentClaim: invitation.DepartmentID,
+			models.InvitedRoleClaim:       invitation.RoleID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("a.tokenSrv.NewJWT error: %w", err)
+	}
+	// register token lives as long as invitation instead of default register token expiration
+	token.Exp = invitation.ExpiresAt.Unix()
+
+	err = a.notifier.Notify(models.SubjectInvitation, &models.InvitationNotification{
+		InvitationID: invitation.ID,
+		Email:        invitation.Email,
+		Token:        token.ToJWTString(),
+		DepartmentID: invitation.DepartmentID,
+		RoleID:       invitation.RoleID,
+		ExpiresAt:    invitation.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("a.notifier.Notify error: %w", err)
+	}
+
+	return nil
+}
+
+// invitationOfToken get pending invitation of register token, nil if token is not issued by invitation
+func (a *AccountService) invitationOfToken(tok *models.JWT) (*models.Invitation, error) {
+	invitationID, ok := tok.Extra[models.InvitationClaim].(string)
+	if !ok {
+		return nil, nil
+	}
+
+	invitation, err := a.db.GetInvitationByID(invitationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.InvitationNotFoundError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("a.db.GetInvitationByID error: %w", err)
+	}
+
+	if invitation.EffectiveStatus(time.Now()) != models.InvitationPending || invitation.ID != tok.Identity {
+		return nil, models.InvitationNotFoundError
+	}
+
+	return invitation, nil
+}
+
+// acceptInvitation mark invitation accepted by registered user and revoke its register tokens,
+// failure is only logged because user is already registered
+func (a *AccountService) acceptInvitation(ctx context.Context, invitation *models.Invitation, user *models.User) {
+	log := hclog.Default()
+
+	_, err := a.db.UpdatePendingInvitation(invitation.ID, map[string]interface{}{
+		"status":           models.InvitationAccepted,
+		"accepted_user_id": user.ID,
+		"accepted_at":      time.Now(),
+	})
+	if err != nil {
+		log.Error("[server.acceptInvitation] a.db.UpdatePendingInvitation", "invitationID", invitation.ID, "error", err)
+	}
+
+	err = a.tokenSrv.RevokeAll(ctx, invitation.ID, nil)
+	if err != nil {
+		log.Error("[server.acceptInvitation] a.tokenSrv.RevokeAll", "invitationID", invitation.ID, "error", err)
+	}
+}
+
+// invitationToProto proto of invitation
+func invitationToProto(invitation *models.Invitation) *protos.Invitation {
+	return &protos.Invitation{
+		InvitationId:   invitation.ID,
+		Email:          invitation.Email,
+		DepartmentId:   invitation.DepartmentID,
+		RoleId:         invitation.RoleID,
+		InvitedBy:      invitation.InvitedBy,
+		Status:         invitation.EffectiveStatus(time.Now()),
+		ExpiresAt:      timestamppb.New(invitation.ExpiresAt),
+		AcceptedUserId: invitation.AcceptedUserID,
+		AcceptedAt:     optionalTimestamp(invitation.AcceptedAt),
+		CreatedAt:      timestamppb.New(invitation.CreatedAt),
+	}
+}
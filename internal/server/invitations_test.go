@@ -0,0 +1,52 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"errors"
+	protos "protos/account"
+	"testing"
+	"time"
+)
+
+func TestInviteUserRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		callerRole uint32
+		role       uint32
+		err        error
+	}{
+		{name: "admin invites admin", callerRole: 2, role: 2},
+		{name: "inviter invites with role of its permissions", callerRole: 3, role: 1},
+		{name: "inviter can't invite admin", callerRole: 3, role: 2, err: models.PermissionDeniedError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "inviter"})
+			db.rolePermissions[3] = []string{models.PermissionUsersInvite, models.PermissionDepartmentsRead, models.PermissionRolesRead}
+			caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: tt.callerRole})
+
+			notifier := &fakeNotifier{}
+			a := newTestService(db, nil)
+			a.cfg.InvitationTTL = time.Hour
+			a.notifier = notifier
+			a.tokenSrv = fakeTokens{}
+
+			invitation, err := a.InviteUser(userContext(t, a, caller), &protos.InviteUserRequest{Email: "bob@example.com", DepartmentId: 1, RoleId: tt.role})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(db.invitations) != 0 || len(notifier.messages) != 0 {
+					t.Errorf("invitations = %+v, messages %+v, want none", db.invitations, notifier.messages)
+				}
+				return
+			}
+
+			if invitation.GetRoleId() != tt.role || invitation.GetInvitedBy() != caller.ID || len(notifier.messages) != 1 {
+				t.Errorf("invitation = %+v, messages %+v", invitation, notifier.messages)
+			}
+		})
+	}
+}
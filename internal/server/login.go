@@ -1,79 +1,61 @@
 package server
 
 import (
+	"account-service/internal/directory"
 	"account-service/internal/models"
 	"account-service/internal/validators"
-	"comet/utils"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/golang-jwt/jwt"
 	"github.com/hashicorp/go-hclog"
+	"gorm.io/gorm"
 	protos "protos/account"
+	"strings"
 	"time"
 )
 
 // LoginUser recurring login user
 func (a *AccountService) LoginUser(ctx context.Context, rr *protos.LoginUserRequest) (*protos.LoginUserResponse, error) {
-	log := hclog.Default()
-
 	tr := a.trace
 	ctx, span := tr.Start(ctx, "Login")
 	defer span.End()
 
-	email := rr.GetEmail()
-
-	err := validators.ValidateEmail(email)
-	if err != nil {
-		log.Error("[server.LoginUser] validators.ValidateEmail", "error", err)
-		return nil, models.EmailNotValidError
-	}
-
-	user, err := a.db.GetUserByEmail(email)
+	user, err := a.Authenticate(ctx, rr.GetEmail(), rr.GetPassword())
 	if err != nil {
-		log.Error("[server.LoginUser] a.db.GetUserByEmail", "error", err)
-		return nil, models.UserNotFoundError
+		return nil, err
 	}
 
-	password := rr.GetPassword()
-	if len(password) > 0 {
-		log.Error("[server.LoginUser] password less then 1", "error")
-		return nil, models.PasswordNotValidError(fmt.Errorf("password less then 1"))
-	}
+	return a.loginResponse(ctx, user)
+}
 
-	if password != "" {
-		isValid, err := utils.VerifyArgon(user.Password, password)
-		if err != nil {
-			log.Error("[server.LoginUser] utils.VerifyArgon", "userID", user.ID, "error", err)
-			return nil, models.InternalError
-		}
+// loginResponse issue access and refresh tokens of authenticated user
+func (a *AccountService) loginResponse(ctx context.Context, user *models.User) (*protos.LoginUserResponse, error) {
+	log := hclog.Default()
 
-		if !isValid {
-			return nil, models.NotMatchError
-		}
-	} else {
-		return nil, models.BadRequestError
+	claims, err := a.AccessClaims(user)
+	if err != nil {
+		log.Error("[server.loginResponse] a.AccessClaims", "userID", user.ID, "error", err)
+		return nil, models.InternalError
 	}
 
-	token, refresh, err := a.tokenSrv.CreateAccessJWT(
-		ctx,
-		user.ID,
-		user.Email,
-	)
+	token, refresh, err := a.tokenSrv.CreateAccessJWT(ctx, user.ID, user.Email, claims)
 
 	if err != nil {
-		log.Error("[server.LoginUser] a.tokenSrv.CreateAccessJWT", "userID", user.ID, "error", err)
+		log.Error("[server.loginResponse] a.tokenSrv.CreateAccessJWT", "userID", user.ID, "error", err)
 		return nil, models.InternalError
 	}
 
 	accessTokenExpirationTime, ok := models.Expirations["AccessToken"]
 
 	if !ok {
-		log.Error("[server.LoginUser] models.Expirations", "error", "accessToken expiration not found")
+		log.Error("[server.loginResponse] models.Expirations", "error", "accessToken expiration not found")
 	}
 
 	refreshTokenExpirationTime, ok := models.Expirations["RefreshToken"]
 
 	if !ok {
-		log.Error("[server.LoginUser] models.Expirations", "error", "refreshToken expiration not found")
+		log.Error("[server.loginResponse] models.Expirations", "error", "refreshToken expiration not found")
 	}
 
 	return &protos.LoginUserResponse{
@@ -85,9 +67,289 @@ func (a *AccountService) LoginUser(ctx context.Context, rr *protos.LoginUserRequ
 			UserId:       user.ID,
 			FirstName:    user.FirstName,
 			SecondName:   user.LastName,
-			Email:        email,
+			Email:        user.Email,
 			DepartmentId: user.DepartmentID,
 			RoleId:       user.RoleID,
 		},
 	}, nil
 }
+
+// Authenticate check email and password of active user by authenticators of chain in configured order,
+// first authenticator accepting password wins
+func (a *AccountService) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	log := hclog.Default()
+
+	ctx, span := a.trace.Start(ctx, "Authenticate")
+	defer span.End()
+
+	err := validators.ValidateEmail(email)
+	if err != nil {
+		log.Error("[server.Authenticate] validators.ValidateEmail", "error", err)
+		return nil, models.EmailNotValidError
+	}
+
+	if len(password) == 0 {
+		log.Error("[server.Authenticate] password less then 1", "error")
+		return nil, models.PasswordNotValidError(fmt.Errorf("password less then 1"))
+	}
+
+	// wrong password is reported over unknown user if any authenticator knows user,
+	// failure of authenticator is reported over both because user might be accepted by it
+	failure := models.UserNotFoundError
+	var failed error
+	for _, authenticator := range a.cfg.AuthChain {
+		var user *models.User
+		switch authenticator {
+		case models.AuthLocal:
+			user, err = a.authenticateLocal(email, password)
+		case models.AuthLDAP:
+			user, err = a.authenticateDirectory(ctx, email, password)
+		default:
+			continue
+		}
+
+		switch err {
+		case nil:
+			return user, nil
+		case models.AccountDeactivatedError:
+			return nil, err
+		case models.NotMatchError:
+			failure = err
+		case models.UserNotFoundError:
+		default:
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+
+	if failed != nil {
+		return nil, failed
+	}
+
+	return nil, failure
+}
+
+// authenticateLocal check password by its hash stored by service
+func (a *AccountService) authenticateLocal(email, password string) (*models.User, error) {
+	log := hclog.Default()
+
+	user, err := a.db.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.authenticateLocal] a.db.GetUserByEmail", "error", err)
+		return nil, models.InternalError
+	}
+
+	// users provisioned by SCIM, directory or external identity provider have no password until they set it
+	if user.Password == "" {
+		return nil, models.NotMatchError
+	}
+
+	isValid, err := a.hasher.Verify(user.Password, password)
+	if err != nil {
+		log.Error("[server.authenticateLocal] a.hasher.Verify", "userID", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	if !isValid {
+		return nil, models.NotMatchError
+	}
+
+	if user.IsDeactivated() {
+		return nil, models.AccountDeactivatedError
+	}
+
+	a.rehashPassword(user, password)
+
+	return user, nil
+}
+
+// authenticateDirectory bind to directory as user, user is provisioned on first login
+// and its names, role and department are synced with directory on next logins
+func (a *AccountService) authenticateDirectory(ctx context.Context, email, password string) (*models.User, error) {
+	log := hclog.Default()
+
+	account, err := a.directory.Authenticate(ctx, email, password)
+	if errors.Is(err, directory.ErrUserNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if errors.Is(err, directory.ErrInvalidCredentials) {
+		return nil, models.NotMatchError
+	}
+	if err != nil {
+		log.Error("[server.authenticateDirectory] a.directory.Authenticate", "error", err)
+		return nil, models.InternalError
+	}
+
+	// entry is found by email filter, but attribute may be absent or differ in case
+	if account.Email == "" {
+		account.Email = strings.ToLower(email)
+	}
+
+	user, err := a.db.GetUserByEmail(account.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return a.provisionDirectoryUser(account)
+	}
+	if err != nil {
+		log.Error("[server.authenticateDirectory] a.db.GetUserByEmail", "error", err)
+		return nil, models.InternalError
+	}
+
+	if user.IsDeactivated() {
+		return nil, models.AccountDeactivatedError
+	}
+
+	return a.syncDirectoryUser(user, account), nil
+}
+
+// provisionDirectoryUser create user of directory account in its department and role,
+// defaults of configuration are used if directory doesn't define them
+func (a *AccountService) provisionDirectoryUser(account *models.DirectoryAccount) (*models.User, error) {
+	log := hclog.Default()
+
+	if validators.ValidateFIO(account.FirstName) != nil || validators.ValidateFIO(account.LastName) != nil {
+		log.Error("[server.provisionDirectoryUser] validators.ValidateFIO", "dn", account.DN)
+		return nil, models.FioNotValidError
+	}
+
+	departmentName := account.Department
+	if departmentName == "" {
+		departmentName = a.cfg.LDAPDefaultDepartment
+	}
+	department, err := a.db.GetUserDepartmentByName(departmentName)
+	if err != nil && departmentName != a.cfg.LDAPDefaultDepartment {
+		department, err = a.db.GetUserDepartmentByName(a.cfg.LDAPDefaultDepartment)
+	}
+	if err != nil {
+		log.Error("[server.provisionDirectoryUser] a.db.GetUserDepartmentByName", "name", departmentName, "error", err)
+		return nil, models.DepartmentNotFoundError
+	}
+
+	roleName := account.Role
+	if roleName == "" {
+		roleName = a.cfg.LDAPDefaultRole
+	}
+	role, err := a.db.GetUserRoleByName(roleName)
+	if err != nil {
+		log.Error("[server.provisionDirectoryUser] a.db.GetUserRoleByName", "name", roleName, "error", err)
+		return nil, models.RoleNotFoundError
+	}
+
+	user, err := a.db.CreateUser(&models.User{
+		FirstName:     account.FirstName,
+		LastName:      account.LastName,
+		Email:         account.Email,
+		EmailVerified: true,
+		DepartmentID:  department.ID,
+		RoleID:        role.ID,
+		IsRegistered:  true,
+	})
+	if err != nil {
+		log.Error("[server.provisionDirectoryUser] a.db.CreateUser", "dn", account.DN, "error", err)
+		return nil, models.InternalError
+	}
+	a.auditDirectory("ProvisionUser", user.ID)
+
+	return user, nil
+}
+
+// syncDirectoryUser update names, role and department of user changed in directory,
+// role and department are kept if directory has no mapped group or known department,
+// failure is only logged because user is already authenticated
+func (a *AccountService) syncDirectoryUser(user *models.User, account *models.DirectoryAccount) *models.User {
+	log := hclog.Default()
+
+	fields := make(map[string]interface{})
+	if account.FirstName != user.FirstName && validators.ValidateFIO(account.FirstName) == nil {
+		fields["first_name"] = account.FirstName
+	}
+	if account.LastName != user.LastName && validators.ValidateFIO(account.LastName) == nil {
+		fields["last_name"] = account.LastName
+	}
+
+	if account.Role != "" {
+		role, err := a.db.GetUserRoleByName(account.Role)
+		if err != nil {
+			log.Error("[server.syncDirectoryUser] a.db.GetUserRoleByName", "name", account.Role, "error", err)
+		} else if role.ID != user.RoleID {
+			fields["role_id"] = role.ID
+		}
+	}
+
+	if account.Department != "" {
+		department, err := a.db.GetUserDepartmentByName(account.Department)
+		if err != nil {
+			log.Error("[server.syncDirectoryUser] a.db.GetUserDepartmentByName", "name", account.Department, "error", err)
+		} else if department.ID != user.DepartmentID {
+			fields["department_id"] = department.ID
+		}
+	}
+
+	if len(fields) == 0 {
+		return user
+	}
+
+	updated, err := a.db.UpdateUserByID(user.ID, fields, user.UpdatedAt)
+	if err != nil {
+		log.Error("[server.syncDirectoryUser] a.db.UpdateUserByID", "userID", user.ID, "error", err)
+		return user
+	}
+	a.auditDirectory("SyncUser", user.ID)
+
+	return updated
+}
+
+// auditDirectory record change of account made by login with directory,
+// failure is only logged because change is already done
+func (a *AccountService) auditDirectory(method, userID string) {
+	err := a.db.AddAuditEntry(&models.AuditEntry{
+		ActorID:    userID,
+		ActorEmail: "ldap:" + a.cfg.LDAPURL,
+		SubjectID:  userID,
+		Method:     "directory." + method,
+		Code:       "OK",
+	})
+	if err != nil {
+		hclog.Default().Error("[server.auditDirectory] a.db.AddAuditEntry", "method", method, "userID", userID, "error", err)
+	}
+}
+
+// AccessClaims claims of access token of user with its effective roles and global permissions
+func (a *AccountService) AccessClaims(user *models.User) (jwt.MapClaims, error) {
+	roles, err := a.effectiveRoles(user)
+	if err != nil {
+		return nil, fmt.Errorf("a.effectiveRoles error: %w", err)
+	}
+
+	return jwt.MapClaims{
+		models.PermissionsClaim: models.GlobalPermissions(roles),
+		models.RolesClaim:       roles,
+	}, nil
+}
+
+// rehashPassword upgrade hash of verified password if it is weaker than current parameters,
+// failure is only logged because user is already authenticated
+func (a *AccountService) rehashPassword(user *models.User, password string) {
+	log := hclog.Default()
+
+	if !a.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		log.Error("[server.rehashPassword] a.hasher.Hash", "userID", user.ID, "error", err)
+		return
+	}
+
+	err = a.db.UpdatePasswordHash(user.ID, user.Password, hash)
+	if err != nil {
+		log.Error("[server.rehashPassword] a.db.UpdatePasswordHash", "userID", user.ID, "error", err)
+		return
+	}
+
+	user.Password = hash
+}
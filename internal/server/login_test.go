@@ -0,0 +1,195 @@
+package server
+
+import (
+	"account-service/config"
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateChain(t *testing.T) {
+	deactivated := time.Now()
+
+	tests := []struct {
+		name  string
+		chain []string
+		// local user with password hash, no local user if hash is empty
+		hash        string
+		deactivated bool
+		emailErr    error
+		dirErr      error
+		password    string
+		err         error
+		// dirCalls count of calls of directory
+		dirCalls int
+	}{
+		{name: "local accepts", chain: []string{models.AuthLocal, models.AuthLDAP}, hash: "hash:local", password: "local"},
+		{
+			name: "directory after wrong local password", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "hash:local", password: "directory", dirCalls: 1,
+		},
+		{
+			name: "local after wrong directory password", chain: []string{models.AuthLDAP, models.AuthLocal},
+			hash: "hash:local", password: "local", dirCalls: 1,
+		},
+		{
+			name: "wrong password of both", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "hash:local", password: "wrong", err: models.NotMatchError, dirCalls: 1,
+		},
+		{
+			name: "deactivated local user stops chain", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "hash:local", deactivated: true, password: "local", err: models.AccountDeactivatedError,
+		},
+		{
+			name: "directory isn't in chain", chain: []string{models.AuthLocal},
+			hash: "hash:local", password: "directory", err: models.NotMatchError,
+		},
+		{
+			name: "wrong directory password without local user", chain: []string{models.AuthLocal, models.AuthLDAP},
+			password: "wrong", err: models.NotMatchError, dirCalls: 1,
+		},
+		{
+			name: "directory unavailable", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "hash:local", dirErr: errors.New("connection refused"), password: "wrong", err: models.InternalError, dirCalls: 1,
+		},
+		{
+			name: "directory unavailable after local accepts", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "hash:local", dirErr: errors.New("connection refused"), password: "local",
+		},
+		{
+			name: "local hash can't be verified", chain: []string{models.AuthLocal, models.AuthLDAP},
+			hash: "unknown", password: "wrong", err: models.InternalError, dirCalls: 1,
+		},
+		{
+			name: "database unavailable", chain: []string{models.AuthLocal},
+			emailErr: errors.New("connection refused"), password: "local", err: models.InternalError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.emailErr = tt.emailErr
+			if tt.hash != "" {
+				user := models.User{Email: "ann@example.com", FirstName: "Ann", LastName: "Lee", Password: tt.hash, DepartmentID: 1, RoleID: 1}
+				if tt.deactivated {
+					user.DeactivatedAt = &deactivated
+				}
+				db.addUser(user)
+			}
+
+			dir := &fakeDirectory{
+				accounts: map[string]models.DirectoryAccount{"ann@example.com": {Email: "ann@example.com", FirstName: "Ann", LastName: "Lee"}},
+				password: "directory",
+				err:      tt.dirErr,
+			}
+			a := newTestService(db, &config.Config{AuthChain: tt.chain, LDAPDefaultDepartment: "Staff", LDAPDefaultRole: models.EmployeeRole})
+			a.directory = dir
+
+			user, err := a.Authenticate(context.Background(), "ann@example.com", tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && user.Email != "ann@example.com" {
+				t.Errorf("user = %+v", user)
+			}
+			if dir.calls != tt.dirCalls {
+				t.Errorf("directory calls = %d, want %d", dir.calls, tt.dirCalls)
+			}
+		})
+	}
+}
+
+func TestAuthenticateDirectoryProvisioning(t *testing.T) {
+	cfg := &config.Config{AuthChain: []string{models.AuthLDAP}, LDAPDefaultDepartment: "Staff", LDAPDefaultRole: models.EmployeeRole}
+
+	tests := []struct {
+		name    string
+		account models.DirectoryAccount
+		// existing user of directory account
+		existing   *models.User
+		err        error
+		department uint32
+		role       uint32
+		firstName  string
+	}{
+		{
+			name:       "provision with directory department and group role",
+			account:    models.DirectoryAccount{FirstName: "Ann", LastName: "Lee", Department: "Engineering", Role: "admin"},
+			department: 2, role: 2, firstName: "Ann",
+		},
+		{
+			name:       "provision with defaults",
+			account:    models.DirectoryAccount{FirstName: "Ann", LastName: "Lee"},
+			department: 1, role: 1, firstName: "Ann",
+		},
+		{
+			name:       "unknown department falls back to default",
+			account:    models.DirectoryAccount{FirstName: "Ann", LastName: "Lee", Department: "Sales"},
+			department: 1, role: 1, firstName: "Ann",
+		},
+		{
+			name:    "unknown role of mapped group",
+			account: models.DirectoryAccount{FirstName: "Ann", LastName: "Lee", Role: "auditor"},
+			err:     models.RoleNotFoundError,
+		},
+		{
+			name:    "invalid name",
+			account: models.DirectoryAccount{FirstName: "Ann Marie", LastName: "Lee"},
+			err:     models.FioNotValidError,
+		},
+		{
+			name:       "sync names, department and role",
+			account:    models.DirectoryAccount{FirstName: "Anna", LastName: "Lee", Department: "Engineering", Role: "admin"},
+			existing:   &models.User{FirstName: "Ann", LastName: "Lee", DepartmentID: 1, RoleID: 1},
+			department: 2, role: 2, firstName: "Anna",
+		},
+		{
+			name:       "sync keeps role without mapped group and unknown department",
+			account:    models.DirectoryAccount{FirstName: "Ann", LastName: "Lee", Department: "Sales"},
+			existing:   &models.User{FirstName: "Ann", LastName: "Lee", DepartmentID: 2, RoleID: 2},
+			department: 2, role: 2, firstName: "Ann",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			if tt.existing != nil {
+				existing := *tt.existing
+				existing.Email = "ann@example.com"
+				db.addUser(existing)
+			}
+
+			// email of entry is taken from login when directory has no email attribute
+			a := newTestService(db, cfg)
+			a.directory = &fakeDirectory{
+				accounts: map[string]models.DirectoryAccount{"ann@example.com": tt.account},
+				password: "directory",
+			}
+
+			user, err := a.Authenticate(context.Background(), "ann@example.com", "directory")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(db.users) != 0 {
+					t.Errorf("users = %+v, want none", db.users)
+				}
+				return
+			}
+
+			if len(db.users) != 1 || user.ID != db.users[0].ID {
+				t.Fatalf("user = %+v, users %+v", user, db.users)
+			}
+			if user.Email != "ann@example.com" || user.FirstName != tt.firstName || user.DepartmentID != tt.department || user.RoleID != tt.role {
+				t.Errorf("user = %+v, want %s in department %d with role %d", user, tt.firstName, tt.department, tt.role)
+			}
+			if tt.existing == nil && (!user.EmailVerified || !user.IsRegistered || user.Password != "") {
+				t.Errorf("provisioned user = %+v, want verified registered user without password", user)
+			}
+		})
+	}
+}
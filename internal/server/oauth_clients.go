@@ -0,0 +1,383 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"net/url"
+	protos "protos/account"
+	"strings"
+)
+
+// clientSecretSize count of random bytes of client secret
+const clientSecretSize = 32
+
+// CreateOAuthClient register oauth client, secret of confidential client is returned only once
+func (a *AccountService) CreateOAuthClient(ctx context.Context, rr *protos.CreateOAuthClientRequest) (*protos.OAuthClientSecret, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "CreateOAuthClient")
+	defer span.End()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grantTypes := rr.GetGrantTypes()
+	if len(grantTypes) == 0 {
+		grantTypes = []string{models.GrantAuthorizationCode, models.GrantRefreshToken}
+	}
+
+	err = validateOAuthClient(rr, grantTypes)
+	if err != nil {
+		return nil, models.InvalidOAuthClientError(err)
+	}
+
+	if rr.GetRoleId() != 0 {
+		role, err := a.db.GetUserRoleByID(rr.GetRoleId())
+		if err != nil || role == nil {
+			log.Error("[server.CreateOAuthClient] a.db.GetUserRoleByID", "roleID", rr.GetRoleId(), "error", err)
+			return nil, models.RoleNotFoundError
+		}
+
+		// caller can bind only role whose permissions it has itself, as for api keys
+		err = a.authorizeRole(principal, role.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rr.GetDepartmentId() != 0 {
+		department, err := a.db.GetUserDepartmentByID(rr.GetDepartmentId())
+		if err != nil || department == nil {
+			log.Error("[server.CreateOAuthClient] a.db.GetUserDepartmentByID", "departmentID", rr.GetDepartmentId(), "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	var secret, secretHash string
+	if rr.GetConfidential() {
+		secret, secretHash, err = a.newClientSecret()
+		if err != nil {
+			log.Error("[server.CreateOAuthClient] a.newClientSecret", "error", err)
+			return nil, models.InternalError
+		}
+	}
+
+	client, err := a.db.AddOAuthClient(&models.OAuthClient{
+		Name:                   strings.TrimSpace(rr.GetName()),
+		SecretHash:             secretHash,
+		RedirectURIs:           strings.Join(rr.GetRedirectUris(), " "),
+		PostLogoutRedirectURIs: strings.Join(rr.GetPostLogoutRedirectUris(), " "),
+		Scopes:                 strings.Join(rr.GetScopes(), " "),
+		GrantTypes:             strings.Join(grantTypes, " "),
+		Trusted:                rr.GetTrusted(),
+		RoleID:                 rr.GetRoleId(),
+		DepartmentID:           rr.GetDepartmentId(),
+		CreatedBy:              principal.UserID,
+	})
+	if err != nil {
+		log.Error("[server.CreateOAuthClient] a.db.AddOAuthClient", "error", err)
+		return nil, models.InternalError
+	}
+
+	return &protos.OAuthClientSecret{
+		Client:       oauthClientToProto(client),
+		ClientSecret: secret,
+	}, nil
+}
+
+// ListOAuthClients return all registered oauth clients
+func (a *AccountService) ListOAuthClients(ctx context.Context, _ *emptypb.Empty) (*protos.ListOAuthClientsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListOAuthClients")
+	defer span.End()
+
+	clients, err := a.db.GetOAuthClients()
+	if err != nil {
+		log.Error("[server.ListOAuthClients] a.db.GetOAuthClients", "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.OAuthClient, 0, len(clients))
+	for i := range clients {
+		result = append(result, oauthClientToProto(&clients[i]))
+	}
+
+	return &protos.ListOAuthClientsResponse{
+		Clients: result,
+	}, nil
+}
+
+// RotateOAuthClientSecret replace secret of confidential oauth client, previous secret stops working immediately,
+// caller must hold permissions of role of client as on registration
+func (a *AccountService) RotateOAuthClientSecret(ctx context.Context, rr *protos.OAuthClientIDRequest) (*protos.OAuthClientSecret, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "RotateOAuthClientSecret")
+	defer span.End()
+
+	err := a.authorizeOAuthClient(ctx, rr.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := a.newClientSecret()
+	if err != nil {
+		log.Error("[server.RotateOAuthClientSecret] a.newClientSecret", "error", err)
+		return nil, models.InternalError
+	}
+
+	client, err := a.db.UpdateOAuthClientSecret(rr.GetClientId(), secretHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.OAuthClientNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RotateOAuthClientSecret] a.db.UpdateOAuthClientSecret", "clientID", rr.GetClientId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &protos.OAuthClientSecret{
+		Client:       oauthClientToProto(client),
+		ClientSecret: secret,
+	}, nil
+}
+
+// DeleteOAuthClient remove oauth client with consents of users, tokens of client are rejected afterwards,
+// caller must hold permissions of role of client
+func (a *AccountService) DeleteOAuthClient(ctx context.Context, rr *protos.OAuthClientIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "DeleteOAuthClient")
+	defer span.End()
+
+	err := a.authorizeOAuthClient(ctx, rr.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.db.RemoveOAuthClient(rr.GetClientId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.OAuthClientNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.DeleteOAuthClient] a.db.RemoveOAuthClient", "clientID", rr.GetClientId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// authorizeOAuthClient check caller holds permissions of role of oauth client
+func (a *AccountService) authorizeOAuthClient(ctx context.Context, clientID string) error {
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := a.db.GetOAuthClientByID(clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.OAuthClientNotFoundError
+	}
+	if err != nil {
+		hclog.Default().Error("[server.authorizeOAuthClient] a.db.GetOAuthClientByID", "clientID", clientID, "error", err)
+		return models.InternalError
+	}
+
+	if client.RoleID == 0 {
+		return nil
+	}
+
+	return a.authorizeRole(principal, client.RoleID, 0)
+}
+
+// ListMyConsents return clients caller allowed to access its account
+func (a *AccountService) ListMyConsents(ctx context.Context, _ *emptypb.Empty) (*protos.ListConsentsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "ListMyConsents")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	consents, err := a.db.GetUserConsents(principal.UserID)
+	if err != nil {
+		log.Error("[server.ListMyConsents] a.db.GetUserConsents", "userID", principal.UserID, "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.Consent, 0, len(consents))
+	for _, consent := range consents {
+		result = append(result, &protos.Consent{
+			ClientId:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     strings.Fields(consent.Scopes),
+			CreatedAt:  timestamppb.New(consent.CreatedAt),
+			UpdatedAt:  timestamppb.New(consent.UpdatedAt),
+		})
+	}
+
+	return &protos.ListConsentsResponse{
+		Consents: result,
+	}, nil
+}
+
+// RevokeConsent remove consent of caller for client, refresh tokens of client are rejected afterwards
+// and issued access tokens expire
+func (a *AccountService) RevokeConsent(ctx context.Context, rr *protos.OAuthClientIDRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "RevokeConsent")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.db.RemoveConsent(principal.UserID, rr.GetClientId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ConsentNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RevokeConsent] a.db.RemoveConsent", "userID", principal.UserID, "clientID", rr.GetClientId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// validateOAuthClient check registration of oauth client
+func validateOAuthClient(rr *protos.CreateOAuthClientRequest, grantTypes []string) error {
+	if strings.TrimSpace(rr.GetName()) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	for _, grantType := range grantTypes {
+		supported := false
+		for _, supportedType := range models.OAuthGrantTypes {
+			if grantType == supportedType {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return fmt.Errorf("unsupported grant type %s", grantType)
+		}
+
+		switch grantType {
+		case models.GrantAuthorizationCode:
+			if len(rr.GetRedirectUris()) == 0 {
+				return fmt.Errorf("redirect uri is required for %s grant", grantType)
+			}
+		case models.GrantClientCredentials:
+			if !rr.GetConfidential() {
+				return fmt.Errorf("%s grant requires confidential client", grantType)
+			}
+			if rr.GetRoleId() == 0 {
+				return fmt.Errorf("role is required for %s grant", grantType)
+			}
+		}
+	}
+
+	for _, redirectURI := range rr.GetRedirectUris() {
+		err := validateRedirectURI(redirectURI)
+		if err != nil {
+			return fmt.Errorf("redirect uri %s: %w", redirectURI, err)
+		}
+	}
+
+	for _, redirectURI := range rr.GetPostLogoutRedirectUris() {
+		err := validateRedirectURI(redirectURI)
+		if err != nil {
+			return fmt.Errorf("post logout redirect uri %s: %w", redirectURI, err)
+		}
+	}
+
+	for _, scope := range rr.GetScopes() {
+		if scope == "" || strings.ContainsAny(scope, " \t\n\"\\") {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	return nil
+}
+
+// validateRedirectURI check redirect uri is absolute without fragment, plain http is allowed only for loopback
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return err
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("must be absolute")
+	}
+	if parsed.Fragment != "" || strings.Contains(redirectURI, "#") {
+		return fmt.Errorf("must not have fragment")
+	}
+	if strings.ContainsAny(redirectURI, " \t\n") {
+		return fmt.Errorf("must not have spaces")
+	}
+
+	hostname := parsed.Hostname()
+	if parsed.Scheme == "http" && hostname != "localhost" && hostname != "127.0.0.1" && hostname != "::1" {
+		return fmt.Errorf("http is allowed only for loopback")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %s", parsed.Scheme)
+	}
+
+	return nil
+}
+
+// newClientSecret random client secret and its hash
+func (a *AccountService) newClientSecret() (string, string, error) {
+	data := make([]byte, clientSecretSize)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(data)
+	hash, err := a.hasher.Hash(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("a.hasher.Hash error: %w", err)
+	}
+
+	return secret, hash, nil
+}
+
+func oauthClientToProto(client *models.OAuthClient) *protos.OAuthClient {
+	return &protos.OAuthClient{
+		ClientId:               client.ID,
+		Name:                   client.Name,
+		RedirectUris:           strings.Fields(client.RedirectURIs),
+		Scopes:                 strings.Fields(client.Scopes),
+		GrantTypes:             strings.Fields(client.GrantTypes),
+		Confidential:           client.IsConfidential(),
+		Trusted:                client.Trusted,
+		RoleId:                 client.RoleID,
+		DepartmentId:           client.DepartmentID,
+		CreatedBy:              client.CreatedBy,
+		CreatedAt:              timestamppb.New(client.CreatedAt),
+		PostLogoutRedirectUris: strings.Fields(client.PostLogoutRedirectURIs),
+	}
+}
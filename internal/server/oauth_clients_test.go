@@ -0,0 +1,55 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"errors"
+	protos "protos/account"
+	"testing"
+)
+
+func TestManageOAuthClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     uint32
+		clientID string
+		err      error
+	}{
+		{name: "client without role", clientID: "client-1"},
+		{name: "client with role of caller permissions", role: 1, clientID: "client-1"},
+		{name: "client with admin role", role: 2, clientID: "client-1", err: models.PermissionDeniedError},
+		{name: "unknown client", clientID: "client-2", err: models.OAuthClientNotFoundError},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []string{"RotateOAuthClientSecret", "DeleteOAuthClient"} {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				db := newFakeRepository()
+				db.roles = append(db.roles, models.Role{ID: 3, Name: "integrator"})
+				db.rolePermissions[3] = []string{models.PermissionClientsWrite, models.PermissionDepartmentsRead, models.PermissionRolesRead}
+				db.clients = []models.OAuthClient{{ID: "client-1", Name: "app", SecretHash: "hash:secret", RoleID: tt.role}}
+				caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: 3})
+
+				a := newTestService(db, nil)
+				ctx := userContext(t, a, caller)
+
+				var err error
+				if method == "RotateOAuthClientSecret" {
+					_, err = a.RotateOAuthClientSecret(ctx, &protos.OAuthClientIDRequest{ClientId: tt.clientID})
+				} else {
+					_, err = a.DeleteOAuthClient(ctx, &protos.OAuthClientIDRequest{ClientId: tt.clientID})
+				}
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+
+				unchanged := len(db.clients) == 1 && db.clients[0].SecretHash == "hash:secret"
+				if tt.err != nil && !unchanged {
+					t.Errorf("clients = %+v, want unchanged client", db.clients)
+				}
+				if tt.err == nil && unchanged {
+					t.Errorf("clients = %+v, want rotated or deleted client", db.clients)
+				}
+			})
+		}
+	}
+}
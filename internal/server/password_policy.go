@@ -0,0 +1,56 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	protos "protos/account"
+)
+
+// GetPasswordPolicy return rules of passwords so clients can show them before submit
+func (a *AccountService) GetPasswordPolicy(ctx context.Context, _ *emptypb.Empty) (*protos.PasswordPolicy, error) {
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetPasswordPolicy")
+	defer span.End()
+
+	policy := a.cfg.PasswordPolicy
+
+	return &protos.PasswordPolicy{
+		MinLength:        int32(policy.MinLength),
+		MaxLength:        int32(policy.MaxLength),
+		RequireUpper:     policy.RequireUpper,
+		RequireLower:     policy.RequireLower,
+		RequireDigit:     policy.RequireDigit,
+		RequireSymbol:    policy.RequireSymbol,
+		BannedSubstrings: policy.BannedSubstrings,
+		BanPersonalInfo:  policy.BanPersonalInfo,
+		MaxRepeated:      int32(policy.MaxRepeated),
+		MinScore:         int32(policy.MinScore),
+	}, nil
+}
+
+// validatePassword check password by configured policy and breached passwords,
+// field is name of request field with password
+func (a *AccountService) validatePassword(field string, password string, pc validators.PasswordContext) error {
+	violations := a.cfg.PasswordPolicy.Validate(password, pc)
+
+	breached, err := a.breach.IsBreached(password)
+	if err != nil {
+		hclog.Default().Error("[server.validatePassword] a.breach.IsBreached", "error", err)
+		return models.InternalError
+	}
+	if breached {
+		violations = append(violations, validators.PasswordViolation{
+			Rule:        validators.RuleBreached,
+			Description: "password is found in known data breaches",
+		})
+	}
+
+	if len(violations) > 0 {
+		return models.PasswordPolicyError(field, violations)
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"comet/utils"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+	"path"
+	"time"
+)
+
+// rpcPermissions permissions required to call administrative rpc, key is name of rpc method
+var rpcPermissions = map[string][]string{
+	"ListAccounts":        {models.PermissionUsersRead},
+	"GetAccountsByIDs":    {models.PermissionUsersLookup},
+	"StreamAccountsByIDs": {models.PermissionUsersLookup},
+
+	"DeactivateAccount": {models.PermissionUsersWrite},
+	"ReactivateAccount": {models.PermissionUsersWrite},
+	"DeleteAccount":     {models.PermissionUsersDelete},
+	"RestoreAccount":    {models.PermissionUsersDelete},
+	"GetErasureReport":  {models.PermissionUsersDelete},
+	"ExportUserData":    {models.PermissionUsersExport},
+	"ExportUsers":       {models.PermissionUsersExport},
+
+	"InviteUser":       {models.PermissionUsersInvite},
+	"ListInvitations":  {models.PermissionUsersInvite},
+	"ResendInvitation": {models.PermissionUsersInvite},
+	"CancelInvitation": {models.PermissionUsersInvite},
+	"ImportUsers":      {models.PermissionUsersImport},
+
+	"CreateDepartment": {models.PermissionDepartmentsWrite},
+	"RenameDepartment": {models.PermissionDepartmentsWrite},
+	"DeleteDepartment": {models.PermissionDepartmentsWrite},
+	"GetDepartment":    {models.PermissionDepartmentsRead},
+	"ListDepartments":  {models.PermissionDepartmentsRead},
+
+	"MoveDepartment":           {models.PermissionDepartmentsWrite},
+	"GetDepartmentAncestors":   {models.PermissionDepartmentsRead},
+	"GetDepartmentDescendants": {models.PermissionDepartmentsRead},
+	"ListDepartmentUsers":      {models.PermissionDepartmentsRead, models.PermissionUsersRead},
+
+	"CreateRole":         {models.PermissionRolesWrite},
+	"RenameRole":         {models.PermissionRolesWrite},
+	"DeleteRole":         {models.PermissionRolesWrite},
+	"GetRole":            {models.PermissionRolesRead},
+	"ListRoles":          {models.PermissionRolesRead},
+	"SetRolePermissions": {models.PermissionRolesWrite},
+
+	"GrantRole":           {models.PermissionRolesAssign},
+	"RevokeRole":          {models.PermissionRolesAssign},
+	"ListRoleAssignments": {models.PermissionUsersRead},
+
+	"ExplainAccess": {models.PermissionPolicyExplain},
+
+	"CreateOAuthClient":       {models.PermissionClientsWrite},
+	"ListOAuthClients":        {models.PermissionClientsWrite},
+	"RotateOAuthClientSecret": {models.PermissionClientsWrite},
+	"DeleteOAuthClient":       {models.PermissionClientsWrite},
+
+	"CreateAPIKey": {models.PermissionAPIKeysWrite},
+	"ListAPIKeys":  {models.PermissionAPIKeysWrite},
+	"RotateAPIKey": {models.PermissionAPIKeysWrite},
+	"RevokeAPIKey": {models.PermissionAPIKeysWrite},
+}
+
+type principalKey struct{}
+
+// principalFromContext get principal stored by interceptors
+func principalFromContext(ctx context.Context) (*models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*models.Principal)
+	return principal, ok
+}
+
+// authenticate get principal by access token or api key from header
+func (a *AccountService) authenticate(ctx context.Context) (*models.Principal, error) {
+	log := hclog.Default()
+
+	if principal, ok := principalFromContext(ctx); ok {
+		return principal, nil
+	}
+
+	accessToken, err := utils.GetAccessHeader(&ctx)
+	if err != nil {
+		log.Error("[server.authenticate] utils.GetAccessHeader", "error", err)
+		return nil, models.InvalidAccessTokenError
+	}
+
+	return a.principalFromToken(ctx, accessToken)
+}
+
+// authenticateUser get principal of user, api keys and oauth clients acting without user are rejected
+func (a *AccountService) authenticateUser(ctx context.Context) (*models.Principal, error) {
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.UserID == "" {
+		hclog.Default().Error("[server.authenticateUser] principal has no user", "clientID", principal.ClientID, "apiKeyID", principal.APIKeyID)
+		return nil, models.PermissionDeniedError
+	}
+
+	return principal, nil
+}
+
+// principalFromToken get principal by access token or api key
+func (a *AccountService) principalFromToken(ctx context.Context, accessToken string) (*models.Principal, error) {
+	log := hclog.Default()
+
+	if prefix, secret, ok := models.SplitAPIKey(accessToken); ok {
+		return a.principalForAPIKey(prefix, secret)
+	}
+
+	tok, err := a.tokenSrv.ParseJWT(
+		ctx,
+		accessToken,
+	)
+	if err != nil {
+		log.Error("[server.principalFromToken] a.tokenSrv.ParseJWT", "error", err)
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+
+	if err := a.tokenSrv.Validate(tok, models.AccessToken); err != nil {
+		log.Error("[server.principalFromToken] a.tokenSrv.Validate", "error", err)
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+
+	if clientID, ok := tok.Extra[models.ClientIDClaim].(string); ok && clientID == tok.Identity {
+		principal, err := a.principalForClient(clientID)
+		if err != nil {
+			return nil, err
+		}
+		principal.Token = tok
+
+		return principal, nil
+	}
+
+	user, err := a.db.GetUserByID(tok.Identity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+	if err != nil {
+		log.Error("[server.principalFromToken] a.db.GetUserByID", "uid", tok.Identity, "error", err)
+		return nil, models.InternalError
+	}
+	if user == nil {
+		return nil, models.UserNotFoundError
+	}
+	if user.IsDeactivated() {
+		return nil, models.AccountDeactivatedError
+	}
+
+	principal, err := a.principalForUser(user)
+	if err != nil {
+		log.Error("[server.principalFromToken] a.principalForUser", "uid", user.ID, "error", err)
+		return nil, models.InternalError
+	}
+	principal.Token = tok
+
+	return principal, nil
+}
+
+// principalForClient get principal of oauth client authenticated by client credentials with permissions of its role
+func (a *AccountService) principalForClient(clientID string) (*models.Principal, error) {
+	log := hclog.Default()
+
+	client, err := a.db.GetOAuthClientByID(clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UnauthenticatedAccessTokenError
+	}
+	if err != nil {
+		log.Error("[server.principalForClient] a.db.GetOAuthClientByID", "clientID", clientID, "error", err)
+		return nil, models.InternalError
+	}
+
+	permissions, err := a.db.GetRolesPermissions([]uint32{client.RoleID})
+	if err != nil {
+		log.Error("[server.principalForClient] a.db.GetRolesPermissions", "clientID", clientID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return &models.Principal{
+		ClientID:     client.ID,
+		DepartmentID: client.DepartmentID,
+		RoleID:       client.RoleID,
+		Roles:        []models.EffectiveRole{{RoleID: client.RoleID, Permissions: permissions[client.RoleID]}},
+		Permissions:  permissions[client.RoleID],
+	}, nil
+}
+
+// principalForUser get principal of user with effective roles
+func (a *AccountService) principalForUser(user *models.User) (*models.Principal, error) {
+	roles, err := a.effectiveRoles(user)
+	if err != nil {
+		return nil, fmt.Errorf("a.effectiveRoles error: %w", err)
+	}
+
+	return &models.Principal{
+		UserID:       user.ID,
+		Email:        user.Email,
+		DepartmentID: user.DepartmentID,
+		RoleID:       user.RoleID,
+		Roles:        roles,
+		Permissions:  models.GlobalPermissions(roles),
+	}, nil
+}
+
+// effectiveRoles primary role of user and its active role assignments with permissions
+func (a *AccountService) effectiveRoles(user *models.User) ([]models.EffectiveRole, error) {
+	assignments, err := a.db.GetActiveRoleAssignments(user.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("a.db.GetActiveRoleAssignments error: %w", err)
+	}
+
+	roles := make([]models.EffectiveRole, 0, len(assignments)+1)
+	roles = append(roles, models.EffectiveRole{RoleID: user.RoleID})
+	for _, assignment := range assignments {
+		roles = append(roles, models.EffectiveRole{
+			RoleID:       assignment.RoleID,
+			DepartmentID: assignment.DepartmentID,
+		})
+	}
+
+	roleIDs := make([]uint32, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.RoleID)
+	}
+
+	permissions, err := a.db.GetRolesPermissions(roleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("a.db.GetRolesPermissions error: %w", err)
+	}
+
+	for i := range roles {
+		roles[i].Permissions = permissions[roles[i].RoleID]
+	}
+
+	return roles, nil
+}
+
+// authorizeRole check principal holds all permissions of role, in department if it is set,
+// so caller can't grant role with permissions it doesn't have itself
+func (a *AccountService) authorizeRole(principal *models.Principal, roleID, departmentID uint32) error {
+	permissions, err := a.db.GetRolesPermissions([]uint32{roleID})
+	if err != nil {
+		hclog.Default().Error("[server.authorizeRole] a.db.GetRolesPermissions", "roleID", roleID, "error", err)
+		return models.InternalError
+	}
+
+	return a.authorizePermissions(principal, permissions[roleID], departmentID)
+}
+
+// authorizePermissions check principal holds all of permissions globally
+// or, if department is set, by roles scoped to department or its ancestors
+func (a *AccountService) authorizePermissions(principal *models.Principal, permissions []string, departmentID uint32) error {
+	log := hclog.Default()
+
+	var departmentIDs []uint32
+	if departmentID != 0 {
+		departments, err := a.db.GetDepartmentPath(departmentID)
+		if err != nil {
+			log.Error("[server.authorizePermissions] a.db.GetDepartmentPath", "departmentID", departmentID, "error", err)
+			return models.InternalError
+		}
+
+		for _, department := range departments {
+			departmentIDs = append(departmentIDs, department.ID)
+		}
+	}
+
+	if !principal.HasPermissionsIn(departmentIDs, permissions...) {
+		log.Error("[server.authorizePermissions] principal.HasPermissionsIn", "userID", principal.UserID, "departmentID", departmentID, "permissions", permissions)
+		return models.PermissionDeniedError
+	}
+
+	return nil
+}
+
+// authorize check caller of method has required permissions
+func (a *AccountService) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	required, ok := rpcPermissions[path.Base(fullMethod)]
+	if !ok {
+		return ctx, nil
+	}
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !principal.HasPermissions(required...) {
+		hclog.Default().Error("[server.authorize] principal.HasPermissions", "method", fullMethod, "userID", principal.UserID, "required", required)
+		return nil, models.PermissionDeniedError
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// UnaryAuthInterceptor check permissions of unary rpc, administrative calls are audited
+func (a *AccountService) UnaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	if principal, ok := principalFromContext(ctx); ok {
+		a.audit(principal, info.FullMethod, req, err)
+	}
+
+	return resp, err
+}
+
+// StreamAuthInterceptor check permissions of stream rpc, administrative calls are audited
+func (a *AccountService) StreamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	err = handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	if principal, ok := principalFromContext(ctx); ok {
+		a.audit(principal, info.FullMethod, nil, err)
+	}
+
+	return err
+}
+
+// authServerStream server stream with principal in context
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context context with principal
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	var engineering uint32 = 2
+
+	tests := []struct {
+		name   string
+		method string
+		// assignments of caller in addition to its primary employee role
+		assignments []models.RoleAssignment
+		err         error
+	}{
+		{name: "self-service rpc", method: "/account.AccountService/GetAccount"},
+		{name: "administrative rpc without permission", method: "/account.AccountService/CreateRole", err: models.PermissionDeniedError},
+		{
+			name: "administrative rpc with permission", method: "/account.AccountService/CreateRole",
+			assignments: []models.RoleAssignment{{RoleID: 2}},
+		},
+		{
+			name: "administrative rpc with permission scoped to department", method: "/account.AccountService/CreateRole",
+			assignments: []models.RoleAssignment{{RoleID: 2, DepartmentID: &engineering}}, err: models.PermissionDeniedError,
+		},
+		{name: "rpc requiring many permissions with one of them", method: "/account.AccountService/ListDepartmentUsers", err: models.PermissionDeniedError},
+		{
+			name: "rpc requiring many permissions granted by many roles", method: "/account.AccountService/ListDepartmentUsers",
+			assignments: []models.RoleAssignment{{RoleID: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "reader"})
+			db.rolePermissions[3] = []string{models.PermissionUsersRead}
+
+			user := db.addUser(models.User{Email: "caller@example.com", RoleID: 1})
+			for _, assignment := range tt.assignments {
+				assignment.UserID = user.ID
+				db.assignments = append(db.assignments, assignment)
+			}
+			a := newTestService(db, nil)
+
+			ctx, err := a.authorize(userContext(t, a, user), tt.method)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("authorize error = %v, want %v", err, tt.err)
+			}
+			if err == nil {
+				if got, ok := principalFromContext(ctx); !ok || got.UserID != user.ID {
+					t.Errorf("principal of context = %+v, want %s", got, user.ID)
+				}
+			}
+		})
+	}
+
+	// self-service rpc doesn't authenticate caller
+	_, err := newTestService(newFakeRepository(), nil).authorize(context.Background(), "/account.AccountService/GetAccount")
+	if err != nil {
+		t.Errorf("authorize without token error: %v", err)
+	}
+
+	_, err = newTestService(newFakeRepository(), nil).authorize(context.Background(), "/account.AccountService/CreateRole")
+	if !errors.Is(err, models.InvalidAccessTokenError) {
+		t.Errorf("authorize without token error = %v, want %v", err, models.InvalidAccessTokenError)
+	}
+}
+
+func TestRPCPermissions(t *testing.T) {
+	for method, permissions := range rpcPermissions {
+		if len(permissions) == 0 {
+			t.Errorf("rpc %s has no permissions", method)
+		}
+
+		for _, permission := range permissions {
+			if !models.IsKnownPermission(permission) {
+				t.Errorf("rpc %s requires unknown permission %s", method, permission)
+			}
+		}
+	}
+}
@@ -2,10 +2,14 @@ package server
 
 import (
 	"account-service/internal/models"
+	"account-service/internal/validators"
 	"comet/utils"
 	"context"
+	"errors"
 	"github.com/hashicorp/go-hclog"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
 	protos "protos/account"
 )
 
@@ -23,39 +27,171 @@ func (a *AccountService) GetAccountInfo(ctx context.Context, _ *emptypb.Empty) (
 		return nil, models.InvalidAccessTokenError
 	}
 
+	user, _, err := a.AccountInfo(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return userToProto(user), nil
+}
+
+// AccountInfo user of access token with the token
+func (a *AccountService) AccountInfo(ctx context.Context, accessToken string) (*models.User, *models.JWT, error) {
+	log := hclog.Default()
+
 	tok, err := a.tokenSrv.ParseJWT(
 		ctx,
 		accessToken,
 	)
 	if err != nil {
-		log.Error("[server.GetAccountInfo] a.tokenSrv.ParseJWT", "error", err)
-		return nil, models.UnauthenticatedAccessTokenError
+		log.Error("[server.AccountInfo] a.tokenSrv.ParseJWT", "error", err)
+		return nil, nil, models.UnauthenticatedAccessTokenError
 	}
 
 	if err := a.tokenSrv.Validate(tok, models.AccessToken); err != nil {
-		log.Error("[server.GetAccountInfo] a.tokenSrv.Validate", "error", err)
-		return nil, models.UnauthenticatedAccessTokenError
+		log.Error("[server.AccountInfo] a.tokenSrv.Validate", "error", err)
+		return nil, nil, models.UnauthenticatedAccessTokenError
 	}
 
 	user, err := a.db.GetUserByID(tok.Identity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, models.UserNotFoundError
+	}
 	if err != nil {
-		log.Error("[server.GetAccountInfo] a.db.GetUserByID", "uid", tok.Identity, "error", err)
-		return nil, models.InternalError
+		log.Error("[server.AccountInfo] a.db.GetUserByID", "uid", tok.Identity, "error", err)
+		return nil, nil, models.InternalError
 	}
 	if user == nil {
-		return nil, models.UserNotFoundError
+		return nil, nil, models.UserNotFoundError
 	}
 
+	return user, tok, nil
+}
+
+func userToProto(user *models.User) *protos.GetAccountInfoResponse {
 	return &protos.GetAccountInfoResponse{
-		UserId:       user.ID,
-		FirstName:    user.FirstName,
-		SecondName:   user.LastName,
-		Email:        user.Email,
-		DepartmentId: user.DepartmentID,
-		RoleId:       user.RoleID,
-	}, nil
+		UserId:        user.ID,
+		FirstName:     user.FirstName,
+		SecondName:    user.LastName,
+		Email:         user.Email,
+		DepartmentId:  user.DepartmentID,
+		RoleId:        user.RoleID,
+		EmailVerified: user.EmailVerified,
+		IsRegistered:  user.IsRegistered,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+		DeactivatedAt: optionalTimestamp(user.DeactivatedAt),
+	}
+}
+
+// selfServiceAccountFields fields which users can change in own account, key is field mask path
+var selfServiceAccountFields = map[string]string{
+	"first_name":  "first_name",
+	"second_name": "last_name",
 }
 
-//func (a *AccountService) GetAccountsInfo(ctx context.Context, _ *emptypb.Empty) (*protos.GetAccountsInfoResponse, error) {
-//
-//}
+// adminAccountFields fields of account which only users with users:write permission can change
+var adminAccountFields = map[string]string{
+	"department_id": "department_id",
+	"role_id":       "role_id",
+}
+
+// UpdateAccount update account fields listed in update mask if account was not changed since expected time,
+// role can be set only by other user holding permissions of role
+func (a *AccountService) UpdateAccount(ctx context.Context, rr *protos.UpdateAccountRequest) (*protos.GetAccountInfoResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "UpdateAccount")
+	defer span.End()
+
+	principal, err := a.authenticateUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := rr.GetUserId()
+	if userID == "" {
+		userID = principal.UserID
+	}
+
+	paths := rr.GetUpdateMask().GetPaths()
+	if len(paths) == 0 || rr.GetExpectedUpdatedAt() == nil {
+		return nil, models.BadRequestError
+	}
+
+	isAdmin := principal.HasPermissions(models.PermissionUsersWrite)
+	if userID != principal.UserID && !isAdmin {
+		return nil, models.PermissionDeniedError
+	}
+
+	fields := make(map[string]interface{}, len(paths))
+	for _, path := range paths {
+		if column, ok := selfServiceAccountFields[path]; ok {
+			value := rr.GetFirstName()
+			if path == "second_name" {
+				value = rr.GetSecondName()
+			}
+
+			err = validators.ValidateFIO(value)
+			if err != nil {
+				log.Error("[server.UpdateAccount] validators.ValidateFIO", "path", path, "error", err)
+				return nil, models.FioNotValidError
+			}
+
+			fields[column] = value
+			continue
+		}
+
+		column, ok := adminAccountFields[path]
+		if !ok {
+			return nil, models.BadRequestError
+		}
+		if !isAdmin {
+			return nil, models.PermissionDeniedError
+		}
+
+		switch path {
+		case "department_id":
+			department, err := a.db.GetUserDepartmentByID(rr.GetDepartmentId())
+			if err != nil || department == nil {
+				log.Error("[server.UpdateAccount] a.db.GetUserDepartmentByID", "departmentID", rr.GetDepartmentId(), "error", err)
+				return nil, models.DepartmentNotFoundError
+			}
+			fields[column] = department.ID
+		case "role_id":
+			// primary role of caller can't be changed by itself
+			if userID == principal.UserID {
+				log.Error("[server.UpdateAccount] caller changes its own role", "userID", userID)
+				return nil, models.PermissionDeniedError
+			}
+
+			role, err := a.db.GetUserRoleByID(rr.GetRoleId())
+			if err != nil || role == nil {
+				log.Error("[server.UpdateAccount] a.db.GetUserRoleByID", "roleID", rr.GetRoleId(), "error", err)
+				return nil, models.RoleNotFoundError
+			}
+
+			// primary role is global, so caller must hold its permissions globally
+			err = a.authorizeRole(principal, role.ID, 0)
+			if err != nil {
+				return nil, err
+			}
+			fields[column] = role.ID
+		}
+	}
+
+	user, err := a.db.UpdateUserByID(userID, fields, rr.GetExpectedUpdatedAt().AsTime())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.UserNotFoundError
+	}
+	if errors.Is(err, models.VersionConflictError) {
+		return nil, models.VersionConflictError
+	}
+	if err != nil {
+		log.Error("[server.UpdateAccount] a.db.UpdateUserByID", "userID", userID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return userToProto(user), nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"errors"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	protos "protos/account"
+	"testing"
+	"time"
+)
+
+func TestUpdateAccountRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		callerRole uint32
+		// self changes role of caller
+		self bool
+		role uint32
+		err  error
+	}{
+		{name: "admin sets admin", callerRole: 2, role: 2},
+		{name: "editor sets role with its permissions", callerRole: 3, role: 1},
+		{name: "editor can't set admin", callerRole: 3, role: 2, err: models.PermissionDeniedError},
+		{name: "admin can't change its own role", callerRole: 2, self: true, role: 1, err: models.PermissionDeniedError},
+		{name: "employee can't set role", callerRole: 1, role: 1, err: models.PermissionDeniedError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "editor"})
+			db.rolePermissions[3] = []string{models.PermissionUsersWrite, models.PermissionDepartmentsRead, models.PermissionRolesRead}
+
+			caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: tt.callerRole})
+			user := db.addUser(models.User{Email: "bob@example.com", DepartmentID: 1, RoleID: 3})
+			if tt.self {
+				user = caller
+			}
+			roleID := user.RoleID
+
+			a := newTestService(db, nil)
+			ctx := userContext(t, a, caller)
+
+			account, err := a.UpdateAccount(ctx, &protos.UpdateAccountRequest{
+				UserId:            user.ID,
+				RoleId:            tt.role,
+				UpdateMask:        &fieldmaskpb.FieldMask{Paths: []string{"role_id"}},
+				ExpectedUpdatedAt: timestamppb.New(time.Now()),
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if user.RoleID != roleID {
+					t.Errorf("role = %d, want unchanged %d", user.RoleID, roleID)
+				}
+				return
+			}
+
+			if account.GetRoleId() != tt.role || user.RoleID != tt.role {
+				t.Errorf("account = %+v, want role %d", account, tt.role)
+			}
+		})
+	}
+}
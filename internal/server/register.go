@@ -5,6 +5,7 @@ import (
 	"account-service/internal/validators"
 	"comet/utils"
 	"context"
+	"errors"
 	"github.com/hashicorp/go-hclog"
 	protos "protos/account"
 	"strings"
@@ -37,7 +38,32 @@ func (a *AccountService) RegisterUser(ctx context.Context, rr *protos.RegisterUs
 		return nil, models.UnauthenticatedAuthTokenError
 	}
 
+	invitation, err := a.invitationOfToken(tok)
+	if errors.Is(err, models.InvitationNotFoundError) {
+		return nil, models.InvitationNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RegisterUser] a.invitationOfToken", "error", err)
+		return nil, models.InternalError
+	}
+
 	email := strings.TrimSpace(strings.ToLower(rr.GetEmail()))
+	departmentID := rr.GetDepartmentId()
+	roleID := rr.GetRoleId()
+
+	// fields of invitation are pre-filled and can't be changed
+	if invitation != nil {
+		if (email != "" && email != invitation.Email) ||
+			(departmentID != 0 && departmentID != invitation.DepartmentID) ||
+			(roleID != 0 && roleID != invitation.RoleID) {
+			return nil, models.InvitationFieldLockedError
+		}
+
+		email = invitation.Email
+		departmentID = invitation.DepartmentID
+		roleID = invitation.RoleID
+	}
+
 	err = validators.ValidateEmail(email)
 	if err != nil {
 		log.Error("[server.RegisterUser] validators.ValidateEmail", "userID", tok.Identity, "email", email, "error", err)
@@ -68,20 +94,16 @@ func (a *AccountService) RegisterUser(ctx context.Context, rr *protos.RegisterUs
 	}
 
 	password := rr.GetPassword()
-	err = validators.ValidatePassword(password)
+	err = a.validatePassword("password", password, validators.PasswordContext{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+	})
 	if err != nil {
-		log.Error("[server.RegisterUser] validators.ValidatePassword", "error", err)
-		return nil, models.PasswordNotValidError(err)
-	}
-
-	b, err := utils.HashArgon(password)
-	if err != nil {
-		log.Error("[server.RegisterUser] utils.HashArgon", err)
-		return nil, models.InternalError
+		log.Error("[server.RegisterUser] a.validatePassword", "error", err)
+		return nil, err
 	}
-	hashPassword := string(b)
 
-	departmentID := rr.GetDepartmentId()
 	department, err := a.db.GetUserDepartmentByID(departmentID)
 	if err != nil {
 		log.Error("[server.RegisterUser] a.db.GetUserDepartmentByID", "error", err)
@@ -93,7 +115,6 @@ func (a *AccountService) RegisterUser(ctx context.Context, rr *protos.RegisterUs
 		return nil, models.DepartmentNotFoundError
 	}
 
-	roleID := rr.GetRoleId()
 	role, err := a.db.GetUserRoleByID(roleID)
 	if err != nil {
 		log.Error("[server.RegisterUser] a.db.GetUserRoleByID", "error", err)
@@ -105,12 +126,16 @@ func (a *AccountService) RegisterUser(ctx context.Context, rr *protos.RegisterUs
 		return nil, models.RoleNotFoundError
 	}
 
-	user, err := a.db.CreateUserIfNotExist(email, firstName, lastName, hashPassword, departmentID, roleID)
+	user, err := a.db.CreateUserIfNotExist(email, firstName, lastName, password, departmentID, roleID)
 	if err != nil {
 		log.Error("[server.RegisterUser] a.db.CreateUserIfNotExist", "error", err)
 		return nil, models.InternalError
 	}
 
+	if invitation != nil {
+		a.acceptInvitation(ctx, invitation, user)
+	}
+
 	return &protos.RegisterUserResponse{
 		Uuid:         user.ID,
 		Email:        user.Email,
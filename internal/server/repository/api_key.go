@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// AddAPIKey create api key
+func (r *Repository) AddAPIKey(key *models.APIKey) (*models.APIKey, error) {
+	result := r.DB.Create(key)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByPrefix get not revoked api key by its public prefix
+func (r *Repository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var resultKey models.APIKey
+	result := r.DB.Where("prefix = ?", prefix).First(&resultKey)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultKey, nil
+}
+
+// GetAPIKeyByID get not revoked api key by id
+func (r *Repository) GetAPIKeyByID(id string) (*models.APIKey, error) {
+	var resultKey models.APIKey
+	result := r.DB.Where("id = ?", id).First(&resultKey)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultKey, nil
+}
+
+// GetAPIKeys get all not revoked api keys ordered by name
+func (r *Repository) GetAPIKeys() ([]models.APIKey, error) {
+	var resultKeys []models.APIKey
+	result := r.DB.Order("name, id").Find(&resultKeys)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultKeys, nil
+}
+
+// UpdateAPIKeySecret replace hash of secret of api key
+func (r *Repository) UpdateAPIKeySecret(id, secretHash string) (*models.APIKey, error) {
+	var resultKey models.APIKey
+	result := r.DB.Model(&resultKey).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Update("secret_hash", secretHash)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Update error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &resultKey, nil
+}
+
+// SetAPIKeyUse set time of last use of api key
+func (r *Repository) SetAPIKeyUse(id string, moment time.Time) error {
+	result := r.DB.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", moment)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.UpdateColumn error: %w", result.Error)
+	}
+
+	return nil
+}
+
+// RemoveAPIKey soft delete api key, it is rejected afterwards
+func (r *Repository) RemoveAPIKey(id string) error {
+	result := r.DB.Where("id = ?", id).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
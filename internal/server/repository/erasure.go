@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// AddAuditEntry add audit entry
+func (r *Repository) AddAuditEntry(entry *models.AuditEntry) error {
+	result := r.DB.Create(entry)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return nil
+}
+
+// RequestUserErasure soft delete user if it is not deleted and mark its personal data to be erased without retention
+func (r *Repository) RequestUserErasure(id string) error {
+	now := time.Now()
+	result := r.DB.Unscoped().
+		Model(&models.User{}).
+		Where("id = ? AND erased_at IS NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"erasure_requested_at": now,
+			"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.UpdateColumns error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetErasureCandidates get not erased users deleted before moment or requested erasure with counts of their data
+func (r *Repository) GetErasureCandidates(deletedBefore time.Time, limit int) ([]models.ErasureCandidate, error) {
+	var candidates []models.ErasureCandidate
+	result := r.DB.Table("users").
+		Select(`users.id AS user_id, users.email, users.deleted_at, users.erasure_requested_at,
+			(SELECT COUNT(*) FROM tokens WHERE tokens.identity = users.id::text) AS tokens,
+			(SELECT COUNT(*) FROM password_histories WHERE password_histories.user_id = users.id) AS password_history,
+			(SELECT COUNT(*) FROM role_assignments WHERE role_assignments.user_id = users.id) AS role_assignments,
+			(SELECT COUNT(*) FROM audit_entries WHERE audit_entries.actor_id = users.id::text OR audit_entries.subject_id = users.id::text) AS audit_entries`).
+		Where("users.erased_at IS NULL").
+		// zero deleted_at of users written before soft delete doesn't mean user is deleted
+		Where("users.erasure_requested_at IS NOT NULL OR (users.deleted_at IS NOT NULL AND users.deleted_at > '0002-01-01' AND users.deleted_at < ?)", deletedBefore).
+		Order("users.deleted_at ASC, users.id ASC").
+		Limit(limit).
+		Scan(&candidates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Scan error: %w", result.Error)
+	}
+
+	return candidates, nil
+}
+
+// EraseUser erase personal data of user, tokens, password history, role assignments, consents,
+// linked identities and invitations of user are removed,
+// user id in audit entries, granted roles and sent invitations is replaced by pseudonym,
+// user row is removed in purge mode or kept with anonymized fields otherwise
+func (r *Repository) EraseUser(id, mode, pseudonym string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		result := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND erased_at IS NULL", id).
+			First(&user)
+		if result.Error != nil {
+			return fmt.Errorf("tx.First error: %w", result.Error)
+		}
+
+		for _, deletion := range []struct {
+			model     interface{}
+			condition string
+		}{
+			{&models.Token{}, "identity = ?"},
+			{&models.PasswordHistory{}, "user_id = ?"},
+			{&models.RoleAssignment{}, "user_id = ?"},
+			{&models.OAuthConsent{}, "user_id = ?"},
+			{&models.LinkedIdentity{}, "user_id = ?"},
+		} {
+			result = tx.Unscoped().Where(deletion.condition, id).Delete(deletion.model)
+			if result.Error != nil {
+				return fmt.Errorf("tx.Delete error: %w", result.Error)
+			}
+		}
+
+		// invitations addressed to user hold its email even if they were not accepted
+		result = tx.Where("email = ? OR accepted_user_id = ?", user.Email, id).Delete(&models.Invitation{})
+		if result.Error != nil {
+			return fmt.Errorf("tx.Delete invitations error: %w", result.Error)
+		}
+
+		for _, update := range []struct {
+			model   interface{}
+			column  string
+			columns map[string]interface{}
+		}{
+			{&models.RoleAssignment{}, "granted_by", map[string]interface{}{"granted_by": pseudonym}},
+			{&models.Invitation{}, "invited_by", map[string]interface{}{"invited_by": pseudonym}},
+			{&models.AuditEntry{}, "actor_id", map[string]interface{}{"actor_id": pseudonym, "actor_email": ""}},
+			{&models.AuditEntry{}, "subject_id", map[string]interface{}{"subject_id": pseudonym}},
+		} {
+			result = tx.Model(update.model).
+				Where(update.column+" = ?", id).
+				UpdateColumns(update.columns)
+			if result.Error != nil {
+				return fmt.Errorf("tx.UpdateColumns error: %w", result.Error)
+			}
+		}
+
+		if mode == models.ErasureModePurge {
+			result = tx.Unscoped().Delete(&user)
+			if result.Error != nil {
+				return fmt.Errorf("tx.Delete user error: %w", result.Error)
+			}
+
+			return nil
+		}
+
+		now := time.Now()
+		result = tx.Unscoped().
+			Model(&user).
+			UpdateColumns(map[string]interface{}{
+				"email":          fmt.Sprintf("erased-%s@invalid", user.ID),
+				"first_name":     "",
+				"last_name":      "",
+				"password":       "",
+				"email_verified": false,
+				"is_registered":  false,
+				"erased_at":      now,
+				"deleted_at":     gorm.Expr("COALESCE(deleted_at, ?)", now),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("tx.UpdateColumns user error: %w", result.Error)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+)
+
+// exportedUserColumns columns of exported user, names are taken by subqueries to keep filter columns unambiguous
+const exportedUserColumns = "id, email, first_name, last_name, department_id, role_id, is_registered, email_verified, created_at, updated_at, deactivated_at, " +
+	"(SELECT name FROM departments WHERE departments.id = users.department_id) AS department_name, " +
+	"(SELECT name FROM roles WHERE roles.id = users.role_id) AS role_name"
+
+// StreamUsers read all users matched by filter with database cursor and call fn for each user,
+// limit and cursor of filter are ignored
+func (r *Repository) StreamUsers(filter models.UserFilter, fn func(user *models.ExportedUser) error) error {
+	column, ok := userSortColumns[filter.SortBy]
+	if !ok {
+		return fmt.Errorf("invalid sort field: %s", filter.SortBy)
+	}
+
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	rows, err := r.filterUsers(filter).
+		Select(exportedUserColumns).
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Rows()
+	if err != nil {
+		return fmt.Errorf("r.DB.Rows error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.ExportedUser
+		err = r.DB.ScanRows(rows, &user)
+		if err != nil {
+			return fmt.Errorf("r.DB.ScanRows error: %w", err)
+		}
+
+		err = fn(&user)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// CreateUserWithIdentity create user provisioned by external provider with its linked identity,
+// password of user is empty so it can log in only by linked identities until password is set
+func (r *Repository) CreateUserWithIdentity(user *models.User, identity *models.LinkedIdentity) (*models.User, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Create(user)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Create user error: %w", result.Error)
+		}
+
+		identity.UserID = user.ID
+		result = tx.Create(identity)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Create identity error: %w", result.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// AddLinkedIdentity link external identity to user,
+// gorm.ErrDuplicatedKey is returned if identity is already linked
+func (r *Repository) AddLinkedIdentity(identity *models.LinkedIdentity) (*models.LinkedIdentity, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(identity)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrDuplicatedKey
+	}
+
+	return identity, nil
+}
+
+// GetLinkedIdentity get linked identity by provider and subject
+func (r *Repository) GetLinkedIdentity(provider, subject string) (*models.LinkedIdentity, error) {
+	var resultIdentity models.LinkedIdentity
+	result := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(&resultIdentity)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultIdentity, nil
+}
+
+// GetUserLinkedIdentities get linked identities of user, oldest first
+func (r *Repository) GetUserLinkedIdentities(userID string) ([]models.LinkedIdentity, error) {
+	var resultIdentities []models.LinkedIdentity
+	result := r.DB.Where("user_id = ?", userID).Order("created_at, id").Find(&resultIdentities)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultIdentities, nil
+}
+
+// SetLinkedIdentityLogin set time of last login by linked identity
+func (r *Repository) SetLinkedIdentityLogin(id uint, moment time.Time) error {
+	result := r.DB.Model(&models.LinkedIdentity{}).Where("id = ?", id).UpdateColumn("last_login_at", moment)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.UpdateColumn error: %w", result.Error)
+	}
+
+	return nil
+}
+
+// RemoveLinkedIdentity remove linked identity of user,
+// gorm.ErrRecordNotFound is returned if user has no such identity
+func (r *Repository) RemoveLinkedIdentity(userID string, id uint) error {
+	result := r.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.LinkedIdentity{})
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
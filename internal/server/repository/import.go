@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// errImportRollback rollback of import transaction in dry run or after failure in atomic mode
+var errImportRollback = errors.New("import is rolled back")
+
+// importSavePoint save point of row, failed row is rolled back to it
+const importSavePoint = "import_row"
+
+// importState departments and roles by name known to import
+type importState struct {
+	departments map[string]uint32
+	roles       map[string]uint32
+}
+
+// ImportUsers create users of valid rows in one transaction, failed rows are rolled back to save point,
+// in atomic mode first failure rolls back whole import and in dry run import is always rolled back
+func (r *Repository) ImportUsers(rows []models.ImportRow, options models.ImportOptions) (*models.ImportReport, error) {
+	report := &models.ImportReport{
+		DryRun:             options.DryRun,
+		CreatedDepartments: make([]string, 0),
+		CreatedRoles:       make([]string, 0),
+		Results:            make([]models.ImportResult, len(rows)),
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		state, err := loadImportState(tx)
+		if err != nil {
+			return err
+		}
+
+		failed := false
+		for i, row := range rows {
+			result := &report.Results[i]
+			result.Line = row.Line
+			result.Email = row.Email
+
+			if row.Error != "" {
+				result.Status = models.ImportFailed
+				result.Error = row.Error
+				failed = true
+				continue
+			}
+
+			if options.Atomic && failed {
+				result.Status = models.ImportSkipped
+				continue
+			}
+
+			result.Status = models.ImportCreated
+			if options.DryRun {
+				result.Status = models.ImportValid
+			}
+
+			err = tx.SavePoint(importSavePoint).Error
+			if err != nil {
+				return fmt.Errorf("tx.SavePoint error: %w", err)
+			}
+
+			userID, rowErr := r.importRow(tx, row, options, state, report)
+			if rowErr != nil {
+				err = tx.RollbackTo(importSavePoint).Error
+				if err != nil {
+					return fmt.Errorf("tx.RollbackTo error: %w", err)
+				}
+
+				result.Status = models.ImportFailed
+				result.Error = rowErr.Error()
+				failed = true
+				continue
+			}
+
+			if !options.DryRun {
+				result.UserID = userID
+			}
+		}
+
+		if options.DryRun || (options.Atomic && failed) {
+			return errImportRollback
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return nil, err
+	}
+	report.Committed = err == nil
+
+	for i := range report.Results {
+		result := &report.Results[i]
+		if !report.Committed && !options.DryRun && result.Status == models.ImportCreated {
+			result.Status = models.ImportRolledBack
+			result.UserID = ""
+		}
+
+		switch result.Status {
+		case models.ImportCreated, models.ImportValid:
+			report.Created++
+		case models.ImportFailed:
+			report.Failed++
+		}
+	}
+
+	if !report.Committed && !options.DryRun {
+		report.Created = 0
+		report.CreatedDepartments = make([]string, 0)
+		report.CreatedRoles = make([]string, 0)
+	}
+
+	return report, nil
+}
+
+// loadImportState get ids of all departments and roles by name
+func loadImportState(tx *gorm.DB) (*importState, error) {
+	var departments []models.Department
+	result := tx.Find(&departments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("tx.Find departments error: %w", result.Error)
+	}
+
+	var roles []models.Role
+	result = tx.Find(&roles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("tx.Find roles error: %w", result.Error)
+	}
+
+	state := &importState{
+		departments: make(map[string]uint32, len(departments)),
+		roles:       make(map[string]uint32, len(roles)),
+	}
+	for _, department := range departments {
+		state.departments[department.Name] = department.ID
+	}
+	for _, role := range roles {
+		state.roles[role.Name] = role.ID
+	}
+
+	return state, nil
+}
+
+// importRow create user of row and its missing department and role, state and report are changed only on success
+// because failed row is rolled back with everything it created
+func (r *Repository) importRow(tx *gorm.DB, row models.ImportRow, options models.ImportOptions, state *importState, report *models.ImportReport) (string, error) {
+	count := int64(0)
+	err := tx.Unscoped().Model(&models.User{}).
+		Where("email = ?", row.Email).
+		Count(&count).Error
+	if err != nil {
+		return "", fmt.Errorf("can't check email: %w", err)
+	}
+	if count > 0 {
+		return "", fmt.Errorf("email already exist")
+	}
+
+	departmentID, departmentCreated := state.departments[row.Department], false
+	if departmentID == 0 {
+		if !options.CreateMissing {
+			return "", fmt.Errorf("department not found: %s", row.Department)
+		}
+
+		department := models.Department{Name: row.Department}
+		result := tx.Create(&department)
+		if result.Error != nil {
+			return "", fmt.Errorf("can't create department: %w", result.Error)
+		}
+		departmentID, departmentCreated = department.ID, true
+	}
+
+	roleID, roleCreated := state.roles[row.Role], false
+	if roleID == 0 {
+		if !options.CreateMissing {
+			return "", fmt.Errorf("role not found: %s", row.Role)
+		}
+
+		role := models.Role{Name: row.Role}
+		result := tx.Create(&role)
+		if result.Error != nil {
+			return "", fmt.Errorf("can't create role: %w", result.Error)
+		}
+		roleID, roleCreated = role.ID, true
+	}
+
+	user := models.User{
+		Email:        row.Email,
+		FirstName:    row.FirstName,
+		LastName:     row.LastName,
+		Password:     row.PasswordHash,
+		DepartmentID: departmentID,
+		RoleID:       roleID,
+		IsRegistered: row.PasswordHash != "",
+	}
+	result := tx.Create(&user)
+	if result.Error != nil {
+		return "", fmt.Errorf("can't create user: %w", result.Error)
+	}
+
+	if departmentCreated {
+		state.departments[row.Department] = departmentID
+		report.CreatedDepartments = append(report.CreatedDepartments, row.Department)
+	}
+	if roleCreated {
+		state.roles[row.Role] = roleID
+		report.CreatedRoles = append(report.CreatedRoles, row.Role)
+	}
+
+	return user.ID, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddInvitation add invitation
+func (r *Repository) AddInvitation(invitation *models.Invitation) (*models.Invitation, error) {
+	result := r.DB.Create(invitation)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return invitation, nil
+}
+
+// GetInvitationByID get invitation by id
+func (r *Repository) GetInvitationByID(id string) (*models.Invitation, error) {
+	var resultInvitation models.Invitation
+	result := r.DB.Where("id = ?", id).First(&resultInvitation)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultInvitation, nil
+}
+
+// GetInvitations get invitations with status or all invitations if status is empty, newest first
+func (r *Repository) GetInvitations(status string) ([]models.Invitation, error) {
+	query := r.DB.Order("created_at DESC, id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var resultInvitations []models.Invitation
+	result := query.Find(&resultInvitations)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultInvitations, nil
+}
+
+// PendingInvitationExist check is there pending not expired invitation for email
+func (r *Repository) PendingInvitationExist(email string) (bool, error) {
+	count := int64(0)
+	err := r.DB.Model(&models.Invitation{}).
+		Where("email = ? AND status = ? AND expires_at > now()", email, models.InvitationPending).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("r.DB.Count error: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// UpdatePendingInvitation update fields of invitation if it is still pending,
+// gorm.ErrRecordNotFound is returned if invitation is not found or not pending
+func (r *Repository) UpdatePendingInvitation(id string, fields map[string]interface{}) (*models.Invitation, error) {
+	var resultInvitation models.Invitation
+	result := r.DB.Model(&resultInvitation).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, models.InvitationPending).
+		Updates(fields)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Updates error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &resultInvitation, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// userSearchVector full text search vector of user, must be same as in users search index
+const userSearchVector = "to_tsvector('simple', first_name || ' ' || last_name || ' ' || email)"
+
+// userSortColumns columns of users by sort field
+var userSortColumns = map[string]string{
+	models.UserSortCreatedAt: "created_at",
+	models.UserSortEmail:     "email",
+	models.UserSortLastName:  "last_name",
+}
+
+// CreateIndexes create indexes which can't be described by model tags
+// email substring search uses trigram index, because leading wildcard of ILIKE can't use btree or full text index
+func (r *Repository) CreateIndexes() error {
+	statements := []string{
+		"CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (" + userSearchVector + ")",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops)",
+	}
+	for _, statement := range statements {
+		result := r.DB.Exec(statement)
+		if result.Error != nil {
+			return fmt.Errorf("r.DB.Exec error: %w", result.Error)
+		}
+	}
+
+	return nil
+}
+
+// ListUsers get page of users by filter sorted by filter sort field and id
+func (r *Repository) ListUsers(filter models.UserFilter) ([]models.User, error) {
+	column, ok := userSortColumns[filter.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field: %s", filter.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := r.filterUsers(filter)
+
+	if filter.After != nil {
+		var value interface{} = filter.After.Value
+		if filter.SortBy == models.UserSortCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339Nano, filter.After.Value)
+			if err != nil {
+				return nil, fmt.Errorf("time.Parse cursor error: %w", err)
+			}
+			value = createdAt
+		}
+
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, filter.After.ID)
+	}
+
+	var resultUsers []models.User
+	result := query.
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(filter.Limit).
+		Find(&resultUsers)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultUsers, nil
+}
+
+// filterUsers query of users matched by filter conditions
+func (r *Repository) filterUsers(filter models.UserFilter) *gorm.DB {
+	query := r.DB.Model(&models.User{})
+
+	if filter.Query != "" {
+		tsQuery := prefixTsQuery(filter.Query)
+		if tsQuery != "" {
+			query = query.Where("("+userSearchVector+" @@ to_tsquery('simple', ?) OR email ILIKE ?)", tsQuery, "%"+escapeLike(filter.Query)+"%")
+		} else {
+			query = query.Where("email ILIKE ?", "%"+escapeLike(filter.Query)+"%")
+		}
+	}
+
+	if filter.DepartmentID != 0 {
+		if filter.IncludeSubdepartments {
+			query = query.Where("department_id IN (?)", gorm.Expr(departmentSubtreeQuery, filter.DepartmentID))
+		} else {
+			query = query.Where("department_id = ?", filter.DepartmentID)
+		}
+	}
+
+	if filter.RoleID != 0 {
+		query = query.Where("role_id = ?", filter.RoleID)
+	}
+
+	if filter.IsRegistered != nil {
+		query = query.Where("is_registered = ?", *filter.IsRegistered)
+	}
+
+	if filter.EmailVerified != nil {
+		query = query.Where("email_verified = ?", *filter.EmailVerified)
+	}
+
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	return query
+}
+
+// prefixTsQuery tsquery matching all words of search query by prefix
+func prefixTsQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, word+":*")
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// escapeLike escape wildcards of LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// AddOAuthClient add oauth client
+func (r *Repository) AddOAuthClient(client *models.OAuthClient) (*models.OAuthClient, error) {
+	result := r.DB.Create(client)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return client, nil
+}
+
+// GetOAuthClientByID get oauth client by id
+func (r *Repository) GetOAuthClientByID(id string) (*models.OAuthClient, error) {
+	var resultClient models.OAuthClient
+	result := r.DB.Where("id = ?", id).First(&resultClient)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultClient, nil
+}
+
+// GetOAuthClients get all oauth clients ordered by name
+func (r *Repository) GetOAuthClients() ([]models.OAuthClient, error) {
+	var resultClients []models.OAuthClient
+	result := r.DB.Order("name, id").Find(&resultClients)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultClients, nil
+}
+
+// UpdateOAuthClientSecret replace secret hash of confidential oauth client,
+// gorm.ErrRecordNotFound is returned if client is not found or is public
+func (r *Repository) UpdateOAuthClientSecret(id, secretHash string) (*models.OAuthClient, error) {
+	var resultClient models.OAuthClient
+	result := r.DB.Model(&resultClient).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND secret_hash <> ''", id).
+		Update("secret_hash", secretHash)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Update error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &resultClient, nil
+}
+
+// RemoveOAuthClient soft delete oauth client and remove consents of users for it
+func (r *Repository) RemoveOAuthClient(id string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&models.OAuthClient{})
+		if result.Error != nil {
+			return fmt.Errorf("tx.Delete error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		result = tx.Where("client_id = ?", id).Delete(&models.OAuthConsent{})
+		if result.Error != nil {
+			return fmt.Errorf("tx.Delete consents error: %w", result.Error)
+		}
+
+		return nil
+	})
+}
+
+// GetConsent get consent of user for client, nil is returned if user has no consent
+func (r *Repository) GetConsent(userID, clientID string) (*models.OAuthConsent, error) {
+	var resultConsents []models.OAuthConsent
+	result := r.DB.Where("user_id = ? AND client_id = ?", userID, clientID).Limit(1).Find(&resultConsents)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+	if len(resultConsents) == 0 {
+		return nil, nil
+	}
+
+	return &resultConsents[0], nil
+}
+
+// SaveConsent create consent of user for client or replace its scopes
+func (r *Repository) SaveConsent(userID, clientID, scopes string) (*models.OAuthConsent, error) {
+	consent := &models.OAuthConsent{
+		UserID:   userID,
+		ClientID: clientID,
+		Scopes:   scopes,
+	}
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"scopes":     scopes,
+			"updated_at": time.Now(),
+		}),
+	}).Create(consent)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return consent, nil
+}
+
+// GetUserConsents get consents of user with names of clients, newest first
+func (r *Repository) GetUserConsents(userID string) ([]models.ConsentWithClient, error) {
+	var resultConsents []models.ConsentWithClient
+	result := r.DB.Model(&models.OAuthConsent{}).
+		Select("oauth_consents.*, oauth_clients.name AS client_name").
+		Joins("LEFT JOIN oauth_clients ON oauth_clients.id = oauth_consents.client_id").
+		Where("oauth_consents.user_id = ?", userID).
+		Order("oauth_consents.updated_at DESC, oauth_consents.id").
+		Scan(&resultConsents)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Scan error: %w", result.Error)
+	}
+
+	return resultConsents, nil
+}
+
+// RemoveConsent remove consent of user for client,
+// gorm.ErrRecordNotFound is returned if user has no consent
+func (r *Repository) RemoveConsent(userID, clientID string) error {
+	result := r.DB.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.OAuthConsent{})
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+)
+
+// GetRetainedUserByID get user by id including soft deleted user whose data is held until erasure
+func (r *Repository) GetRetainedUserByID(id string) (*models.User, error) {
+	var resultUser models.User
+	result := r.DB.Unscoped().Where("id = ? AND erased_at IS NULL", id).First(&resultUser)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultUser, nil
+}
+
+// GetUserTokens get all tokens of user including revoked
+func (r *Repository) GetUserTokens(userID string) ([]models.Token, error) {
+	var resultTokens []models.Token
+	result := r.DB.Where("identity = ?", userID).
+		Order("created_at").
+		Find(&resultTokens)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultTokens, nil
+}
+
+// GetUserAuditEntries get audit entries where user is actor or subject
+func (r *Repository) GetUserAuditEntries(userID string) ([]models.AuditEntry, error) {
+	var resultEntries []models.AuditEntry
+	result := r.DB.Where("actor_id = ? OR subject_id = ?", userID, userID).
+		Order("created_at, id").
+		Find(&resultEntries)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultEntries, nil
+}
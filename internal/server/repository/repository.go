@@ -1,35 +1,55 @@
 package repository
 
 import (
+	"account-service/config"
+	"account-service/internal/hasher"
 	"account-service/internal/models"
-	"comet/utils"
 	"fmt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"time"
+)
+
+// departmentSubtreeQuery select ids of department and all its descendants,
+// UNION stops recursion even if tree has cycle
+const departmentSubtreeQuery = `WITH RECURSIVE subtree AS (
+		SELECT id FROM departments WHERE id = ?
+		UNION
+		SELECT d.id FROM departments d JOIN subtree s ON d.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
+const (
+	// departmentMoveLock key of advisory lock serializing moves of departments
+	departmentMoveLock = 0x64657074
+	// maxDepartmentDepth max depth of ancestors query, it stops recursion if tree has cycle
+	maxDepartmentDepth = 1000
 )
 
 type Repository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	cfg    *config.Config
+	hasher *hasher.Hasher
 }
 
 // NewRepository create new Repository
-func NewRepository(db *gorm.DB) *Repository {
+func NewRepository(db *gorm.DB, cfg *config.Config, h *hasher.Hasher) *Repository {
 	return &Repository{
-		DB: db,
+		DB:     db,
+		cfg:    cfg,
+		hasher: h,
 	}
 }
 
 // CreateUserIfNotExist create user if not exist by email
 func (r *Repository) CreateUserIfNotExist(email, firstName, lastName, password string, departmentID, roleID uint32) (*models.User, error) {
 	var resultUser models.User
-	b, err := utils.HashArgon(password)
+	hashPassword, err := r.hasher.Hash(password)
 
 	if err != nil {
-		return nil, fmt.Errorf("utils.HashArgon error: %w", err)
+		return nil, fmt.Errorf("r.hasher.Hash error: %w", err)
 	}
 
-	hashPassword := string(b)
-
 	result := r.DB.Where(models.User{
 		Email: email,
 	}).Attrs(models.User{
@@ -51,7 +71,7 @@ func (r *Repository) CreateUserIfNotExist(email, firstName, lastName, password s
 // GetUserByID get user by id
 func (r *Repository) GetUserByID(id string) (*models.User, error) {
 	var resultUser models.User
-	result := r.DB.Where(models.User{ID: id}).First(&resultUser)
+	result := r.DB.Where("id = ?", id).First(&resultUser)
 
 	if result.Error != nil {
 		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
@@ -60,10 +80,22 @@ func (r *Repository) GetUserByID(id string) (*models.User, error) {
 	return &resultUser, nil
 }
 
+// GetUsersByIDs get users by ids, not existing ids are skipped
+func (r *Repository) GetUsersByIDs(ids []string) ([]models.User, error) {
+	var resultUsers []models.User
+	result := r.DB.Where("id IN ?", ids).Find(&resultUsers)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultUsers, nil
+}
+
 // GetUserByEmail get user by id
 func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
 	var resultUser models.User
-	result := r.DB.Where(models.User{Email: email}).First(&resultUser)
+	result := r.DB.Where("email = ?", email).First(&resultUser)
 
 	if result.Error != nil {
 		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
@@ -72,30 +104,179 @@ func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
 	return &resultUser, nil
 }
 
-// ChangePasswordByID change password by id
+// ChangePasswordByID change password by id, previous password is kept in history
+// and models.PasswordReusedError is returned if password is one of recent passwords
 func (r *Repository) ChangePasswordByID(id, password string) (*models.User, error) {
-	b, err := utils.HashArgon(password)
+	hashPassword, err := r.hasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("r.hasher.Hash error: %w", err)
+	}
+
+	var resultUser models.User
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).
+			First(&resultUser)
+		if result.Error != nil {
+			return fmt.Errorf("tx.First error: %w", result.Error)
+		}
+
+		isReused, err := r.isRecentPassword(tx, &resultUser, password)
+		if err != nil {
+			return fmt.Errorf("r.isRecentPassword error: %w", err)
+		}
+		if isReused {
+			return models.PasswordReusedError
+		}
+
+		if resultUser.Password != "" {
+			result = tx.Create(&models.PasswordHistory{
+				UserID: resultUser.ID,
+				Hash:   resultUser.Password,
+			})
+			if result.Error != nil {
+				return fmt.Errorf("tx.Create error: %w", result.Error)
+			}
+		}
+
+		err = r.prunePasswordHistory(tx, resultUser.ID)
+		if err != nil {
+			return fmt.Errorf("r.prunePasswordHistory error: %w", err)
+		}
+
+		result = tx.Model(&resultUser).
+			Clauses(clause.Returning{}).
+			Update("password", hashPassword)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Update error: %w", result.Error)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("utils.HashArgon error: %w", err)
+		return nil, err
+	}
+
+	return &resultUser, nil
+}
+
+// UpdatePasswordHash replace hash of same password if it is not changed concurrently,
+// updated_at is kept because password is not changed
+func (r *Repository) UpdatePasswordHash(id, oldHash, newHash string) error {
+	result := r.DB.Model(&models.User{}).
+		Where("id = ? AND password = ?", id, oldHash).
+		UpdateColumn("password", newHash)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.UpdateColumn error: %w", result.Error)
+	}
+
+	return nil
+}
+
+// isRecentPassword check password is current or one of previous passwords within history size
+func (r *Repository) isRecentPassword(tx *gorm.DB, user *models.User, password string) (bool, error) {
+	if r.cfg.PasswordHistorySize <= 0 {
+		return false, nil
+	}
+
+	hashes := []string{user.Password}
+
+	var history []models.PasswordHistory
+	result := tx.Where("user_id = ? AND created_at >= ?", user.ID, time.Now().Add(-r.cfg.PasswordHistoryRetention)).
+		Order("created_at DESC").
+		Limit(r.cfg.PasswordHistorySize - 1).
+		Find(&history)
+	if result.Error != nil {
+		return false, fmt.Errorf("tx.Find error: %w", result.Error)
+	}
+
+	for _, entry := range history {
+		hashes = append(hashes, entry.Hash)
+	}
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+
+		isValid, err := r.hasher.Verify(hash, password)
+		if err != nil {
+			return false, fmt.Errorf("r.hasher.Verify error: %w", err)
+		}
+		if isValid {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// prunePasswordHistory remove previous passwords out of retention or history size
+func (r *Repository) prunePasswordHistory(tx *gorm.DB, userID string) error {
+	result := tx.Where("user_id = ? AND created_at < ?", userID, time.Now().Add(-r.cfg.PasswordHistoryRetention)).
+		Delete(&models.PasswordHistory{})
+	if result.Error != nil {
+		return fmt.Errorf("tx.Delete expired error: %w", result.Error)
+	}
+
+	keep := r.cfg.PasswordHistorySize - 1
+	if keep < 0 {
+		keep = 0
+	}
+
+	result = tx.Where("user_id = ? AND id NOT IN (?)", userID,
+		tx.Model(&models.PasswordHistory{}).
+			Select("id").
+			Where("user_id = ?", userID).
+			Order("created_at DESC").
+			Limit(keep),
+	).Delete(&models.PasswordHistory{})
+	if result.Error != nil {
+		return fmt.Errorf("tx.Delete overflow error: %w", result.Error)
 	}
-	hashPassword := string(b)
+
+	return nil
+}
+
+// UpdateUserByID update fields of user if it was not changed since expectedUpdatedAt
+func (r *Repository) UpdateUserByID(id string, fields map[string]interface{}, expectedUpdatedAt time.Time) (*models.User, error) {
+	updates := make(map[string]interface{}, len(fields)+1)
+	for column, value := range fields {
+		updates[column] = value
+	}
+	updates["updated_at"] = time.Now()
 
 	var users []models.User
 	result := r.DB.Model(&users).
 		Clauses(clause.Returning{}).
-		Where("id = ?", id).
-		Update("password", hashPassword)
+		Where("id = ? AND updated_at = ?", id, expectedUpdatedAt).
+		Updates(updates)
 	if result.Error != nil {
-		return nil, fmt.Errorf("r.DB.Update error: %w", result.Error)
+		return nil, fmt.Errorf("r.DB.Updates error: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		count := int64(0)
+		err := r.DB.Model(&models.User{}).
+			Where("id = ?", id).
+			Count(&count).Error
+		if err != nil {
+			return nil, fmt.Errorf("r.DB.Count error: %w", err)
+		}
+		if count == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+
+		return nil, models.VersionConflictError
 	}
 
 	return &users[0], nil
 }
 
-// EmailExist check is email exist or not
+// EmailExist check is email exist or not, emails of soft deleted users are taken until they are erased
 func (r *Repository) EmailExist(email string) (bool, error) {
 	count := int64(0)
-	err := r.DB.Model(&models.User{}).
+	err := r.DB.Unscoped().Model(&models.User{}).
 		Where("email = ?", email).
 		Count(&count).Error
 
@@ -106,36 +287,188 @@ func (r *Repository) EmailExist(email string) (bool, error) {
 	return count > 0, nil
 }
 
-// RemoveUserByID remove user by id
+// RemoveUserByID soft delete user by id
 func (r *Repository) RemoveUserByID(id string) error {
-
-	result := r.DB.Model(&models.User{}).Delete("id = ?", id)
+	result := r.DB.Where("id = ?", id).Delete(&models.User{})
 	if result.Error != nil {
 		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
 	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
 
 	return nil
 }
 
-// AddUserDepartment add new department if not exist
-func (r *Repository) AddUserDepartment(name string) (*models.Department, error) {
-	var resultDepartment models.Department
-	result := r.DB.Where(models.Department{Name: name}).FirstOrCreate(&resultDepartment)
+// MigrateDeletedAt set deleted_at of users written before soft delete to NULL,
+// such users have zero time which hides them from soft delete scope, migration can be run many times
+func (r *Repository) MigrateDeletedAt() error {
+	// zero time is compared by range because it can be shifted by time zone of session
+	result := r.DB.Exec("UPDATE users SET deleted_at = NULL WHERE deleted_at < '0002-01-01'")
 	if result.Error != nil {
-		return nil, fmt.Errorf("r.DB.FirstOrCreate error: %w", result.Error)
+		return fmt.Errorf("r.DB.Exec error: %w", result.Error)
 	}
 
-	return &resultDepartment, nil
+	return nil
 }
 
-// RemoveUserDepartment remove user department by id
-func (r *Repository) RemoveUserDepartment(id uint32) error {
-	result := r.DB.Delete(models.Department{ID: id})
+// RestoreUserByID restore soft deleted user by id, requested erasure is cancelled and erased user is not restored
+func (r *Repository) RestoreUserByID(id string) (*models.User, error) {
+	var resultUser models.User
+	result := r.DB.Unscoped().
+		Model(&resultUser).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND erased_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at":           nil,
+			"erasure_requested_at": nil,
+		})
 	if result.Error != nil {
-		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+		return nil, fmt.Errorf("r.DB.Updates error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
 	}
 
-	return nil
+	return &resultUser, nil
+}
+
+// SetUserDeactivated deactivate or reactivate user by id
+func (r *Repository) SetUserDeactivated(id string, deactivated bool) (*models.User, error) {
+	var deactivatedAt *time.Time
+	if deactivated {
+		now := time.Now()
+		deactivatedAt = &now
+	}
+
+	var resultUser models.User
+	result := r.DB.Model(&resultUser).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Update("deactivated_at", deactivatedAt)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Update error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &resultUser, nil
+}
+
+// AddUserDepartment add new department, models.AlreadyExistError is returned if department with name exists
+func (r *Repository) AddUserDepartment(name string, parentID *uint32) (*models.Department, error) {
+	resultDepartment := models.Department{Name: name, ParentID: parentID}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		count := int64(0)
+		err := tx.Model(&models.Department{}).
+			Where("name = ?", name).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("tx.Count error: %w", err)
+		}
+		if count > 0 {
+			return models.AlreadyExistError
+		}
+
+		result := tx.Create(&resultDepartment)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Create error: %w", result.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultDepartment, nil
+}
+
+// RenameUserDepartment rename department by id
+func (r *Repository) RenameUserDepartment(id uint32, name string) (*models.Department, error) {
+	var resultDepartment models.Department
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		count := int64(0)
+		err := tx.Model(&models.Department{}).
+			Where("name = ? AND id <> ?", name, id).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("tx.Count error: %w", err)
+		}
+		if count > 0 {
+			return models.AlreadyExistError
+		}
+
+		result := tx.Model(&resultDepartment).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Update("name", name)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Update error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultDepartment, nil
+}
+
+// RemoveUserDepartment remove user department by id, users of department are moved to reassignID if it is not zero,
+// soft deleted users are moved too so they can be restored
+func (r *Repository) RemoveUserDepartment(id, reassignID uint32) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if reassignID != 0 {
+			for _, model := range []interface{}{&models.User{}, &models.RoleAssignment{}} {
+				result := tx.Unscoped().Model(model).
+					Where("department_id = ?", id).
+					Update("department_id", reassignID)
+				if result.Error != nil {
+					return fmt.Errorf("tx.Update error: %w", result.Error)
+				}
+			}
+		}
+
+		for _, model := range []interface{}{&models.User{}, &models.RoleAssignment{}} {
+			count := int64(0)
+			err := tx.Unscoped().Model(model).
+				Where("department_id = ?", id).
+				Count(&count).Error
+			if err != nil {
+				return fmt.Errorf("tx.Count error: %w", err)
+			}
+			if count > 0 {
+				return models.DepartmentInUseError
+			}
+		}
+
+		count := int64(0)
+		err := tx.Model(&models.Department{}).
+			Where("parent_id = ?", id).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("tx.Count error: %w", err)
+		}
+		if count > 0 {
+			return models.DepartmentHasChildrenError
+		}
+
+		result := tx.Delete(models.Department{ID: id})
+		if result.Error != nil {
+			return fmt.Errorf("tx.Delete error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 // GetUserDepartmentByID get department by id
@@ -149,6 +482,93 @@ func (r *Repository) GetUserDepartmentByID(id uint32) (*models.Department, error
 	return &resultDepartment, nil
 }
 
+// MoveUserDepartment set parent of department, nil parentID makes department a root
+func (r *Repository) MoveUserDepartment(id uint32, parentID *uint32) (*models.Department, error) {
+	var resultDepartment models.Department
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// concurrent moves could create cycle which neither of them sees
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", departmentMoveLock).Error
+		if err != nil {
+			return fmt.Errorf("tx.Exec lock error: %w", err)
+		}
+
+		if parentID != nil {
+			count := int64(0)
+			err := tx.Model(&models.Department{}).
+				Where("id = ? AND id IN (?)", *parentID, gorm.Expr(departmentSubtreeQuery, id)).
+				Count(&count).Error
+			if err != nil {
+				return fmt.Errorf("tx.Count error: %w", err)
+			}
+			if count > 0 {
+				return models.DepartmentCycleError
+			}
+		}
+
+		result := tx.Model(&resultDepartment).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Update("parent_id", parentID)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Update error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultDepartment, nil
+}
+
+// GetDepartmentPath get departments from root to department with id
+func (r *Repository) GetDepartmentPath(id uint32) ([]models.Department, error) {
+	var resultDepartments []models.Department
+	result := r.DB.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, name, parent_id, 0 AS depth FROM departments WHERE id = ?
+			UNION ALL
+			SELECT d.id, d.name, d.parent_id, a.depth + 1 FROM departments d JOIN ancestors a ON d.id = a.parent_id
+			WHERE a.depth < ?
+		)
+		SELECT id, name, parent_id FROM ancestors ORDER BY depth DESC`, id, maxDepartmentDepth).
+		Scan(&resultDepartments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Raw error: %w", result.Error)
+	}
+
+	return resultDepartments, nil
+}
+
+// GetDepartmentSubtree get department with id and all its descendants
+func (r *Repository) GetDepartmentSubtree(id uint32) ([]models.Department, error) {
+	var resultDepartments []models.Department
+	result := r.DB.Where("id IN (?)", gorm.Expr(departmentSubtreeQuery, id)).
+		Order("id").
+		Find(&resultDepartments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultDepartments, nil
+}
+
+// GetDepartmentSubtreeUsers get users of department with id and all its descendants
+func (r *Repository) GetDepartmentSubtreeUsers(id uint32) ([]models.User, error) {
+	var resultUsers []models.User
+	result := r.DB.Where("department_id IN (?)", gorm.Expr(departmentSubtreeQuery, id)).
+		Order("last_name, first_name").
+		Find(&resultUsers)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultUsers, nil
+}
+
 // GetUserDepartments get all departments
 func (r *Repository) GetUserDepartments() (*[]models.Department, error) {
 	var resultDepatments []models.Department
@@ -161,31 +581,120 @@ func (r *Repository) GetUserDepartments() (*[]models.Department, error) {
 	return &resultDepatments, nil
 }
 
-// AddUserRole add new department if not exist
+// AddUserRole add new role, models.AlreadyExistError is returned if role with name exists
 func (r *Repository) AddUserRole(name string) (*models.Role, error) {
-	var resultRole models.Role
-	result := r.DB.Where(models.Role{Name: name}).FirstOrCreate(&resultRole)
-	if result.Error != nil {
-		return nil, fmt.Errorf("r.DB.FirstOrCreate error: %w", result.Error)
+	resultRole := models.Role{Name: name}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		count := int64(0)
+		err := tx.Model(&models.Role{}).
+			Where("name = ?", name).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("tx.Count error: %w", err)
+		}
+		if count > 0 {
+			return models.AlreadyExistError
+		}
+
+		result := tx.Create(&resultRole)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Create error: %w", result.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &resultRole, nil
 }
 
-// RemoveUserRole remove user department by id
-func (r *Repository) RemoveUserRole(id uint32) error {
-	result := r.DB.Delete(models.Role{ID: id})
-	if result.Error != nil {
-		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+// RenameUserRole rename role by id
+func (r *Repository) RenameUserRole(id uint32, name string) (*models.Role, error) {
+	var resultRole models.Role
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		count := int64(0)
+		err := tx.Model(&models.Role{}).
+			Where("name = ? AND id <> ?", name, id).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("tx.Count error: %w", err)
+		}
+		if count > 0 {
+			return models.AlreadyExistError
+		}
+
+		result := tx.Model(&resultRole).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Update("name", name)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Update error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &resultRole, nil
+}
+
+// RemoveUserRole remove user role by id, users of role are moved to reassignID if it is not zero,
+// soft deleted users are moved too so they can be restored
+func (r *Repository) RemoveUserRole(id, reassignID uint32) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if reassignID != 0 {
+			for _, model := range []interface{}{&models.User{}, &models.RoleAssignment{}} {
+				result := tx.Unscoped().Model(model).
+					Where("role_id = ?", id).
+					Update("role_id", reassignID)
+				if result.Error != nil {
+					return fmt.Errorf("tx.Update error: %w", result.Error)
+				}
+			}
+		}
+
+		for _, model := range []interface{}{&models.User{}, &models.RoleAssignment{}} {
+			count := int64(0)
+			err := tx.Unscoped().Model(model).
+				Where("role_id = ?", id).
+				Count(&count).Error
+			if err != nil {
+				return fmt.Errorf("tx.Count error: %w", err)
+			}
+			if count > 0 {
+				return models.RoleInUseError
+			}
+		}
+
+		role := models.Role{ID: id}
+		err := tx.Model(&role).Association("Permissions").Clear()
+		if err != nil {
+			return fmt.Errorf("tx.Association clear error: %w", err)
+		}
+
+		result := tx.Delete(&role)
+		if result.Error != nil {
+			return fmt.Errorf("tx.Delete error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 // GetUserRoleByID get department by id
 func (r *Repository) GetUserRoleByID(id uint32) (*models.Role, error) {
 	var resultRole models.Role
-	result := r.DB.Where("id = ? ", id).First(&resultRole)
+	result := r.DB.Preload("Permissions").Where("id = ? ", id).First(&resultRole)
 	if result.Error != nil {
 		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
 	}
@@ -196,7 +705,7 @@ func (r *Repository) GetUserRoleByID(id uint32) (*models.Role, error) {
 // GetUserRoles get all departments
 func (r *Repository) GetUserRoles() (*[]models.Role, error) {
 	var resultRoles []models.Role
-	result := r.DB.Find(&resultRoles)
+	result := r.DB.Preload("Permissions").Find(&resultRoles)
 
 	if result.Error != nil {
 		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
@@ -204,3 +713,137 @@ func (r *Repository) GetUserRoles() (*[]models.Role, error) {
 
 	return &resultRoles, nil
 }
+
+// SetRolePermissions replace permissions of role, missing permissions are created
+func (r *Repository) SetRolePermissions(id uint32, permissionNames []string) (*models.Role, error) {
+	var resultRole models.Role
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&resultRole)
+		if result.Error != nil {
+			return fmt.Errorf("tx.First error: %w", result.Error)
+		}
+
+		permissions := make([]models.Permission, len(permissionNames))
+		for i, permissionName := range permissionNames {
+			result := tx.Where(models.Permission{Name: permissionName}).FirstOrCreate(&permissions[i])
+			if result.Error != nil {
+				return fmt.Errorf("tx.FirstOrCreate permission error: %w", result.Error)
+			}
+		}
+
+		err := tx.Model(&resultRole).Association("Permissions").Replace(permissions)
+		if err != nil {
+			return fmt.Errorf("tx.Association replace error: %w", err)
+		}
+		resultRole.Permissions = permissions
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultRole, nil
+}
+
+// SeedRoles create roles and grant them permissions if not exist
+func (r *Repository) SeedRoles(roles map[string][]string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for name, permissionNames := range roles {
+			var role models.Role
+			result := tx.Where(models.Role{Name: name}).FirstOrCreate(&role)
+			if result.Error != nil {
+				return fmt.Errorf("tx.FirstOrCreate role error: %w", result.Error)
+			}
+
+			permissions := make([]models.Permission, len(permissionNames))
+			for i, permissionName := range permissionNames {
+				result := tx.Where(models.Permission{Name: permissionName}).FirstOrCreate(&permissions[i])
+				if result.Error != nil {
+					return fmt.Errorf("tx.FirstOrCreate permission error: %w", result.Error)
+				}
+			}
+
+			err := tx.Model(&role).Association("Permissions").Append(permissions)
+			if err != nil {
+				return fmt.Errorf("tx.Association append error: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetRolesPermissions get names of permissions for each of roles
+func (r *Repository) GetRolesPermissions(roleIDs []uint32) (map[uint32][]string, error) {
+	var rows []struct {
+		RoleID uint32
+		Name   string
+	}
+	result := r.DB.Model(&models.Permission{}).
+		Select("role_permissions.role_id, permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id IN ?", roleIDs).
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Scan error: %w", result.Error)
+	}
+
+	permissions := make(map[uint32][]string, len(roleIDs))
+	for _, row := range rows {
+		permissions[row.RoleID] = append(permissions[row.RoleID], row.Name)
+	}
+
+	return permissions, nil
+}
+
+// AddRoleAssignment grant role to user
+func (r *Repository) AddRoleAssignment(assignment *models.RoleAssignment) (*models.RoleAssignment, error) {
+	result := r.DB.Create(assignment)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return assignment, nil
+}
+
+// RemoveRoleAssignment revoke role assignment by id
+func (r *Repository) RemoveRoleAssignment(id uint32) error {
+	result := r.DB.Delete(models.RoleAssignment{ID: id})
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Delete error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetRoleAssignments get all role assignments of user
+func (r *Repository) GetRoleAssignments(userID string) ([]models.RoleAssignment, error) {
+	var resultAssignments []models.RoleAssignment
+	result := r.DB.Where("user_id = ?", userID).
+		Order("id").
+		Find(&resultAssignments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultAssignments, nil
+}
+
+// GetActiveRoleAssignments get role assignments of user valid at moment
+func (r *Repository) GetActiveRoleAssignments(userID string, moment time.Time) ([]models.RoleAssignment, error) {
+	var resultAssignments []models.RoleAssignment
+	result := r.DB.Where("user_id = ?", userID).
+		Where("starts_at IS NULL OR starts_at <= ?", moment).
+		Where("ends_at IS NULL OR ends_at > ?", moment).
+		Order("id").
+		Find(&resultAssignments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultAssignments, nil
+}
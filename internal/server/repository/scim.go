@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"account-service/internal/models"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// SearchUsers get page of users matched by condition sorted by creation time and total count of matched users,
+// condition is sql with placeholders for args, negative limit returns all users
+func (r *Repository) SearchUsers(condition string, args []interface{}, offset, limit int) ([]models.User, int64, error) {
+	query := r.DB.Model(&models.User{})
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	// new session so count and find don't share statement
+	query = query.Session(&gorm.Session{})
+
+	total := int64(0)
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("r.DB.Count error: %w", err)
+	}
+
+	var resultUsers []models.User
+	result := query.
+		Order("created_at ASC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&resultUsers)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("r.DB.Find error: %w", result.Error)
+	}
+
+	return resultUsers, total, nil
+}
+
+// CreateUser create user with fields of user, password is stored as is
+func (r *Repository) CreateUser(user *models.User) (*models.User, error) {
+	result := r.DB.Create(user)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.Create error: %w", result.Error)
+	}
+
+	return user, nil
+}
+
+// UpdateUsersByIDs update fields of users by ids
+func (r *Repository) UpdateUsersByIDs(ids []string, fields map[string]interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := r.DB.Model(&models.User{}).
+		Where("id IN ?", ids).
+		Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Updates error: %w", result.Error)
+	}
+
+	return nil
+}
+
+// GetUserDepartmentByName get department by name
+func (r *Repository) GetUserDepartmentByName(name string) (*models.Department, error) {
+	var resultDepartment models.Department
+	result := r.DB.Where("name = ?", name).First(&resultDepartment)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultDepartment, nil
+}
+
+// GetUserRoleByName get role by name
+func (r *Repository) GetUserRoleByName(name string) (*models.Role, error) {
+	var resultRole models.Role
+	result := r.DB.Where("name = ?", name).First(&resultRole)
+	if result.Error != nil {
+		return nil, fmt.Errorf("r.DB.First error: %w", result.Error)
+	}
+
+	return &resultRole, nil
+}
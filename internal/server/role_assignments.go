@@ -0,0 +1,160 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	protos "protos/account"
+	"time"
+)
+
+// GrantRole assign role to user, optionally scoped to department and limited in time,
+// caller must hold permissions of role in department
+func (a *AccountService) GrantRole(ctx context.Context, rr *protos.GrantRoleRequest) (*protos.RoleAssignment, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "GrantRole")
+	defer span.End()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	startsAt := optionalTime(rr.GetStartsAt())
+	endsAt := optionalTime(rr.GetEndsAt())
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return nil, models.InvalidValidityWindowError
+	}
+
+	user, err := a.db.GetUserByID(rr.GetUserId())
+	if err != nil || user == nil {
+		log.Error("[server.GrantRole] a.db.GetUserByID", "userID", rr.GetUserId(), "error", err)
+		return nil, models.UserNotFoundError
+	}
+
+	role, err := a.db.GetUserRoleByID(rr.GetRoleId())
+	if err != nil || role == nil {
+		log.Error("[server.GrantRole] a.db.GetUserRoleByID", "roleID", rr.GetRoleId(), "error", err)
+		return nil, models.RoleNotFoundError
+	}
+
+	departmentID := rr.GetDepartmentId()
+	if departmentID != 0 {
+		department, err := a.db.GetUserDepartmentByID(departmentID)
+		if err != nil || department == nil {
+			log.Error("[server.GrantRole] a.db.GetUserDepartmentByID", "departmentID", departmentID, "error", err)
+			return nil, models.DepartmentNotFoundError
+		}
+	}
+
+	err = a.authorizeRole(principal, role.ID, departmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := a.db.AddRoleAssignment(&models.RoleAssignment{
+		UserID:       user.ID,
+		RoleID:       role.ID,
+		DepartmentID: optionalID(departmentID),
+		StartsAt:     startsAt,
+		EndsAt:       endsAt,
+		GrantedBy:    principal.UserID,
+	})
+	if err != nil {
+		log.Error("[server.GrantRole] a.db.AddRoleAssignment", "userID", user.ID, "roleID", role.ID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return roleAssignmentToProto(assignment), nil
+}
+
+// RevokeRole remove role assignment
+func (a *AccountService) RevokeRole(ctx context.Context, rr *protos.RevokeRoleRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "RevokeRole")
+	defer span.End()
+
+	err := a.db.RemoveRoleAssignment(rr.GetId())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.RoleAssignmentNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.RevokeRole] a.db.RemoveRoleAssignment", "assignmentID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListRoleAssignments return all role assignments of user
+func (a *AccountService) ListRoleAssignments(ctx context.Context, rr *protos.ListRoleAssignmentsRequest) (*protos.ListRoleAssignmentsResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListRoleAssignments")
+	defer span.End()
+
+	assignments, err := a.db.GetRoleAssignments(rr.GetUserId())
+	if err != nil {
+		log.Error("[server.ListRoleAssignments] a.db.GetRoleAssignments", "userID", rr.GetUserId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.RoleAssignment, 0, len(assignments))
+	for i := range assignments {
+		result = append(result, roleAssignmentToProto(&assignments[i]))
+	}
+
+	return &protos.ListRoleAssignmentsResponse{
+		Assignments: result,
+	}, nil
+}
+
+func roleAssignmentToProto(assignment *models.RoleAssignment) *protos.RoleAssignment {
+	result := &protos.RoleAssignment{
+		Id:        assignment.ID,
+		UserId:    assignment.UserID,
+		RoleId:    assignment.RoleID,
+		GrantedBy: assignment.GrantedBy,
+		Active:    assignment.IsActive(time.Now()),
+	}
+
+	if assignment.DepartmentID != nil {
+		result.DepartmentId = *assignment.DepartmentID
+	}
+	if assignment.StartsAt != nil {
+		result.StartsAt = timestamppb.New(*assignment.StartsAt)
+	}
+	if assignment.EndsAt != nil {
+		result.EndsAt = timestamppb.New(*assignment.EndsAt)
+	}
+
+	return result
+}
+
+// optionalTime nil for unset timestamp
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	t := ts.AsTime()
+	return &t
+}
+
+// optionalTimestamp nil timestamp for unset time
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
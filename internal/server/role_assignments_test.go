@@ -0,0 +1,80 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"errors"
+	protos "protos/account"
+	"testing"
+)
+
+func TestGrantRole(t *testing.T) {
+	var engineering uint32 = 2
+
+	tests := []struct {
+		name string
+		// assignments of caller in addition to its primary role
+		assignments []models.RoleAssignment
+		callerRole  uint32
+		role        uint32
+		department  uint32
+		err         error
+	}{
+		{name: "admin grants admin", callerRole: 2, role: 2},
+		{name: "assigner grants role with its permissions", callerRole: 3, role: 1},
+		{name: "assigner can't grant admin", callerRole: 3, role: 2, err: models.PermissionDeniedError},
+		{name: "assigner can't grant admin in department", callerRole: 3, role: 2, department: 3, err: models.PermissionDeniedError},
+		{
+			name: "scoped admin grants admin in subdepartment", callerRole: 3, role: 2, department: 3,
+			assignments: []models.RoleAssignment{{RoleID: 2, DepartmentID: &engineering}},
+		},
+		{
+			name: "scoped admin grants admin in its department", callerRole: 3, role: 2, department: 2,
+			assignments: []models.RoleAssignment{{RoleID: 2, DepartmentID: &engineering}},
+		},
+		{
+			name: "scoped admin can't grant admin in other department", callerRole: 3, role: 2, department: 1,
+			assignments: []models.RoleAssignment{{RoleID: 2, DepartmentID: &engineering}}, err: models.PermissionDeniedError,
+		},
+		{
+			name: "scoped admin can't grant admin globally", callerRole: 3, role: 2,
+			assignments: []models.RoleAssignment{{RoleID: 2, DepartmentID: &engineering}}, err: models.PermissionDeniedError,
+		},
+		{name: "unknown role", callerRole: 2, role: 9, err: models.RoleNotFoundError},
+		{name: "unknown department", callerRole: 2, role: 1, department: 9, err: models.DepartmentNotFoundError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "assigner"})
+			db.rolePermissions[3] = []string{models.PermissionRolesAssign, models.PermissionDepartmentsRead, models.PermissionRolesRead}
+
+			caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: tt.callerRole})
+			for _, assignment := range tt.assignments {
+				assignment.UserID = caller.ID
+				db.assignments = append(db.assignments, assignment)
+			}
+			user := db.addUser(models.User{Email: "bob@example.com", DepartmentID: 1, RoleID: 1})
+
+			a := newTestService(db, nil)
+			ctx := userContext(t, a, caller)
+			granted := len(db.assignments)
+
+			assignment, err := a.GrantRole(ctx, &protos.GrantRoleRequest{UserId: user.ID, RoleId: tt.role, DepartmentId: tt.department})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(db.assignments) != granted {
+					t.Errorf("assignments = %+v, want none granted", db.assignments[granted:])
+				}
+				return
+			}
+
+			if assignment.GetUserId() != user.ID || assignment.GetRoleId() != tt.role || assignment.GetDepartmentId() != tt.department ||
+				assignment.GetGrantedBy() != caller.ID {
+				t.Errorf("assignment = %+v", assignment)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"account-service/internal/validators"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+	protos "protos/account"
+	"strings"
+)
+
+// CreateRole create new role
+func (a *AccountService) CreateRole(ctx context.Context, rr *protos.CreateRoleRequest) (*protos.Role, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "CreateRole")
+	defer span.End()
+
+	name := rr.GetName()
+	err := validators.ValidateName(name)
+	if err != nil {
+		return nil, models.NameNotValidError(err)
+	}
+
+	role, err := a.db.AddUserRole(name)
+	if errors.Is(err, models.AlreadyExistError) {
+		return nil, models.AlreadyExistError
+	}
+	if err != nil {
+		log.Error("[server.CreateRole] a.db.AddUserRole", "name", name, "error", err)
+		return nil, models.InternalError
+	}
+
+	return roleToProto(role), nil
+}
+
+// RenameRole change name of role, built-in roles can't be renamed
+func (a *AccountService) RenameRole(ctx context.Context, rr *protos.RenameRoleRequest) (*protos.Role, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "RenameRole")
+	defer span.End()
+
+	name := rr.GetName()
+	err := validators.ValidateName(name)
+	if err != nil {
+		return nil, models.NameNotValidError(err)
+	}
+
+	role, err := a.db.GetUserRoleByID(rr.GetId())
+	if err != nil {
+		log.Error("[server.RenameRole] a.db.GetUserRoleByID", "roleID", rr.GetId(), "error", err)
+		return nil, models.RoleNotFoundError
+	}
+	if _, ok := models.BuiltinRoles[role.Name]; ok {
+		return nil, models.BuiltinRoleError
+	}
+
+	role, err = a.db.RenameUserRole(rr.GetId(), name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.RoleNotFoundError
+	}
+	if errors.Is(err, models.AlreadyExistError) {
+		return nil, models.AlreadyExistError
+	}
+	if err != nil {
+		log.Error("[server.RenameRole] a.db.RenameUserRole", "roleID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return roleToProto(role), nil
+}
+
+// DeleteRole remove role, users of role are moved to reassign role if it is set
+func (a *AccountService) DeleteRole(ctx context.Context, rr *protos.DeleteRoleRequest) (*emptypb.Empty, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "DeleteRole")
+	defer span.End()
+
+	roleID := rr.GetId()
+	reassignID := rr.GetReassignToId()
+	if reassignID == roleID {
+		return nil, models.BadRequestError
+	}
+
+	if reassignID != 0 {
+		role, err := a.db.GetUserRoleByID(reassignID)
+		if err != nil || role == nil {
+			log.Error("[server.DeleteRole] a.db.GetUserRoleByID", "roleID", reassignID, "error", err)
+			return nil, models.RoleNotFoundError
+		}
+	}
+
+	role, err := a.db.GetUserRoleByID(roleID)
+	if err != nil {
+		log.Error("[server.DeleteRole] a.db.GetUserRoleByID", "roleID", roleID, "error", err)
+		return nil, models.RoleNotFoundError
+	}
+	if _, ok := models.BuiltinRoles[role.Name]; ok {
+		return nil, models.BuiltinRoleError
+	}
+
+	err = a.db.RemoveUserRole(roleID, reassignID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.RoleNotFoundError
+	}
+	if errors.Is(err, models.RoleInUseError) {
+		return nil, models.RoleInUseError
+	}
+	if err != nil {
+		log.Error("[server.DeleteRole] a.db.RemoveUserRole", "roleID", roleID, "error", err)
+		return nil, models.InternalError
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// SetRolePermissions replace permissions of role, built-in roles can't be changed,
+// caller must hold permissions of role before and after change
+func (a *AccountService) SetRolePermissions(ctx context.Context, rr *protos.SetRolePermissionsRequest) (*protos.Role, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	ctx, span := tr.Start(ctx, "SetRolePermissions")
+	defer span.End()
+
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]string, 0, len(rr.GetPermissions()))
+	// required permissions of role before and after change
+	required := make(map[string]bool, len(rr.GetPermissions()))
+	for _, permission := range rr.GetPermissions() {
+		permission = strings.TrimSpace(permission)
+		if !models.IsKnownPermission(permission) {
+			return nil, models.PermissionNotValidError(permission)
+		}
+		if !required[permission] {
+			required[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+
+	role, err := a.db.GetUserRoleByID(rr.GetId())
+	if err != nil {
+		log.Error("[server.SetRolePermissions] a.db.GetUserRoleByID", "roleID", rr.GetId(), "error", err)
+		return nil, models.RoleNotFoundError
+	}
+	if _, ok := models.BuiltinRoles[role.Name]; ok {
+		return nil, models.BuiltinRoleError
+	}
+
+	// caller can't grant permissions it doesn't have or take them from users of role
+	for _, permission := range role.Permissions {
+		required[permission.Name] = true
+	}
+	for permission := range required {
+		if !principal.HasPermissions(permission) {
+			log.Error("[server.SetRolePermissions] principal.HasPermissions", "userID", principal.UserID, "roleID", role.ID, "permission", permission)
+			return nil, models.PermissionDeniedError
+		}
+	}
+
+	role, err = a.db.SetRolePermissions(role.ID, permissions)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.RoleNotFoundError
+	}
+	if err != nil {
+		log.Error("[server.SetRolePermissions] a.db.SetRolePermissions", "roleID", rr.GetId(), "error", err)
+		return nil, models.InternalError
+	}
+
+	return roleToProto(role), nil
+}
+
+// GetRole return role by id
+func (a *AccountService) GetRole(ctx context.Context, rr *protos.GetRoleRequest) (*protos.Role, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "GetRole")
+	defer span.End()
+
+	role, err := a.db.GetUserRoleByID(rr.GetId())
+	if err != nil {
+		log.Error("[server.GetRole] a.db.GetUserRoleByID", "roleID", rr.GetId(), "error", err)
+		return nil, models.RoleNotFoundError
+	}
+
+	return roleToProto(role), nil
+}
+
+// ListRoles return all roles
+func (a *AccountService) ListRoles(ctx context.Context, _ *emptypb.Empty) (*protos.ListRolesResponse, error) {
+	log := hclog.Default()
+
+	tr := a.trace
+	_, span := tr.Start(ctx, "ListRoles")
+	defer span.End()
+
+	roles, err := a.db.GetUserRoles()
+	if err != nil {
+		log.Error("[server.ListRoles] a.db.GetUserRoles", "error", err)
+		return nil, models.InternalError
+	}
+
+	result := make([]*protos.Role, 0, len(*roles))
+	for i := range *roles {
+		result = append(result, roleToProto(&(*roles)[i]))
+	}
+
+	return &protos.ListRolesResponse{
+		Roles: result,
+	}, nil
+}
+
+func roleToProto(role *models.Role) *protos.Role {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+
+	return &protos.Role{
+		Id:          role.ID,
+		Name:        role.Name,
+		Permissions: permissions,
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"account-service/internal/models"
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protos "protos/account"
+	"reflect"
+	"testing"
+)
+
+func TestSetRolePermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		role        uint32
+		current     []string
+		permissions []string
+		want        []string
+		code        codes.Code
+		err         error
+	}{
+		{
+			name: "grant permissions of caller", role: 4, current: []string{models.PermissionUsersRead},
+			permissions: []string{models.PermissionUsersRead, " roles:read", models.PermissionUsersRead},
+			want:        []string{models.PermissionUsersRead, models.PermissionRolesRead},
+		},
+		{name: "remove all permissions", role: 4, current: []string{models.PermissionUsersRead}, want: []string{}},
+		{
+			name: "can't grant permission caller doesn't have", role: 4,
+			permissions: []string{models.PermissionUsersWrite}, err: models.PermissionDeniedError,
+		},
+		{
+			name: "can't remove permission caller doesn't have", role: 4, current: []string{models.PermissionDepartmentsWrite},
+			permissions: []string{models.PermissionUsersRead}, err: models.PermissionDeniedError,
+		},
+		{name: "unknown permission", role: 4, permissions: []string{"users:fly"}, code: codes.InvalidArgument},
+		{name: "built-in role", role: 1, permissions: []string{models.PermissionRolesRead}, err: models.BuiltinRoleError},
+		{name: "unknown role", role: 9, err: models.RoleNotFoundError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeRepository()
+			db.roles = append(db.roles, models.Role{ID: 3, Name: "roles-admin"}, models.Role{ID: 4, Name: "auditor"})
+			db.rolePermissions[3] = []string{models.PermissionRolesWrite, models.PermissionRolesRead, models.PermissionUsersRead}
+			db.rolePermissions[4] = tt.current
+			caller := db.addUser(models.User{Email: "ann@example.com", DepartmentID: 1, RoleID: 3})
+			builtin := db.rolePermissions[1]
+
+			a := newTestService(db, nil)
+			principal := &models.Principal{UserID: caller.ID, RoleID: caller.RoleID, Permissions: db.rolePermissions[3]}
+			ctx := context.WithValue(context.Background(), principalKey{}, principal)
+
+			role, err := a.SetRolePermissions(ctx, &protos.SetRolePermissionsRequest{Id: tt.role, Permissions: tt.permissions})
+			if tt.code != codes.OK {
+				if status.Code(err) != tt.code {
+					t.Fatalf("error = %v, want code %v", err, tt.code)
+				}
+			} else if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				if !reflect.DeepEqual(db.rolePermissions[4], tt.current) || !reflect.DeepEqual(db.rolePermissions[1], builtin) {
+					t.Errorf("permissions = %v, want unchanged", db.rolePermissions)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(db.rolePermissions[4], tt.want) || len(role.GetPermissions()) != len(tt.want) {
+				t.Errorf("permissions = %v, role %+v, want %v", db.rolePermissions[4], role, tt.want)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 
 // Repository interface for repository
 type Repository interface {
-	CreateToken(ctx context.Context, variety string, identity string) (*models.Token, error)
+	CreateToken(ctx context.Context, variety string, identity string, session string) (*models.Token, error)
 	GetTokenByID(ctx context.Context, id string, variety string) (*models.Token, error)
 	SetUse(token *models.Token)
 	Revoke(token *models.Token)
+	RevokeActive(ctx context.Context, token *models.Token) (bool, error)
+	RevokeAll(ctx context.Context, identity string, keep *models.Token) error
 }
@@ -23,7 +23,7 @@ func NewRepository(db *gorm.DB, tr trace.Tracer) *Repository {
 }
 
 // CreateToken create new token with specific variety
-func (r *Repository) CreateToken(ctx context.Context, variety string, identity string) (*models.Token, error) {
+func (r *Repository) CreateToken(ctx context.Context, variety string, identity string, session string) (*models.Token, error) {
 	tr := r.Tracer
 	_, span := tr.Start(ctx, "db-create-token")
 	defer span.End()
@@ -31,6 +31,7 @@ func (r *Repository) CreateToken(ctx context.Context, variety string, identity s
 	token := models.Token{
 		Variety:  variety,
 		Identity: identity,
+		Session:  session,
 	}
 	result := r.DB.Create(&token)
 
@@ -71,3 +72,44 @@ func (r *Repository) Revoke(token *models.Token) {
 	token.IsRevoked = true
 	r.DB.Save(token)
 }
+
+// RevokeActive revoke token if it is not revoked yet, false is returned if token was already revoked
+func (r *Repository) RevokeActive(ctx context.Context, token *models.Token) (bool, error) {
+	tr := r.Tracer
+	_, span := tr.Start(ctx, "db-revoke-active")
+	defer span.End()
+
+	result := r.DB.Model(&models.Token{}).
+		Where("id = ? AND is_revoked = ?", token.ID, false).
+		Updates(map[string]interface{}{"is_revoked": true, "last_use": time.Now()})
+	if result.Error != nil {
+		return false, fmt.Errorf("r.DB.Updates error: %w", result.Error)
+	}
+	token.IsRevoked = true
+
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAll revoke all tokens of identity, except keep token and tokens of its session if keep is not nil
+func (r *Repository) RevokeAll(ctx context.Context, identity string, keep *models.Token) error {
+	tr := r.Tracer
+	_, span := tr.Start(ctx, "db-revoke-all")
+	defer span.End()
+
+	query := r.DB.Model(&models.Token{}).
+		Where("identity = ? AND is_revoked = ?", identity, false)
+
+	if keep != nil {
+		query = query.Where("id <> ?", keep.ID)
+		if keep.Session != "" {
+			query = query.Where("session IS DISTINCT FROM ?", keep.Session)
+		}
+	}
+
+	result := query.Update("is_revoked", true)
+	if result.Error != nil {
+		return fmt.Errorf("r.DB.Update error: %w", result.Error)
+	}
+
+	return nil
+}
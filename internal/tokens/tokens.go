@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 	"time"
 )
@@ -27,12 +28,18 @@ func NewToken(db Repository, tr trace.Tracer, cfg *config.Config) *TokenService
 	}
 }
 
+// NewJWT create new jwt without session
 func (t *TokenService) NewJWT(ctx context.Context, variety string, identity string, email string, extra jwt.MapClaims) (*models.JWT, error) {
+	return t.newSessionJWT(ctx, variety, identity, email, "", extra)
+}
+
+// newSessionJWT create new jwt of session
+func (t *TokenService) newSessionJWT(ctx context.Context, variety string, identity string, email string, session string, extra jwt.MapClaims) (*models.JWT, error) {
 	tr := t.tracer
 	ctx, span := tr.Start(ctx, "new-jwt")
 	defer span.End()
 
-	tokenObj, err := t.db.CreateToken(ctx, variety, identity)
+	tokenObj, err := t.db.CreateToken(ctx, variety, identity, session)
 	if err != nil {
 		return nil, fmt.Errorf("t.db.CreateToken error: %w", err)
 	}
@@ -112,6 +119,7 @@ func (t *TokenService) ParseJWT(ctx context.Context, token string) (*models.JWT,
 		IsRevoked:   tokenObj.IsRevoked,
 		LastUse:     &tokenObj.LastUse,
 		TokenObject: tokenObj,
+		Extra:       claim,
 	}, nil
 }
 
@@ -159,16 +167,41 @@ func (t *TokenService) Validate(j *models.JWT, variety string) error {
 	return nil
 }
 
+// Consume validate single use JWT by variety and revoke it, token can be consumed only once
+func (t *TokenService) Consume(ctx context.Context, j *models.JWT, variety string) error {
+	if j.Variety != variety {
+		return fmt.Errorf("variety failed")
+	}
+
+	revoked, err := t.db.RevokeActive(ctx, j.TokenObject)
+	if err != nil {
+		return fmt.Errorf("t.db.RevokeActive error: %w", err)
+	}
+	if !revoked {
+		return fmt.Errorf("jwt is already used")
+	}
+	j.IsRevoked = true
+
+	return nil
+}
+
 // CreateAccessJWT create access and access refresh
-func (t *TokenService) CreateAccessJWT(ctx context.Context, identity string, email string) (*models.JWT, *models.JWT, error) {
-	authToken, err := t.NewJWT(ctx, models.AccessToken, identity, email, nil)
+func (t *TokenService) CreateAccessJWT(ctx context.Context, identity string, email string, extra jwt.MapClaims) (*models.JWT, *models.JWT, error) {
+	return t.CreateSessionJWT(ctx, identity, email, extra, nil)
+}
+
+// CreateSessionJWT create access and access refresh of new session with own extra claims of refresh
+func (t *TokenService) CreateSessionJWT(ctx context.Context, identity string, email string, extra jwt.MapClaims, refreshExtra jwt.MapClaims) (*models.JWT, *models.JWT, error) {
+	session := uuid.NewString()
+
+	authToken, err := t.newSessionJWT(ctx, models.AccessToken, identity, email, session, extra)
 	if err != nil {
-		return nil, nil, fmt.Errorf("[tokens.CreateAccessJWT] t.NewJWT access: %w", err)
+		return nil, nil, fmt.Errorf("[tokens.CreateSessionJWT] t.NewJWT access: %w", err)
 	}
 
-	refreshAuthToken, err := t.NewJWT(ctx, models.RefreshAccessToken, identity, email, nil)
+	refreshAuthToken, err := t.newSessionJWT(ctx, models.RefreshAccessToken, identity, email, session, refreshExtra)
 	if err != nil {
-		return nil, nil, fmt.Errorf("[tokens.CreateAccessJWT] t.NewJWT refresh access: %w", err)
+		return nil, nil, fmt.Errorf("[tokens.CreateSessionJWT] t.NewJWT refresh access: %w", err)
 	}
 
 	return authToken, refreshAuthToken, nil
@@ -193,3 +226,18 @@ func (t *TokenService) CreateAuthJWT(ctx context.Context, identity string, email
 func (t *TokenService) Revoke(j *models.JWT) {
 	t.db.Revoke(j.TokenObject)
 }
+
+// RevokeAll revoke all tokens of identity, except keep token and its session if keep is not nil
+func (t *TokenService) RevokeAll(ctx context.Context, identity string, keep *models.JWT) error {
+	var keepToken *models.Token
+	if keep != nil {
+		keepToken = keep.TokenObject
+	}
+
+	err := t.db.RevokeAll(ctx, identity, keepToken)
+	if err != nil {
+		return fmt.Errorf("t.db.RevokeAll error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateName validate name of department or role
+func ValidateName(name string) error {
+	// Maximum 100 and minimum 1
+	length := utf8.RuneCountInString(name)
+	if length > 100 || length == 0 {
+		return fmt.Errorf("length name must be less 100 and not empty")
+	}
+
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("name must not start or end with space")
+	}
+
+	return nil
+}
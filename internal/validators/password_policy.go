@@ -0,0 +1,326 @@
+package validators
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// RuleMinLength password is too short
+	RuleMinLength = "min_length"
+	// RuleMaxLength password is too long
+	RuleMaxLength = "max_length"
+	// RuleRequireUpper password has no upper case letter
+	RuleRequireUpper = "require_upper"
+	// RuleRequireLower password has no lower case letter
+	RuleRequireLower = "require_lower"
+	// RuleRequireDigit password has no digit
+	RuleRequireDigit = "require_digit"
+	// RuleRequireSymbol password has no symbol
+	RuleRequireSymbol = "require_symbol"
+	// RuleBannedSubstring password contains banned substring
+	RuleBannedSubstring = "banned_substring"
+	// RulePersonalInfo password contains name or email of user
+	RulePersonalInfo = "personal_info"
+	// RuleMaxRepeated password has too many repeated characters in row
+	RuleMaxRepeated = "max_repeated"
+	// RuleMinScore password is too easy to guess
+	RuleMinScore = "min_score"
+	// RuleBreached password is found in known breaches
+	RuleBreached = "breached"
+)
+
+// PasswordPolicy rules of passwords, zero values disable rules
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	BannedSubstrings []string
+	BanPersonalInfo  bool
+	MaxRepeated      int
+	MinScore         int
+}
+
+// PasswordContext user data which must not be part of password
+type PasswordContext struct {
+	FirstName string
+	LastName  string
+	Email     string
+}
+
+// PasswordViolation violated rule of password policy
+type PasswordViolation struct {
+	Rule        string
+	Description string
+}
+
+// DefaultPasswordPolicy policy with length at least 8 and upper, lower and digit characters
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    128,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}
+}
+
+// Validate check password against all rules of policy and return all violations
+func (p *PasswordPolicy) Validate(password string, pc PasswordContext) []PasswordViolation {
+	var violations []PasswordViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{
+			Rule:        rule,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violate(RuleMinLength, "password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(RuleMaxLength, "password must be at most %d characters", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLetter(r):
+			upper = true
+		case unicode.IsLower(r) && unicode.IsLetter(r):
+			lower = true
+		case unicode.IsNumber(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violate(RuleRequireUpper, "password must contain upper case letter")
+	}
+	if p.RequireLower && !lower {
+		violate(RuleRequireLower, "password must contain lower case letter")
+	}
+	if p.RequireDigit && !digit {
+		violate(RuleRequireDigit, "password must contain digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violate(RuleRequireSymbol, "password must contain symbol")
+	}
+
+	lowerPassword := strings.ToLower(password)
+	for _, banned := range p.BannedSubstrings {
+		if banned != "" && strings.Contains(lowerPassword, strings.ToLower(banned)) {
+			violate(RuleBannedSubstring, "password must not contain %q", banned)
+		}
+	}
+
+	if p.BanPersonalInfo {
+		for _, info := range pc.inputs() {
+			if strings.Contains(lowerPassword, info) {
+				violate(RulePersonalInfo, "password must not contain name or email")
+				break
+			}
+		}
+	}
+
+	if p.MaxRepeated > 0 && maxRepeated(password) > p.MaxRepeated {
+		violate(RuleMaxRepeated, "password must not have more than %d same characters in row", p.MaxRepeated)
+	}
+
+	if p.MinScore > 0 {
+		score := PasswordScore(password, pc.inputs())
+		if score < p.MinScore {
+			violate(RuleMinScore, "password is too easy to guess, score %d of required %d", score, p.MinScore)
+		}
+	}
+
+	return violations
+}
+
+// inputs lower case parts of name and email at least 3 characters long
+func (pc PasswordContext) inputs() []string {
+	var inputs []string
+	add := func(value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if utf8.RuneCountInString(value) >= 3 {
+			inputs = append(inputs, value)
+		}
+	}
+
+	add(pc.FirstName)
+	add(pc.LastName)
+
+	local, domain, _ := strings.Cut(pc.Email, "@")
+	for _, part := range strings.FieldsFunc(local, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		add(part)
+	}
+	if name, _, ok := strings.Cut(domain, "."); ok {
+		add(name)
+	}
+
+	return inputs
+}
+
+// maxRepeated length of longest run of same character
+func maxRepeated(password string) int {
+	longest, current := 0, 0
+	var previous rune
+	for i, r := range []rune(password) {
+		if i > 0 && r == previous {
+			current++
+		} else {
+			current = 1
+		}
+		previous = r
+
+		if current > longest {
+			longest = current
+		}
+	}
+
+	return longest
+}
+
+// keyboardRows rows of qwerty keyboard for detection of keyboard walks
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// commonPasswords frequently used passwords and words
+var commonPasswords = []string{
+	"password", "passw0rd", "qwerty", "letmein", "welcome", "admin", "login", "master", "dragon",
+	"monkey", "football", "baseball", "iloveyou", "sunshine", "princess", "shadow", "superman",
+	"trustno1", "hello", "freedom", "whatever", "secret", "summer", "winter", "spring", "autumn",
+	"company", "changeme", "default", "access", "computer", "internet", "service", "account",
+}
+
+// PasswordScore estimate strength of password from 0 to 4 like zxcvbn by estimated guesses,
+// predictable parts like repeats, sequences, keyboard walks, common words and user inputs are cheap
+func PasswordScore(password string, userInputs []string) int {
+	lower := strings.ToLower(password)
+	runes := []rune(lower)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	// characters covered by dictionary words cost log2 of dictionary size per word,
+	// words overlapping already covered characters are skipped so they aren't counted twice
+	covered := make([]bool, len(runes))
+	bits := 0.0
+	dictionary := append(append([]string{}, commonPasswords...), userInputs...)
+	for _, word := range dictionary {
+		wordRunes := []rune(word)
+		if len(wordRunes) < 3 {
+			continue
+		}
+
+		for start := 0; start+len(wordRunes) <= len(runes); start++ {
+			end := start + len(wordRunes)
+			if string(runes[start:end]) != word || isCovered(covered[start:end]) {
+				continue
+			}
+
+			for i := start; i < end; i++ {
+				covered[i] = true
+			}
+			bits += math.Log2(float64(len(dictionary)))
+		}
+	}
+
+	charsetBits := math.Log2(float64(charsetSize(password)))
+	for i, r := range runes {
+		if covered[i] {
+			continue
+		}
+
+		if i > 0 && isPredictable(runes[i-1], r) {
+			bits++
+			continue
+		}
+
+		bits += charsetBits
+	}
+
+	switch {
+	case bits < 10:
+		return 0
+	case bits < 20:
+		return 1
+	case bits < 27:
+		return 2
+	case bits < 34:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// isCovered check any character of span is covered by dictionary word
+func isCovered(span []bool) bool {
+	for _, covered := range span {
+		if covered {
+			return true
+		}
+	}
+
+	return false
+}
+
+// charsetSize size of character set used by password
+func charsetSize(password string) int {
+	var upper, lower, digit, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if upper {
+		size += 26
+	}
+	if lower {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if other {
+		size += 33
+	}
+
+	return size
+}
+
+// isPredictable check character repeats previous, continues sequence or keyboard walk
+func isPredictable(previous, current rune) bool {
+	delta := current - previous
+	if delta >= -1 && delta <= 1 {
+		return true
+	}
+
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, previous)
+		j := strings.IndexRune(row, current)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+
+	return false
+}
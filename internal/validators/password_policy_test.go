@@ -0,0 +1,89 @@
+package validators
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	pc := PasswordContext{FirstName: "Alice", LastName: "Jo", Email: "alice.cooper@acme.com"}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		rules    []string
+	}{
+		{name: "default policy", policy: DefaultPasswordPolicy(), password: "Tr0ub4dor"},
+		{name: "empty policy", password: ""},
+		{name: "min length", policy: PasswordPolicy{MinLength: 8}, password: "Short1", rules: []string{RuleMinLength}},
+		{name: "min length counts characters", policy: PasswordPolicy{MinLength: 4}, password: "пароль"},
+		{name: "max length", policy: PasswordPolicy{MaxLength: 4}, password: "Longer1", rules: []string{RuleMaxLength}},
+		{name: "require upper", policy: PasswordPolicy{RequireUpper: true}, password: "lower1", rules: []string{RuleRequireUpper}},
+		{name: "require lower", policy: PasswordPolicy{RequireLower: true}, password: "UPPER1", rules: []string{RuleRequireLower}},
+		{name: "require digit", policy: PasswordPolicy{RequireDigit: true}, password: "NoDigits", rules: []string{RuleRequireDigit}},
+		{name: "require symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "NoSymbol1", rules: []string{RuleRequireSymbol}},
+		{name: "space is symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "with space"},
+		{
+			name: "banned substring ignores case", policy: PasswordPolicy{BannedSubstrings: []string{"acme", "", "corp"}}, password: "MyACME2024",
+			rules: []string{RuleBannedSubstring},
+		},
+		{name: "first name", policy: PasswordPolicy{BanPersonalInfo: true}, password: "xALICEx", rules: []string{RulePersonalInfo}},
+		{name: "part of email", policy: PasswordPolicy{BanPersonalInfo: true}, password: "cooper99", rules: []string{RulePersonalInfo}},
+		{name: "email domain", policy: PasswordPolicy{BanPersonalInfo: true}, password: "AcmeRocks", rules: []string{RulePersonalInfo}},
+		{name: "short name is ignored", policy: PasswordPolicy{BanPersonalInfo: true}, password: "JoJo1234"},
+		{name: "max repeated", policy: PasswordPolicy{MaxRepeated: 2}, password: "paaass", rules: []string{RuleMaxRepeated}},
+		{name: "repeated within limit", policy: PasswordPolicy{MaxRepeated: 3}, password: "paaass"},
+		{name: "min score", policy: PasswordPolicy{MinScore: 3}, password: "Password1", rules: []string{RuleMinScore}},
+		{name: "min score with user inputs", policy: PasswordPolicy{MinScore: 4}, password: "alicecooper2024", rules: []string{RuleMinScore}},
+		{
+			name: "all violations", policy: PasswordPolicy{MinLength: 8, RequireUpper: true, RequireDigit: true, RequireSymbol: true}, password: "abc",
+			rules: []string{RuleMinLength, RuleRequireUpper, RuleRequireDigit, RuleRequireSymbol},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, violation := range tt.policy.Validate(tt.password, pc) {
+				if violation.Description == "" {
+					t.Errorf("violation %s has no description", violation.Rule)
+				}
+				rules = append(rules, violation.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestPasswordScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		score      int
+	}{
+		{name: "empty", password: "", score: 0},
+		{name: "keyboard walk", password: "qwertyuiop", score: 0},
+		{name: "repeat", password: "aaaaaaaa", score: 1},
+		{name: "sequence", password: "abcdefgh", score: 1},
+		{name: "common word", password: "Password1", score: 1},
+		{name: "random characters", password: "kX9#mP2$vL", score: 4},
+		{name: "user inputs", password: "johnsmith2024", userInputs: []string{"john", "smith"}, score: 3},
+		{name: "without user inputs", password: "johnsmith2024", score: 4},
+		// badm overlaps common admin, so it doesn't add cost of another word
+		{name: "overlapping words", password: "Zq7abadmin", userInputs: []string{"badm"}, score: 3},
+		{name: "without overlapping word", password: "Zq7abadmin", score: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score := PasswordScore(tt.password, tt.userInputs); score != tt.score {
+				t.Errorf("PasswordScore = %d, want %d", score, tt.score)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package validators
 import (
 	"fmt"
 	"net/mail"
+	"strings"
 	"unicode"
 )
 
@@ -43,44 +44,20 @@ func ValidateEmail(email string) error {
 	return err
 }
 
-// ValidatePassword validate password
+// ValidatePassword validate password by default password policy
 func ValidatePassword(password string) error {
-	// minimum 8
-	if len(password) < 8 {
-		return fmt.Errorf("length password must be larger 8")
+	policy := DefaultPasswordPolicy()
+	violations := policy.Validate(password, PasswordContext{})
+	if len(violations) == 0 {
+		return nil
 	}
 
-	// upper case
-	upper := false
-	for _, r := range password {
-		if unicode.IsUpper(r) && unicode.IsLetter(r) {
-			upper = true
-			break
-		}
-	}
-
-	// lower case
-	lower := false
-	for _, r := range password {
-		if unicode.IsLower(r) && unicode.IsLetter(r) {
-			lower = true
-			break
-		}
+	descriptions := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		descriptions = append(descriptions, violation.Description)
 	}
 
-	// number
-	number := false
-	for _, r := range password {
-		if unicode.IsNumber(r) {
-			number = true
-			break
-		}
-	}
-	if !upper || !number || !lower {
-		return fmt.Errorf("password must contain upper, lower, number")
-	}
-
-	return nil
+	return fmt.Errorf("%s", strings.Join(descriptions, ", "))
 }
 
 // ValidateFIO validate user first name and last name
@@ -2,13 +2,24 @@ package main
 
 import (
 	"account-service/config"
+	"account-service/internal/breach"
+	"account-service/internal/directory"
+	"account-service/internal/federation"
+	"account-service/internal/hasher"
 	"account-service/internal/models"
+	"account-service/internal/notifier"
+	"account-service/internal/oauth"
+	"account-service/internal/policy"
+	"account-service/internal/retention"
+	"account-service/internal/scim"
 	"account-service/internal/server"
 	"account-service/internal/server/repository"
 	"account-service/internal/tokens"
 	tokensRepository "account-service/internal/tokens/repository"
 	"comet/db"
 	"comet/utils"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/getsentry/sentry-go"
@@ -18,12 +29,37 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 	"net"
+	"net/http"
 	"os"
 	protos "protos/account"
 	"time"
 )
 
+const (
+	// scimPath path of SCIM endpoint on http server
+	scimPath = "/scim/v2"
+	// oauthPath path of OAuth 2.0 authorization server on http server
+	oauthPath = "/oauth2"
+)
+
+// commands subcommands of service, service is run without subcommand
+var commands = map[string]func(args []string) error{
+	"build-breach-filter": buildBreachFilter,
+	"import-users":        importUsers,
+	"export-users":        exportUsers,
+	"scim-compliance":     scimCompliance,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				panic(err)
+			}
+			return
+		}
+	}
+
 	if err := run(); err != nil {
 		panic(err)
 	}
@@ -54,11 +90,74 @@ func run() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	err = database.AutoMigrate(&models.User{}, &models.Token{}, &models.Department{}, &models.Role{})
+	err = database.AutoMigrate(&models.User{}, &models.Token{}, &models.Department{}, &models.Role{}, &models.Permission{}, &models.RoleAssignment{}, &models.PasswordHistory{}, &models.AuditEntry{}, &models.Invitation{}, &models.OAuthClient{}, &models.OAuthConsent{}, &models.LinkedIdentity{}, &models.APIKey{})
 	if err != nil {
 		return fmt.Errorf("failed AutoMigrate database: %w", err)
 	}
 
+	passwordHasher, err := hasher.NewHasher(cfg.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to create password hasher: %w", err)
+	}
+
+	repoAccount := repository.NewRepository(database, cfg, passwordHasher)
+	err = repoAccount.MigrateDeletedAt()
+	if err != nil {
+		return fmt.Errorf("failed migrate deleted users: %w", err)
+	}
+
+	err = repoAccount.CreateIndexes()
+	if err != nil {
+		return fmt.Errorf("failed create indexes: %w", err)
+	}
+
+	err = repoAccount.SeedRoles(models.BuiltinRoles)
+	if err != nil {
+		return fmt.Errorf("failed seed roles: %w", err)
+	}
+
+	repoToken := tokensRepository.NewRepository(database, tracer)
+	tokenSrv := tokens.NewToken(repoToken, tracer, cfg)
+
+	policyEngine, err := policy.NewEngine(cfg.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load policy: %w", err)
+	}
+	go policyEngine.Watch(context.Background(), cfg.PolicyReloadInterval)
+
+	breachChecker, err := breach.NewChecker(cfg.BreachedPasswordsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load breached passwords: %w", err)
+	}
+
+	erasureWorker := retention.NewWorker(repoAccount, cfg)
+	go erasureWorker.Run(context.Background())
+
+	natsNotifier, err := notifier.NewNotifier(cfg.NatsHost)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer natsNotifier.Close()
+
+	identityProviders, err := federation.LoadProviders(cfg.IdentityProvidersFile)
+	if err != nil {
+		return fmt.Errorf("failed to load identity providers: %w", err)
+	}
+	identityFederation := federation.New(identityProviders, nil)
+
+	var directoryClient *directory.Client
+	for _, authenticator := range cfg.AuthChain {
+		if authenticator != models.AuthLDAP {
+			continue
+		}
+		directoryClient, err = directory.NewClient(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to setup LDAP: %w", err)
+		}
+	}
+
+	srv := server.NewAccount(repoAccount, tokenSrv, policyEngine, breachChecker, passwordHasher, erasureWorker, natsNotifier, identityFederation, directoryClient, tracer, cfg)
+
 	creds, err := credentials.NewServerTLSFromFile("cert/server-cert.pem", "cert/server-key.pem")
 	if err != nil {
 		return fmt.Errorf("failed to setup TLS: %w", err)
@@ -67,18 +166,38 @@ func run() error {
 	// Create a new gRPC srv
 	gs := grpc.NewServer(
 		grpc.Creds(creds),
-		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
-		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor(), srv.UnaryAuthInterceptor),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor(), srv.StreamAuthInterceptor),
 	)
 
-	repoAccount := repository.NewRepository(database)
-	repoToken := tokensRepository.NewRepository(database, tracer)
-	tokenSrv := tokens.NewToken(repoToken, tracer, cfg)
-
-	srv := server.NewAccount(repoAccount, tokenSrv, tracer, cfg)
-
 	protos.RegisterAccountServiceServer(gs, srv)
 
+	if cfg.HTTPHost != "" {
+		signer, err := oauth.NewSigner(cfg.OIDCSigningKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load OIDC signing key: %w", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(oauthPath+"/", http.StripPrefix(oauthPath, oauth.NewHandler(repoAccount, tokenSrv, srv, passwordHasher, signer, tracer, cfg, oauthPath)))
+		if cfg.ScimToken != "" {
+			mux.Handle(scimPath+"/", http.StripPrefix(scimPath, scim.NewHandler(repoAccount, tokenSrv, tracer, cfg, cfg.ScimBaseURL)))
+		}
+
+		httpServer := &http.Server{
+			Addr:              cfg.HTTPHost,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			err := httpServer.ListenAndServeTLS("cert/server-cert.pem", "cert/server-key.pem")
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("http server failed", "error", err)
+			}
+		}()
+		defer httpServer.Close()
+	}
+
 	reflection.Register(gs)
 
 	l, err := net.Listen("tcp", cfg.ServerHost)
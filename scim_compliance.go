@@ -0,0 +1,45 @@
+package main
+
+import (
+	"account-service/internal/scim"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// scimCompliance run SCIM compliance checks against running SCIM endpoint
+func scimCompliance(args []string) error {
+	fs := flag.NewFlagSet("scim-compliance", flag.ExitOnError)
+	baseURL := fs.String("url", "https://localhost"+scimPath, "base url of SCIM endpoint")
+	token := fs.String("token", "", "bearer token of SCIM endpoint")
+	insecure := fs.Bool("insecure", false, "skip verification of server certificate")
+	err := fs.Parse(args)
+	if err != nil {
+		return fmt.Errorf("fs.Parse error: %w", err)
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure},
+		},
+	}
+
+	failed := 0
+	for _, result := range scim.RunCompliance(client, *baseURL, *token) {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %s\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Printf("PASS %s\n", result.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d compliance checks failed", failed)
+	}
+
+	return nil
+}